@@ -0,0 +1,174 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	pb "github.com/jmsadair/raft/internal/protobuf"
+)
+
+// appendEntriesStream sends AppendEntries requests to a peer over a long-lived bidirectional
+// stream instead of making a separate RPC for each request. The peer handles the requests in
+// the order that they are sent and responds to them in the same order, so responses are matched
+// to requests by their position. Once the stream breaks, it may not be used again.
+// This implementation is concurrent safe.
+type appendEntriesStream struct {
+	// The stream to the peer.
+	stream pb.Raft_AppendEntriesStreamClient
+
+	// Cancels the stream.
+	cancel context.CancelFunc
+
+	// The requests awaiting a response in the order that they were sent.
+	pending []chan appendEntriesStreamResult
+
+	// The error that caused the stream to break, if it has broken.
+	err error
+
+	// Serializes sends so that requests are queued in the order that they are sent. A send
+	// holds it by placing a value in it until the request has been written to the stream.
+	sendCh chan struct{}
+
+	mu sync.Mutex
+}
+
+// appendEntriesStreamResult is the outcome of a request sent over a stream.
+type appendEntriesStreamResult struct {
+	// The response to the request.
+	response *pb.AppendEntriesResponse
+
+	// The error that caused the stream to break before the response was received.
+	err error
+}
+
+// newAppendEntriesStream opens a stream using the provided client and starts receiving
+// responses from it.
+func newAppendEntriesStream(client pb.RaftClient) (*appendEntriesStream, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.AppendEntriesStream(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("could not open AppendEntries stream: %w", err)
+	}
+	return startAppendEntriesStream(stream, cancel), nil
+}
+
+// startAppendEntriesStream starts receiving responses from the provided stream, which is
+// cancelled by the provided function.
+func startAppendEntriesStream(
+	stream pb.Raft_AppendEntriesStreamClient,
+	cancel context.CancelFunc,
+) *appendEntriesStream {
+	s := &appendEntriesStream{stream: stream, cancel: cancel, sendCh: make(chan struct{}, 1)}
+	go s.receive()
+	return s
+}
+
+// send sends the provided request over the stream and waits for its response. An error is
+// returned if the stream breaks before the response is received. The stream is broken if the
+// provided context is done before the request is written or its response is received.
+func (s *appendEntriesStream) send(
+	ctx context.Context,
+	request *pb.AppendEntriesRequest,
+) (*pb.AppendEntriesResponse, error) {
+	resultCh := make(chan appendEntriesStreamResult, 1)
+
+	// Another request is being written to the stream. If it is blocked, it breaks the
+	// stream once its own context is done.
+	select {
+	case s.sendCh <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		<-s.sendCh
+		return nil, s.err
+	}
+	s.pending = append(s.pending, resultCh)
+	s.mu.Unlock()
+
+	// Writing to the stream blocks if the peer stops reading from it, so it must not
+	// outlive the provided context. Breaking the stream cancels it, which unblocks the write.
+	sendErrCh := make(chan error, 1)
+	go func() {
+		sendErrCh <- s.stream.Send(request)
+		<-s.sendCh
+	}()
+	select {
+	case err := <-sendErrCh:
+		if err != nil {
+			s.fail(err)
+		}
+	case <-ctx.Done():
+		s.fail(ctx.Err())
+		return nil, ctx.Err()
+	}
+
+	select {
+	case result := <-resultCh:
+		return result.response, result.err
+	case <-ctx.Done():
+		// Requests are handled in order, so the requests sent after this one would wait
+		// for it to be handled. Breaking the stream also notifies the peer that the request
+		// was abandoned.
+		s.fail(ctx.Err())
+		return nil, ctx.Err()
+	}
+}
+
+// receive delivers the responses received over the stream to the requests awaiting them
+// until the stream breaks.
+func (s *appendEntriesStream) receive() {
+	for {
+		response, err := s.stream.Recv()
+		if err != nil {
+			s.fail(err)
+			return
+		}
+
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.mu.Unlock()
+			s.fail(errors.New("received a response without a request"))
+			return
+		}
+		resultCh := s.pending[0]
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+
+		resultCh <- appendEntriesStreamResult{response: response}
+	}
+}
+
+// fail breaks the stream and fails all requests awaiting a response.
+func (s *appendEntriesStream) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = fmt.Errorf("AppendEntries stream is broken: %w", err)
+	}
+	s.cancel()
+	for _, resultCh := range s.pending {
+		resultCh <- appendEntriesStreamResult{err: s.err}
+	}
+	s.pending = nil
+}
+
+// broken indicates whether the stream has broken.
+func (s *appendEntriesStream) broken() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err != nil
+}
+
+// close breaks the stream.
+func (s *appendEntriesStream) close() {
+	s.fail(errors.New("stream is closed"))
+}
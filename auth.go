@@ -0,0 +1,264 @@
+package raft
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// The metadata key containing the time at which an RPC was signed.
+	authTimestampMetadataKey = "raft-auth-timestamp"
+
+	// The metadata key containing the signature of an RPC.
+	authSignatureMetadataKey = "raft-auth-signature"
+
+	// The metadata key containing the random nonce that makes the signature of an RPC unique.
+	authNonceMetadataKey = "raft-auth-nonce"
+
+	// The number of random bytes in a nonce.
+	authNonceSize = 16
+
+	// The maximum difference between the time at which an RPC was signed and the
+	// time at which it is verified. This limits how long the nonce of an RPC must be
+	// remembered to reject replays of it and should exceed the clock skew between peers.
+	maxAuthClockSkew = time.Minute
+)
+
+// PeerAuthenticator authenticates the RPCs sent between the transports of peers. Every outgoing
+// RPC is signed and every incoming RPC is verified before it is passed to its handler. The body
+// of an RPC is its request serialized using deterministic protobuf encoding. Streams are signed
+// and verified once when they are opened, so the body of a stream is nil and the messages sent
+// over it are not authenticated individually. For this reason, AppendEntries requests are only
+// streamed if TLS is enabled. The implementation must be concurrent safe.
+type PeerAuthenticator interface {
+	// Sign returns the metadata that is attached to an outgoing RPC invoking the provided method
+	// with the provided body.
+	Sign(ctx context.Context, method string, body []byte) (map[string]string, error)
+
+	// Verify returns an error if the metadata attached to an incoming RPC invoking the provided
+	// method with the provided body does not authenticate the peer that sent it.
+	Verify(ctx context.Context, method string, body []byte, md metadata.MD) error
+}
+
+// sharedSecretAuthenticator is an implementation of the PeerAuthenticator interface that
+// authenticates peers using a secret shared by all members of the cluster. Each RPC is signed
+// with an HMAC over the method it invokes, a digest of its body, the time at which it was sent,
+// and a random nonce. An RPC whose nonce has already been verified is rejected as a replay.
+type sharedSecretAuthenticator struct {
+	// The secret shared by all members of the cluster.
+	secret []byte
+
+	// The nonces of the RPCs that have been verified, mapped to the time after which the
+	// RPCs are rejected regardless of their nonce because their timestamp is too old.
+	nonces map[string]time.Time
+
+	// The last time that expired nonces were removed.
+	lastSweep time.Time
+
+	mu sync.Mutex
+}
+
+// newSharedSecretAuthenticator creates a new authenticator using the provided secret.
+func newSharedSecretAuthenticator(secret []byte) *sharedSecretAuthenticator {
+	return &sharedSecretAuthenticator{
+		secret:    append([]byte(nil), secret...),
+		nonces:    make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func (a *sharedSecretAuthenticator) Sign(
+	ctx context.Context,
+	method string,
+	body []byte,
+) (map[string]string, error) {
+	nonceBytes := make([]byte, authNonceSize)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return nil, fmt.Errorf("could not generate nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return map[string]string{
+		authTimestampMetadataKey: timestamp,
+		authNonceMetadataKey:     nonce,
+		authSignatureMetadataKey: hex.EncodeToString(a.signature(method, body, timestamp, nonce)),
+	}, nil
+}
+
+func (a *sharedSecretAuthenticator) Verify(
+	ctx context.Context,
+	method string,
+	body []byte,
+	md metadata.MD,
+) error {
+	timestamps := md.Get(authTimestampMetadataKey)
+	nonces := md.Get(authNonceMetadataKey)
+	signatures := md.Get(authSignatureMetadataKey)
+	if len(timestamps) != 1 || len(nonces) != 1 || len(signatures) != 1 {
+		return errors.New("RPC is not signed")
+	}
+
+	nanos, err := strconv.ParseInt(timestamps[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("RPC has an invalid timestamp: %w", err)
+	}
+	signedAt := time.Unix(0, nanos)
+	if skew := time.Since(signedAt); skew > maxAuthClockSkew || skew < -maxAuthClockSkew {
+		return fmt.Errorf("RPC timestamp is outside of the allowed window: skew = %v", skew)
+	}
+
+	signature, err := hex.DecodeString(signatures[0])
+	if err != nil {
+		return fmt.Errorf("RPC has an invalid signature: %w", err)
+	}
+	if !hmac.Equal(signature, a.signature(method, body, timestamps[0], nonces[0])) {
+		return errors.New("RPC signature does not match")
+	}
+
+	// The nonce only needs to be remembered until the timestamp is outside of the allowed window.
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if now.Sub(a.lastSweep) > maxAuthClockSkew {
+		for nonce, expiry := range a.nonces {
+			if now.After(expiry) {
+				delete(a.nonces, nonce)
+			}
+		}
+		a.lastSweep = now
+	}
+	if _, ok := a.nonces[nonces[0]]; ok {
+		return errors.New("RPC has already been verified")
+	}
+	a.nonces[nonces[0]] = signedAt.Add(maxAuthClockSkew)
+
+	return nil
+}
+
+// signature returns the HMAC of the provided method, the digest of the provided body, and the
+// provided timestamp and nonce.
+func (a *sharedSecretAuthenticator) signature(
+	method string,
+	body []byte,
+	timestamp string,
+	nonce string,
+) []byte {
+	digest := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(method))
+	mac.Write([]byte{0})
+	mac.Write(digest[:])
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{0})
+	mac.Write([]byte(nonce))
+	return mac.Sum(nil)
+}
+
+// authBody returns the body of an RPC with the provided request that is signed and verified.
+func authBody(request interface{}) ([]byte, error) {
+	message, ok := request.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("request is not a protobuf message: %T", request)
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(message)
+}
+
+// authClientInterceptor returns an interceptor that signs outgoing RPCs using the
+// provided authenticator.
+func authClientInterceptor(authenticator PeerAuthenticator) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		body, err := authBody(req)
+		if err != nil {
+			return fmt.Errorf("could not sign RPC: %w", err)
+		}
+		md, err := authenticator.Sign(ctx, method, body)
+		if err != nil {
+			return fmt.Errorf("could not sign RPC: %w", err)
+		}
+		for key, value := range md {
+			ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// authServerInterceptor returns an interceptor that rejects incoming RPCs that are not
+// verified by the provided authenticator before they reach their handler.
+func authServerInterceptor(authenticator PeerAuthenticator) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		body, err := authBody(req)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "could not authenticate RPC: %v", err)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		if err := authenticator.Verify(ctx, info.FullMethod, body, md); err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "could not authenticate RPC: %v", err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authStreamClientInterceptor returns an interceptor that signs outgoing streams using the
+// provided authenticator.
+func authStreamClientInterceptor(authenticator PeerAuthenticator) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		md, err := authenticator.Sign(ctx, method, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not sign stream: %w", err)
+		}
+		for key, value := range md {
+			ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// authStreamServerInterceptor returns an interceptor that rejects incoming streams that are
+// not verified by the provided authenticator before they reach their handler.
+func authStreamServerInterceptor(authenticator PeerAuthenticator) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		if err := authenticator.Verify(stream.Context(), info.FullMethod, nil, md); err != nil {
+			return status.Errorf(codes.Unauthenticated, "could not authenticate stream: %v", err)
+		}
+		return handler(srv, stream)
+	}
+}
@@ -23,6 +23,37 @@ type Configuration struct {
 
 	// The log index of the configuration.
 	Index uint64
+
+	// Maps node ID to a boolean that indicates whether the node was a
+	// voting member of the previous configuration. This is only populated
+	// while the cluster is transitioning between configurations using
+	// joint consensus, in which case elections and commitment require a
+	// majority of the voting members of both configurations. Members is
+	// the union of the members of both configurations and IsVoter is the
+	// voting status of the members in the next configuration.
+	OldIsVoter map[string]bool
+
+	// The members of a joint configuration that are not members of the
+	// next configuration. They are removed once the joint configuration
+	// is committed.
+	Removed map[string]bool
+}
+
+// MembershipChange is a change to the membership of the cluster. A node is either
+// added to the cluster, has its voting status changed, or is removed from the cluster.
+type MembershipChange struct {
+	// The ID of the node.
+	ID string
+
+	// The address of the node. Ignored if the node is being removed.
+	Address string
+
+	// Indicates whether the node is a voting member. Ignored if the node
+	// is being removed.
+	IsVoter bool
+
+	// Indicates whether the node is being removed rather than added.
+	Remove bool
 }
 
 // NewConfiguration creates a new configuration with the provided
@@ -53,30 +84,120 @@ func (c *Configuration) Clone() Configuration {
 		configuration.Members[id] = c.Members[id]
 	}
 
+	if c.IsJoint() {
+		configuration.OldIsVoter = make(map[string]bool, len(c.OldIsVoter))
+		configuration.Removed = make(map[string]bool, len(c.Removed))
+		for id, isVoter := range c.OldIsVoter {
+			configuration.OldIsVoter[id] = isVoter
+		}
+		for id := range c.Removed {
+			configuration.Removed[id] = true
+		}
+	}
+
+	return configuration
+}
+
+// IsJoint returns true if the configuration is a joint configuration used to transition
+// between configurations and false otherwise.
+func (c *Configuration) IsJoint() bool {
+	return len(c.OldIsVoter) > 0
+}
+
+// joint returns the joint configuration used to transition from this configuration
+// to the configuration that results from applying the provided changes.
+func (c *Configuration) joint(changes []MembershipChange) Configuration {
+	configuration := c.Clone()
+	configuration.OldIsVoter = make(map[string]bool, len(c.Members))
+	configuration.Removed = make(map[string]bool)
+	for id := range c.Members {
+		configuration.OldIsVoter[id] = c.IsVoter[id]
+	}
+
+	for _, change := range changes {
+		if change.Remove {
+			if _, ok := configuration.Members[change.ID]; ok {
+				configuration.IsVoter[change.ID] = false
+				configuration.Removed[change.ID] = true
+			}
+			continue
+		}
+		configuration.Members[change.ID] = change.Address
+		configuration.IsVoter[change.ID] = change.IsVoter
+		delete(configuration.Removed, change.ID)
+	}
+
 	return configuration
 }
 
+// next returns the configuration that a joint configuration transitions to once it
+// has been committed.
+func (c *Configuration) next() Configuration {
+	configuration := c.Clone()
+	for id := range c.Removed {
+		delete(configuration.Members, id)
+		delete(configuration.IsVoter, id)
+	}
+	configuration.OldIsVoter = nil
+	configuration.Removed = nil
+	return configuration
+}
+
+// equal returns true if the provided configuration has the same members and
+// voting statuses as this configuration and false otherwise.
+func (c *Configuration) equal(other *Configuration) bool {
+	if len(c.Members) != len(other.Members) || c.IsJoint() != other.IsJoint() {
+		return false
+	}
+	for id, address := range c.Members {
+		otherAddress, ok := other.Members[id]
+		if !ok || otherAddress != address || c.IsVoter[id] != other.IsVoter[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasVoter returns true if the configuration has at least one voting member
+// and false otherwise.
+func (c *Configuration) hasVoter() bool {
+	for _, isVoter := range c.IsVoter {
+		if isVoter {
+			return true
+		}
+	}
+	return false
+}
+
 // String returns a string representation of the configuration.
 func (c *Configuration) String() string {
 	var builder strings.Builder
 
 	builder.WriteString(fmt.Sprintf("logIndex: %d members: ", c.Index))
 	for nodeID, address := range c.Members {
-		if c.IsVoter[nodeID] {
+		switch {
+		case c.Removed[nodeID]:
+			builder.WriteString(fmt.Sprintf("(%s, %s, removed),", nodeID, address))
+		case c.IsVoter[nodeID]:
 			builder.WriteString(fmt.Sprintf("(%s, %s, voter),", nodeID, address))
-		} else {
+		default:
 			builder.WriteString(fmt.Sprintf("(%s, %s, non-voter),", nodeID, address))
 		}
 	}
+	if c.IsJoint() {
+		builder.WriteString(" joint: true")
+	}
 
 	return fmt.Sprintf("{%s}", strings.TrimSuffix(builder.String(), ","))
 }
 
 func encodeConfiguration(configuration *Configuration) ([]byte, error) {
 	pbConfiguration := &pb.Configuration{
-		Members: configuration.Members,
-		IsVoter: configuration.IsVoter,
-		Index:   configuration.Index,
+		Members:    configuration.Members,
+		IsVoter:    configuration.IsVoter,
+		Index:      configuration.Index,
+		OldIsVoter: configuration.OldIsVoter,
+		Removed:    configuration.Removed,
 	}
 	data, err := proto.Marshal(pbConfiguration)
 	if err != nil {
@@ -91,9 +212,11 @@ func decodeConfiguration(data []byte) (Configuration, error) {
 		return Configuration{}, fmt.Errorf("could not unmarshal protobuf message: %w", err)
 	}
 	configuration := Configuration{
-		Members: pbConfiguration.GetMembers(),
-		IsVoter: pbConfiguration.GetIsVoter(),
-		Index:   pbConfiguration.GetIndex(),
+		Members:    pbConfiguration.GetMembers(),
+		IsVoter:    pbConfiguration.GetIsVoter(),
+		Index:      pbConfiguration.GetIndex(),
+		OldIsVoter: pbConfiguration.GetOldIsVoter(),
+		Removed:    pbConfiguration.GetRemoved(),
 	}
 	return configuration, nil
 }
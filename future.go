@@ -1,6 +1,7 @@
 package raft
 
 import (
+	"context"
 	"errors"
 	"time"
 )
@@ -12,7 +13,7 @@ var ErrTimeout = errors.New(
 
 // Response is the concrete result produced by a node after processing a client submitted operation.
 type Response interface {
-	OperationResponse | Configuration
+	OperationResponse | Configuration | LeadershipTransferResponse
 }
 
 // Future represents an operation that will occur at a later point in time.
@@ -53,6 +54,23 @@ func (f *future[T]) Await() Result[T] {
 	return f.response
 }
 
+// awaitContext retrieves the result of the future. It returns the error of the provided
+// context instead if the context is done before the result is available.
+func (f *future[T]) awaitContext(ctx context.Context) (Result[T], error) {
+	if f.response != nil {
+		return f.response, nil
+	}
+	select {
+	case response := <-f.responseCh:
+		f.response = response
+	case <-time.After(f.timeout):
+		f.response = &result[T]{err: ErrTimeout}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return f.response, nil
+}
+
 // Result represents an abstract result produced by a node after processing a
 // client submitted operation.
 type Result[T Response] interface {
@@ -0,0 +1,786 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/jmsadair/raft/internal/protobuf"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The metadata key used to identify the raft group that an RPC is bound for.
+const groupMetadataKey = "raft-group-id"
+
+// heartbeat is an AppendEntries RPC without log entries that is waiting to be sent
+// to a node in a batch with the heartbeats of other groups.
+type heartbeat struct {
+	// The context of the heartbeat. The heartbeat is not sent if this is cancelled.
+	ctx context.Context
+
+	// The request including the ID of the group that it is bound for.
+	request *pb.GroupAppendEntriesRequest
+
+	// Receives the response to the heartbeat.
+	responseCh chan heartbeatResult
+}
+
+// heartbeatResult is the outcome of sending a heartbeat.
+type heartbeatResult struct {
+	// The response to the heartbeat. Only valid if err is nil.
+	response AppendEntriesResponse
+
+	// Any error that occurred while sending the heartbeat.
+	err error
+}
+
+// heartbeatQueue contains the heartbeats waiting to be sent to a single node.
+type heartbeatQueue struct {
+	// Indicates whether a batch of heartbeats is currently being sent to the node.
+	sending bool
+
+	// The heartbeats that will be sent in the next batch.
+	pending []*heartbeat
+}
+
+// GroupTransport allows many raft groups within a process to share a single listener and RPC
+// server. Each group is given its own Transport by Group, and RPCs are routed to the group that
+// they are bound for using the group ID. Heartbeats from different groups that are bound for the
+// same node are coalesced into a single RPC.
+type GroupTransport struct {
+	pb.UnimplementedRaftServer
+
+	// Indicates whether the transport is started.
+	running bool
+
+	// The local network address. A host name is resolved when the transport is run. An
+	// address of the form unix:///absolute/path or unix:path refers to a Unix domain socket.
+	address string
+
+	// The RPC server shared by all groups.
+	server *grpc.Server
+
+	// The options used to create the RPC server.
+	serverOptions []grpc.ServerOption
+
+	// The transports of the groups that are running. Maps group ID to transport.
+	groups map[string]*groupTransport
+
+	// Protects the groups. This is separate from mu so that groups may be started
+	// and stopped while RPCs are in-flight.
+	groupsMu sync.RWMutex
+
+	// Manages connections to other nodes.
+	connManager *connectionManager
+
+	// The heartbeats waiting to be sent. Maps address to queue.
+	heartbeats map[string]*heartbeatQueue
+
+	// Protects the heartbeat queues.
+	heartbeatMu sync.Mutex
+
+	mu sync.RWMutex
+}
+
+// NewGroupTransport creates a new GroupTransport instance. The same options that may be
+// provided to NewTransport may be provided to secure RPCs using TLS or to configure the
+// underlying gRPC server and connections.
+func NewGroupTransport(address string, opts ...TransportOption) (*GroupTransport, error) {
+	if _, _, err := parseListenAddress(address); err != nil {
+		return nil, fmt.Errorf("could not parse address: %w", err)
+	}
+
+	connManager, serverOptions, err := makeGRPCOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GroupTransport{
+		address:       address,
+		serverOptions: serverOptions,
+		groups:        make(map[string]*groupTransport),
+		connManager:   connManager,
+		heartbeats:    make(map[string]*heartbeatQueue),
+	}, nil
+}
+
+// Run starts serving incoming RPCs for all groups. This must be called before any of
+// the groups are able to send or receive RPCs.
+func (g *GroupTransport) Run() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.running {
+		return nil
+	}
+
+	listener, err := listen(g.address)
+	if err != nil {
+		return fmt.Errorf("could not create listener: %w", err)
+	}
+
+	g.server = grpc.NewServer(g.serverOptions...)
+	pb.RegisterRaftServer(g.server, g)
+	go g.server.Serve(listener)
+	g.running = true
+
+	return nil
+}
+
+// Shutdown stops serving incoming RPCs for all groups.
+func (g *GroupTransport) Shutdown() error {
+	g.mu.Lock()
+	if !g.running {
+		g.mu.Unlock()
+		return nil
+	}
+	g.running = false
+	g.mu.Unlock()
+
+	stopped := make(chan interface{})
+	defer g.connManager.closeAll()
+
+	go func() {
+		g.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-time.After(shutdownGracePeriod):
+		g.server.Stop()
+	case <-stopped:
+		g.server.Stop()
+	}
+
+	return nil
+}
+
+// Address returns the local network address.
+func (g *GroupTransport) Address() string {
+	return g.address
+}
+
+// Group returns the Transport for the group with the provided ID. The group receives RPCs
+// once the Run method of the returned transport is called, which happens when the node
+// using it is started.
+func (g *GroupTransport) Group(groupID string) Transport {
+	return &groupTransport{groupID: groupID, parent: g}
+}
+
+// register routes the RPCs bound for the group of the provided transport to it.
+func (g *GroupTransport) register(transport *groupTransport) error {
+	g.groupsMu.Lock()
+	defer g.groupsMu.Unlock()
+	if _, ok := g.groups[transport.groupID]; ok {
+		return fmt.Errorf("group %s is already running", transport.groupID)
+	}
+	g.groups[transport.groupID] = transport
+	return nil
+}
+
+// deregister stops routing RPCs to the group of the provided transport.
+func (g *GroupTransport) deregister(transport *groupTransport) {
+	g.groupsMu.Lock()
+	defer g.groupsMu.Unlock()
+	if g.groups[transport.groupID] == transport {
+		delete(g.groups, transport.groupID)
+	}
+}
+
+// group returns the transport of the group that the RPC with the provided context is bound for.
+func (g *GroupTransport) group(ctx context.Context) (*groupTransport, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(groupMetadataKey)) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "RPC does not contain a group ID")
+	}
+	return g.lookup(md.Get(groupMetadataKey)[0])
+}
+
+// lookup returns the transport of the group with the provided ID.
+func (g *GroupTransport) lookup(groupID string) (*groupTransport, error) {
+	g.groupsMu.RLock()
+	defer g.groupsMu.RUnlock()
+	transport, ok := g.groups[groupID]
+	if !ok {
+		return nil, status.Errorf(codes.Unavailable, "group %s is not running", groupID)
+	}
+	return transport, nil
+}
+
+// client returns a client for the provided address if the transport is running.
+func (g *GroupTransport) client(address string) (pb.RaftClient, error) {
+	if !g.running {
+		return nil, errors.New("transport is closed")
+	}
+	client, err := g.connManager.getClient(address)
+	if err != nil {
+		return nil, fmt.Errorf("could not get client connection: %w", err)
+	}
+	return client, nil
+}
+
+// sendHeartbeat queues the provided heartbeat to be sent to the provided address and waits
+// for the response. If no heartbeats are currently being sent to the address, the heartbeat
+// is sent immediately. Otherwise, it is sent with the other heartbeats that are queued once
+// the current batch completes.
+func (g *GroupTransport) sendHeartbeat(
+	ctx context.Context,
+	address string,
+	groupID string,
+	request AppendEntriesRequest,
+) (AppendEntriesResponse, error) {
+	heartbeat := &heartbeat{
+		ctx: ctx,
+		request: &pb.GroupAppendEntriesRequest{
+			GroupId: groupID,
+			Request: makeProtoAppendEntriesRequest(request),
+		},
+		responseCh: make(chan heartbeatResult, 1),
+	}
+
+	g.heartbeatMu.Lock()
+	queue, ok := g.heartbeats[address]
+	if !ok {
+		queue = &heartbeatQueue{}
+		g.heartbeats[address] = queue
+	}
+	queue.pending = append(queue.pending, heartbeat)
+	if !queue.sending {
+		queue.sending = true
+		go g.sendHeartbeats(address, queue)
+	}
+	g.heartbeatMu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return AppendEntriesResponse{}, fmt.Errorf("could not make AppendEntries RPC: %w", ctx.Err())
+	case result := <-heartbeat.responseCh:
+		return result.response, result.err
+	}
+}
+
+// sendHeartbeats sends the heartbeats in the provided queue to the provided address in batches
+// until the queue is empty.
+func (g *GroupTransport) sendHeartbeats(address string, queue *heartbeatQueue) {
+	for {
+		g.heartbeatMu.Lock()
+		var batch []*heartbeat
+		for _, heartbeat := range queue.pending {
+			if heartbeat.ctx.Err() == nil {
+				batch = append(batch, heartbeat)
+			}
+		}
+		queue.pending = nil
+		if len(batch) == 0 {
+			queue.sending = false
+			g.heartbeatMu.Unlock()
+			return
+		}
+		g.heartbeatMu.Unlock()
+
+		g.sendHeartbeatBatch(address, batch)
+	}
+}
+
+// sendHeartbeatBatch sends the provided heartbeats to the provided address in a single RPC.
+// The RPC is abandoned once the deadlines of all of the heartbeats have been exceeded.
+func (g *GroupTransport) sendHeartbeatBatch(address string, batch []*heartbeat) {
+	request := &pb.BatchAppendEntriesRequest{
+		Requests: make([]*pb.GroupAppendEntriesRequest, len(batch)),
+	}
+	var deadline time.Time
+	unbounded := false
+	for i, heartbeat := range batch {
+		request.Requests[i] = heartbeat.request
+		d, ok := heartbeat.ctx.Deadline()
+		unbounded = unbounded || !ok
+		if d.After(deadline) {
+			deadline = d
+		}
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if unbounded {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithDeadline(context.Background(), deadline)
+	}
+	defer cancel()
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	fail := func(err error) {
+		for _, heartbeat := range batch {
+			heartbeat.responseCh <- heartbeatResult{err: err}
+		}
+	}
+
+	client, err := g.client(address)
+	if err != nil {
+		fail(fmt.Errorf("could not make AppendEntries RPC: %w", err))
+		return
+	}
+
+	response, err := client.BatchAppendEntries(ctx, request)
+	if err != nil {
+		fail(fmt.Errorf("could not make AppendEntries RPC: %w", err))
+		return
+	}
+	if len(response.GetResponses()) != len(batch) {
+		fail(errors.New("could not make AppendEntries RPC: incomplete batch response"))
+		return
+	}
+
+	for i, heartbeat := range batch {
+		groupResponse := response.GetResponses()[i]
+		if groupResponse.GetError() != "" {
+			heartbeat.responseCh <- heartbeatResult{
+				err: fmt.Errorf("could not make AppendEntries RPC: %s", groupResponse.GetError()),
+			}
+			continue
+		}
+		heartbeat.responseCh <- heartbeatResult{
+			response: makeAppendEntriesResponse(groupResponse.GetResponse()),
+		}
+	}
+}
+
+func (g *GroupTransport) AppendEntries(
+	ctx context.Context,
+	request *pb.AppendEntriesRequest,
+) (*pb.AppendEntriesResponse, error) {
+	group, err := g.group(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appendEntriesRequest := makeAppendEntriesRequest(request)
+	appendEntriesResponse := &AppendEntriesResponse{}
+	if err := group.appendEntriesHandler(ctx, &appendEntriesRequest, appendEntriesResponse); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoAppendEntriesResponse(*appendEntriesResponse), nil
+}
+
+func (g *GroupTransport) BatchAppendEntries(
+	ctx context.Context,
+	request *pb.BatchAppendEntriesRequest,
+) (*pb.BatchAppendEntriesResponse, error) {
+	responses := make([]*pb.GroupAppendEntriesResponse, len(request.GetRequests()))
+
+	// Each group handles its heartbeat concurrently so that a slow group does not delay the others.
+	var wg sync.WaitGroup
+	for i, groupRequest := range request.GetRequests() {
+		wg.Add(1)
+		go func(i int, groupRequest *pb.GroupAppendEntriesRequest) {
+			defer wg.Done()
+			group, err := g.lookup(groupRequest.GetGroupId())
+			if err != nil {
+				responses[i] = &pb.GroupAppendEntriesResponse{Error: err.Error()}
+				return
+			}
+			appendEntriesRequest := makeAppendEntriesRequest(groupRequest.GetRequest())
+			appendEntriesResponse := &AppendEntriesResponse{}
+			if err := group.appendEntriesHandler(ctx, &appendEntriesRequest, appendEntriesResponse); err != nil {
+				responses[i] = &pb.GroupAppendEntriesResponse{Error: err.Error()}
+				return
+			}
+			responses[i] = &pb.GroupAppendEntriesResponse{
+				Response: makeProtoAppendEntriesResponse(*appendEntriesResponse),
+			}
+		}(i, groupRequest)
+	}
+	wg.Wait()
+
+	return &pb.BatchAppendEntriesResponse{Responses: responses}, nil
+}
+
+func (g *GroupTransport) RequestVote(
+	ctx context.Context,
+	request *pb.RequestVoteRequest,
+) (*pb.RequestVoteResponse, error) {
+	group, err := g.group(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requestVoteRequest := makeRequestVoteRequest(request)
+	requestVoteResponse := &RequestVoteResponse{}
+	if err := group.requestVoteHandler(ctx, &requestVoteRequest, requestVoteResponse); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoRequestVoteResponse(*requestVoteResponse), nil
+}
+
+func (g *GroupTransport) InstallSnapshot(
+	ctx context.Context,
+	request *pb.InstallSnapshotRequest,
+) (*pb.InstallSnapshotResponse, error) {
+	group, err := g.group(ctx)
+	if err != nil {
+		return nil, err
+	}
+	installSnapshotRequest := makeInstallSnapshotRequest(request)
+	installSnapshotResponse := &InstallSnapshotResponse{}
+	if err := group.installSnapshotHandler(ctx, &installSnapshotRequest, installSnapshotResponse); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoInstallSnapshotResponse(*installSnapshotResponse), nil
+}
+
+func (g *GroupTransport) FetchSnapshot(
+	ctx context.Context,
+	request *pb.FetchSnapshotRequest,
+) (*pb.FetchSnapshotResponse, error) {
+	group, err := g.group(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fetchSnapshotRequest := makeFetchSnapshotRequest(request)
+	fetchSnapshotResponse := &FetchSnapshotResponse{}
+	if err := group.fetchSnapshotHandler(ctx, &fetchSnapshotRequest, fetchSnapshotResponse); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoFetchSnapshotResponse(*fetchSnapshotResponse), nil
+}
+
+func (g *GroupTransport) TimeoutNow(
+	ctx context.Context,
+	request *pb.TimeoutNowRequest,
+) (*pb.TimeoutNowResponse, error) {
+	group, err := g.group(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timeoutNowRequest := makeTimeoutNowRequest(request)
+	timeoutNowResponse := &TimeoutNowResponse{}
+	if err := group.timeoutNowHandler(ctx, &timeoutNowRequest, timeoutNowResponse); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoTimeoutNowResponse(*timeoutNowResponse), nil
+}
+
+func (g *GroupTransport) ForwardOperation(
+	ctx context.Context,
+	request *pb.ForwardOperationRequest,
+) (*pb.ForwardOperationResponse, error) {
+	group, err := g.group(ctx)
+	if err != nil {
+		return nil, err
+	}
+	forwardOperationRequest := makeForwardOperationRequest(request)
+	forwardOperationResponse := &ForwardOperationResponse{}
+	if err := group.forwardOperationHandler(ctx, &forwardOperationRequest, forwardOperationResponse); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoForwardOperationResponse(*forwardOperationResponse), nil
+}
+
+func (g *GroupTransport) ForwardMembershipChange(
+	ctx context.Context,
+	request *pb.ForwardMembershipChangeRequest,
+) (*pb.ForwardMembershipChangeResponse, error) {
+	group, err := g.group(ctx)
+	if err != nil {
+		return nil, err
+	}
+	forwardMembershipChangeRequest := makeForwardMembershipChangeRequest(request)
+	forwardMembershipChangeResponse := &ForwardMembershipChangeResponse{}
+	if err := group.forwardMembershipChangeHandler(
+		ctx,
+		&forwardMembershipChangeRequest,
+		forwardMembershipChangeResponse,
+	); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoForwardMembershipChangeResponse(*forwardMembershipChangeResponse), nil
+}
+
+// groupTransport is the Transport used by a single group of a GroupTransport.
+type groupTransport struct {
+	// The ID of the group.
+	groupID string
+
+	// The transport shared by all groups.
+	parent *GroupTransport
+
+	// The function that is called when an AppendEntries RPC is received.
+	appendEntriesHandler func(context.Context, *AppendEntriesRequest, *AppendEntriesResponse) error
+
+	// The function that is called when a RequestVote RPC is received.
+	requestVoteHandler func(context.Context, *RequestVoteRequest, *RequestVoteResponse) error
+
+	// The function that is called when an InstallSnapshot RPC is received.
+	installSnapshotHandler func(context.Context, *InstallSnapshotRequest, *InstallSnapshotResponse) error
+
+	// The function that is called when a FetchSnapshot RPC is received.
+	fetchSnapshotHandler func(context.Context, *FetchSnapshotRequest, *FetchSnapshotResponse) error
+
+	// The function that is called when a TimeoutNow RPC is received.
+	timeoutNowHandler func(context.Context, *TimeoutNowRequest, *TimeoutNowResponse) error
+
+	// The function that is called when a ForwardOperation RPC is received.
+	forwardOperationHandler func(context.Context, *ForwardOperationRequest, *ForwardOperationResponse) error
+
+	// The function that is called when a ForwardMembershipChange RPC is received.
+	forwardMembershipChangeHandler func(
+		context.Context,
+		*ForwardMembershipChangeRequest,
+		*ForwardMembershipChangeResponse,
+	) error
+}
+
+func (t *groupTransport) Run() error {
+	if err := t.parent.register(t); err != nil {
+		return fmt.Errorf("could not run transport: %w", err)
+	}
+	return nil
+}
+
+func (t *groupTransport) Shutdown() error {
+	t.parent.deregister(t)
+	return nil
+}
+
+// outgoingContext returns a copy of the provided context that identifies this group.
+func (t *groupTransport) outgoingContext(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, groupMetadataKey, t.groupID)
+}
+
+func (t *groupTransport) SendAppendEntries(
+	ctx context.Context,
+	address string,
+	request AppendEntriesRequest,
+) (AppendEntriesResponse, error) {
+	if len(request.Entries) == 0 {
+		return t.parent.sendHeartbeat(ctx, address, t.groupID, request)
+	}
+
+	t.parent.mu.RLock()
+	defer t.parent.mu.RUnlock()
+
+	client, err := t.parent.client(address)
+	if err != nil {
+		return AppendEntriesResponse{}, fmt.Errorf("could not make AppendEntries RPC: %w", err)
+	}
+
+	pbRequest := makeProtoAppendEntriesRequest(request)
+	pbResponse, err := client.AppendEntries(t.outgoingContext(ctx), pbRequest)
+	if err != nil {
+		return AppendEntriesResponse{}, fmt.Errorf("could not make AppendEntries RPC: %w", err)
+	}
+
+	return makeAppendEntriesResponse(pbResponse), nil
+}
+
+func (t *groupTransport) SendRequestVote(
+	ctx context.Context,
+	address string,
+	request RequestVoteRequest,
+) (RequestVoteResponse, error) {
+	t.parent.mu.RLock()
+	defer t.parent.mu.RUnlock()
+
+	client, err := t.parent.client(address)
+	if err != nil {
+		return RequestVoteResponse{}, fmt.Errorf("could not make RequestVote RPC: %w", err)
+	}
+
+	pbRequest := makeProtoRequestVoteRequest(request)
+	pbResponse, err := client.RequestVote(t.outgoingContext(ctx), pbRequest)
+	if err != nil {
+		return RequestVoteResponse{}, fmt.Errorf("could not make RequestVote RPC: %w", err)
+	}
+
+	return makeRequestVoteResponse(pbResponse), nil
+}
+
+func (t *groupTransport) SendInstallSnapshot(
+	ctx context.Context,
+	address string,
+	request InstallSnapshotRequest,
+) (InstallSnapshotResponse, error) {
+	t.parent.mu.RLock()
+	defer t.parent.mu.RUnlock()
+
+	client, err := t.parent.client(address)
+	if err != nil {
+		return InstallSnapshotResponse{}, fmt.Errorf("could not make InstallSnapshot RPC: %w", err)
+	}
+
+	pbRequest := makeProtoInstallSnapshotRequest(request)
+	pbResponse, err := client.InstallSnapshot(t.outgoingContext(ctx), pbRequest)
+	if err != nil {
+		return InstallSnapshotResponse{}, fmt.Errorf("could not make InstallSnapshot RPC: %w", err)
+	}
+
+	return makeInstallSnapshotResponse(pbResponse), nil
+}
+
+func (t *groupTransport) SendFetchSnapshot(
+	ctx context.Context,
+	address string,
+	request FetchSnapshotRequest,
+) (FetchSnapshotResponse, error) {
+	t.parent.mu.RLock()
+	defer t.parent.mu.RUnlock()
+
+	client, err := t.parent.client(address)
+	if err != nil {
+		return FetchSnapshotResponse{}, fmt.Errorf("could not make FetchSnapshot RPC: %w", err)
+	}
+
+	pbRequest := makeProtoFetchSnapshotRequest(request)
+	pbResponse, err := client.FetchSnapshot(t.outgoingContext(ctx), pbRequest)
+	if err != nil {
+		return FetchSnapshotResponse{}, fmt.Errorf("could not make FetchSnapshot RPC: %w", err)
+	}
+
+	return makeFetchSnapshotResponse(pbResponse), nil
+}
+
+func (t *groupTransport) SendTimeoutNow(
+	ctx context.Context,
+	address string,
+	request TimeoutNowRequest,
+) (TimeoutNowResponse, error) {
+	t.parent.mu.RLock()
+	defer t.parent.mu.RUnlock()
+
+	client, err := t.parent.client(address)
+	if err != nil {
+		return TimeoutNowResponse{}, fmt.Errorf("could not make TimeoutNow RPC: %w", err)
+	}
+
+	pbRequest := makeProtoTimeoutNowRequest(request)
+	pbResponse, err := client.TimeoutNow(t.outgoingContext(ctx), pbRequest)
+	if err != nil {
+		return TimeoutNowResponse{}, fmt.Errorf("could not make TimeoutNow RPC: %w", err)
+	}
+
+	return makeTimeoutNowResponse(pbResponse), nil
+}
+
+func (t *groupTransport) SendForwardOperation(
+	ctx context.Context,
+	address string,
+	request ForwardOperationRequest,
+) (ForwardOperationResponse, error) {
+	t.parent.mu.RLock()
+	defer t.parent.mu.RUnlock()
+
+	client, err := t.parent.client(address)
+	if err != nil {
+		return ForwardOperationResponse{}, fmt.Errorf("could not make ForwardOperation RPC: %w", err)
+	}
+
+	pbRequest := makeProtoForwardOperationRequest(request)
+	pbResponse, err := client.ForwardOperation(t.outgoingContext(ctx), pbRequest)
+	if err != nil {
+		return ForwardOperationResponse{}, fmt.Errorf("could not make ForwardOperation RPC: %w", err)
+	}
+
+	return makeForwardOperationResponse(pbResponse), nil
+}
+
+func (t *groupTransport) SendForwardMembershipChange(
+	ctx context.Context,
+	address string,
+	request ForwardMembershipChangeRequest,
+) (ForwardMembershipChangeResponse, error) {
+	t.parent.mu.RLock()
+	defer t.parent.mu.RUnlock()
+
+	client, err := t.parent.client(address)
+	if err != nil {
+		return ForwardMembershipChangeResponse{}, fmt.Errorf(
+			"could not make ForwardMembershipChange RPC: %w",
+			err,
+		)
+	}
+
+	pbRequest := makeProtoForwardMembershipChangeRequest(request)
+	pbResponse, err := client.ForwardMembershipChange(t.outgoingContext(ctx), pbRequest)
+	if err != nil {
+		return ForwardMembershipChangeResponse{}, fmt.Errorf(
+			"could not make ForwardMembershipChange RPC: %w",
+			err,
+		)
+	}
+
+	return makeForwardMembershipChangeResponse(pbResponse), nil
+}
+
+func (t *groupTransport) RegisterAppendEntriesHandler(
+	handler func(context.Context, *AppendEntriesRequest, *AppendEntriesResponse) error,
+) {
+	t.appendEntriesHandler = handler
+}
+
+func (t *groupTransport) RegisterRequestVoteHandler(
+	handler func(context.Context, *RequestVoteRequest, *RequestVoteResponse) error,
+) {
+	t.requestVoteHandler = handler
+}
+
+func (t *groupTransport) RegsiterInstallSnapshotHandler(
+	handler func(context.Context, *InstallSnapshotRequest, *InstallSnapshotResponse) error,
+) {
+	t.installSnapshotHandler = handler
+}
+
+func (t *groupTransport) RegisterFetchSnapshotHandler(
+	handler func(context.Context, *FetchSnapshotRequest, *FetchSnapshotResponse) error,
+) {
+	t.fetchSnapshotHandler = handler
+}
+
+func (t *groupTransport) RegisterTimeoutNowHandler(
+	handler func(context.Context, *TimeoutNowRequest, *TimeoutNowResponse) error,
+) {
+	t.timeoutNowHandler = handler
+}
+
+func (t *groupTransport) RegisterForwardOperationHandler(
+	handler func(context.Context, *ForwardOperationRequest, *ForwardOperationResponse) error,
+) {
+	t.forwardOperationHandler = handler
+}
+
+func (t *groupTransport) RegisterForwardMembershipChangeHandler(
+	handler func(
+		context.Context,
+		*ForwardMembershipChangeRequest,
+		*ForwardMembershipChangeResponse,
+	) error,
+) {
+	t.forwardMembershipChangeHandler = handler
+}
+
+func (t *groupTransport) EncodeConfiguration(configuration *Configuration) ([]byte, error) {
+	data, err := encodeConfiguration(configuration)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode configuration: %w", err)
+	}
+	return data, nil
+}
+
+func (t *groupTransport) DecodeConfiguration(data []byte) (Configuration, error) {
+	configuration, err := decodeConfiguration(data)
+	if err != nil {
+		return Configuration{}, fmt.Errorf("could not decode configuration: %w", err)
+	}
+	return configuration, nil
+}
+
+// RemovePeer does nothing because the connection to a peer is shared by all groups and
+// the peer may still be a member of other groups.
+func (t *groupTransport) RemovePeer(address string) {}
+
+func (t *groupTransport) Address() string {
+	return t.parent.Address()
+}
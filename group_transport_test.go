@@ -0,0 +1,196 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func startGroupTransport(t *testing.T, address string) *GroupTransport {
+	transport, err := NewGroupTransport(address)
+	require.NoError(t, err)
+	require.NoError(t, transport.Run())
+	t.Cleanup(func() { transport.Shutdown() })
+	return transport
+}
+
+// startGroup starts a group that responds to RPCs with the provided term.
+func startGroup(t *testing.T, transport *GroupTransport, groupID string, term uint64) Transport {
+	group := transport.Group(groupID)
+	group.RegisterAppendEntriesHandler(
+		func(ctx context.Context, request *AppendEntriesRequest, response *AppendEntriesResponse) error {
+			response.Term = term
+			response.Success = true
+			return nil
+		},
+	)
+	group.RegisterRequestVoteHandler(
+		func(ctx context.Context, request *RequestVoteRequest, response *RequestVoteResponse) error {
+			response.Term = term
+			response.VoteGranted = true
+			return nil
+		},
+	)
+	require.NoError(t, group.Run())
+	t.Cleanup(func() { group.Shutdown() })
+	return group
+}
+
+// TestGroupTransportRouting checks that RPCs are delivered to the group they are bound for.
+func TestGroupTransportRouting(t *testing.T) {
+	client := startGroupTransport(t, "127.0.0.1:18090")
+	server := startGroupTransport(t, "127.0.0.1:18091")
+
+	groupA := startGroup(t, client, "a", 1)
+	groupB := startGroup(t, client, "b", 2)
+	startGroup(t, server, "a", 1)
+	startGroup(t, server, "b", 2)
+
+	// A heartbeat.
+	response, err := groupA.SendAppendEntries(context.Background(), server.Address(), AppendEntriesRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), response.Term)
+
+	// An AppendEntries RPC with entries.
+	entries := []*LogEntry{NewLogEntry(1, 1, []byte("entry"), OperationEntry)}
+	response, err = groupB.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Entries: entries},
+	)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), response.Term)
+
+	voteResponse, err := groupB.SendRequestVote(context.Background(), server.Address(), RequestVoteRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), voteResponse.Term)
+
+	// RPCs bound for a group that is not running fail.
+	groupC := startGroup(t, client, "c", 3)
+	_, err = groupC.SendAppendEntries(context.Background(), server.Address(), AppendEntriesRequest{})
+	require.Error(t, err)
+	_, err = groupC.SendRequestVote(context.Background(), server.Address(), RequestVoteRequest{})
+	require.Error(t, err)
+
+	// A group may not be run twice on the same transport.
+	require.Error(t, client.Group("a").Run())
+}
+
+// TestGroupTransportHeartbeatBatching checks that heartbeats from different groups bound
+// for the same node are sent in a single batch.
+func TestGroupTransportHeartbeatBatching(t *testing.T) {
+	client := startGroupTransport(t, "127.0.0.1:18092")
+	server := startGroupTransport(t, "127.0.0.1:18093")
+
+	// Block the first heartbeat so that the following heartbeats are queued.
+	release := make(chan struct{})
+	blocked := server.Group("blocked")
+	blocked.RegisterAppendEntriesHandler(
+		func(ctx context.Context, request *AppendEntriesRequest, response *AppendEntriesResponse) error {
+			<-release
+			response.Success = true
+			return nil
+		},
+	)
+	require.NoError(t, blocked.Run())
+	defer blocked.Shutdown()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := client.Group("blocked").SendAppendEntries(
+			context.Background(),
+			server.Address(),
+			AppendEntriesRequest{},
+		)
+		require.NoError(t, err)
+	}()
+
+	// Wait for the blocked heartbeat to be sent.
+	sending := func() bool {
+		client.heartbeatMu.Lock()
+		defer client.heartbeatMu.Unlock()
+		queue, ok := client.heartbeats[server.Address()]
+		return ok && queue.sending && len(queue.pending) == 0
+	}
+	require.Eventually(t, sending, time.Second, 10*time.Millisecond)
+
+	numGroups := 10
+	for i := 0; i < numGroups; i++ {
+		groupID := fmt.Sprint(i)
+		startGroup(t, server, groupID, uint64(i))
+		group := startGroup(t, client, groupID, uint64(i))
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := group.SendAppendEntries(context.Background(), server.Address(), AppendEntriesRequest{})
+			require.NoError(t, err)
+			require.Equal(t, uint64(i), response.Term)
+		}(i)
+	}
+
+	// Wait for every heartbeat to be queued behind the blocked one.
+	queued := func() int {
+		client.heartbeatMu.Lock()
+		defer client.heartbeatMu.Unlock()
+		if queue, ok := client.heartbeats[server.Address()]; ok {
+			return len(queue.pending)
+		}
+		return 0
+	}
+	require.Eventually(t, func() bool { return queued() == numGroups }, time.Second, 10*time.Millisecond)
+
+	close(release)
+	wg.Wait()
+}
+
+// TestMultiRaft checks that multiple raft groups are able to elect leaders and
+// replicate operations while sharing transports.
+func TestMultiRaft(t *testing.T) {
+	addresses := []string{"127.0.0.1:18094", "127.0.0.1:18095", "127.0.0.1:18096"}
+	members := make(map[string]string)
+	transports := make([]*GroupTransport, len(addresses))
+	for i, address := range addresses {
+		members[fmt.Sprint(i)] = address
+		transports[i] = startGroupTransport(t, address)
+	}
+
+	groups := []string{"a", "b", "c"}
+	nodes := make(map[string][]*Raft)
+	for _, groupID := range groups {
+		for i := range addresses {
+			node, err := makeRaftWithStateMachine(
+				fmt.Sprint(i),
+				addresses[i],
+				t.TempDir(),
+				newStateMachineMock(false, 0),
+				WithTransport(transports[i].Group(groupID)),
+			)
+			require.NoError(t, err)
+			require.NoError(t, node.Bootstrap(members))
+			require.NoError(t, node.Start())
+			defer node.Stop()
+			nodes[groupID] = append(nodes[groupID], node)
+		}
+	}
+
+	// Each group should be able to replicate operations independently of the others.
+	for _, groupID := range groups {
+		operation := []byte("operation " + groupID)
+		require.Eventually(t, func() bool {
+			for _, node := range nodes[groupID] {
+				response := node.SubmitOperation(operation, Replicated, futureTimeout).Await()
+				if response.Error() == nil {
+					return string(response.Success().Operation.Bytes) == string(operation)
+				}
+			}
+			return false
+		}, maxSubmissionTime*time.Second, 50*time.Millisecond)
+	}
+}
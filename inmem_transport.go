@@ -0,0 +1,612 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// LinkConfig describes how messages sent from one in-memory transport to another are delivered.
+type LinkConfig struct {
+	// The minimum amount of time that a message takes to be delivered.
+	MinDelay time.Duration
+
+	// The maximum amount of time that a message takes to be delivered. Each message is delayed
+	// by a random amount between the minimum and maximum delay, so messages that are sent close
+	// together may be delivered out of order.
+	MaxDelay time.Duration
+
+	// The fraction of messages that are dropped. This must be between 0 and 1.
+	DropRate float64
+
+	// The fraction of messages that are held back for an additional ReorderDelay so that they
+	// are delivered after messages that were sent later. This must be between 0 and 1.
+	ReorderRate float64
+
+	// The additional amount of time that a reordered message is held back for.
+	ReorderDelay time.Duration
+}
+
+// link identifies the direction that messages are sent between two addresses.
+type link struct {
+	from string
+	to   string
+}
+
+// InmemNetwork connects in-memory transports within a single process. Messages sent between
+// the transports never touch the network, but their delivery may be programmed to simulate
+// delays, message loss, message reordering, and partitions. This implementation is concurrent
+// safe.
+type InmemNetwork struct {
+	// The transports that are currently running, keyed by address.
+	transports map[string]*InmemTransport
+
+	// The configuration used for links that have not been configured.
+	defaultLink LinkConfig
+
+	// The configuration of each link that has been configured.
+	links map[link]LinkConfig
+
+	// The links that messages may not be sent over.
+	partitions map[link]bool
+
+	// The addresses that may not send or receive messages.
+	disconnected map[string]bool
+
+	// The source of randomness for delays, drops, and reordering.
+	rand *rand.Rand
+
+	mu sync.Mutex
+}
+
+// NewInmemNetwork creates a new in-memory network with no delays, loss, or partitions.
+func NewInmemNetwork() *InmemNetwork {
+	return &InmemNetwork{
+		transports:   make(map[string]*InmemTransport),
+		links:        make(map[link]LinkConfig),
+		partitions:   make(map[link]bool),
+		disconnected: make(map[string]bool),
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// NewTransport creates a new in-memory transport with the provided address that is connected
+// to this network. The transport may not receive messages until it is running.
+func (n *InmemNetwork) NewTransport(address string) *InmemTransport {
+	return &InmemTransport{address: address, network: n}
+}
+
+// SetDefaultLink sets the configuration used for every link that has not been configured
+// with SetLink.
+func (n *InmemNetwork) SetDefaultLink(config LinkConfig) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.defaultLink = config
+}
+
+// SetLink sets the configuration used for messages sent from the first provided address to
+// the second provided address. Messages sent in the opposite direction are unaffected.
+func (n *InmemNetwork) SetLink(from string, to string, config LinkConfig) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.links[link{from: from, to: to}] = config
+}
+
+// Partition prevents messages from being sent from the first provided address to the second
+// provided address. Messages may still be sent in the opposite direction, but the responses to
+// them will not be delivered.
+func (n *InmemNetwork) Partition(from string, to string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.partitions[link{from: from, to: to}] = true
+}
+
+// Heal allows messages to be sent from the first provided address to the second provided
+// address again after a call to Partition.
+func (n *InmemNetwork) Heal(from string, to string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.partitions, link{from: from, to: to})
+}
+
+// Disconnect prevents the provided address from sending or receiving any messages.
+func (n *InmemNetwork) Disconnect(address string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.disconnected[address] = true
+}
+
+// Connect allows the provided address to send and receive messages again after
+// a call to Disconnect.
+func (n *InmemNetwork) Connect(address string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.disconnected, address)
+}
+
+// Reset removes all link configurations, partitions, and disconnections.
+func (n *InmemNetwork) Reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.defaultLink = LinkConfig{}
+	n.links = make(map[link]LinkConfig)
+	n.partitions = make(map[link]bool)
+	n.disconnected = make(map[string]bool)
+}
+
+// register makes the provided transport reachable at its address.
+func (n *InmemNetwork) register(transport *InmemTransport) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.transports[transport.address]; ok {
+		return fmt.Errorf("address %s is already in use", transport.address)
+	}
+	n.transports[transport.address] = transport
+	return nil
+}
+
+// deregister makes the provided transport unreachable.
+func (n *InmemNetwork) deregister(transport *InmemTransport) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.transports[transport.address] == transport {
+		delete(n.transports, transport.address)
+	}
+}
+
+// route determines how a message sent from the first provided address to the second provided
+// address is delivered. It returns how long the message takes to be delivered and whether the
+// message is delivered at all.
+func (n *InmemNetwork) route(from string, to string) (time.Duration, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.disconnected[from] || n.disconnected[to] || n.partitions[link{from: from, to: to}] {
+		return 0, false
+	}
+
+	config, ok := n.links[link{from: from, to: to}]
+	if !ok {
+		config = n.defaultLink
+	}
+
+	if n.rand.Float64() < config.DropRate {
+		return 0, false
+	}
+
+	delay := config.MinDelay
+	if config.MaxDelay > config.MinDelay {
+		delay += time.Duration(n.rand.Int63n(int64(config.MaxDelay - config.MinDelay)))
+	}
+	if n.rand.Float64() < config.ReorderRate {
+		delay += config.ReorderDelay
+	}
+
+	return delay, true
+}
+
+// deliver waits for a message sent from the first provided address to the second provided
+// address to be delivered. It returns the transport at the second address if the message
+// was delivered and an error otherwise. A message is not delivered unless the transports at
+// both addresses are running.
+func (n *InmemNetwork) deliver(ctx context.Context, from string, to string) (*InmemTransport, error) {
+	delay, ok := n.route(from, to)
+	if !ok {
+		return nil, fmt.Errorf("%s is unreachable from %s", to, from)
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.transports[from]; !ok {
+		return nil, fmt.Errorf("%s is not running", from)
+	}
+	transport, ok := n.transports[to]
+	if !ok {
+		return nil, fmt.Errorf("%s is not running", to)
+	}
+
+	return transport, nil
+}
+
+// InmemTransport is a Transport that sends and receives RPCs over an InmemNetwork.
+// It is useful for testing clusters of nodes within a single process.
+type InmemTransport struct {
+	// The address of this transport.
+	address string
+
+	// The network that this transport is connected to.
+	network *InmemNetwork
+
+	// Indicates whether this transport is serving incoming RPCs.
+	running bool
+
+	// The function that is called when an AppendEntries RPC is received.
+	appendEntriesHandler func(context.Context, *AppendEntriesRequest, *AppendEntriesResponse) error
+
+	// The function that is called when a RequestVote RPC is received.
+	requestVoteHandler func(context.Context, *RequestVoteRequest, *RequestVoteResponse) error
+
+	// The function that is called when an InstallSnapshot RPC is received.
+	installSnapshotHandler func(context.Context, *InstallSnapshotRequest, *InstallSnapshotResponse) error
+
+	// The function that is called when a FetchSnapshot RPC is received.
+	fetchSnapshotHandler func(context.Context, *FetchSnapshotRequest, *FetchSnapshotResponse) error
+
+	// The function that is called when a TimeoutNow RPC is received.
+	timeoutNowHandler func(context.Context, *TimeoutNowRequest, *TimeoutNowResponse) error
+
+	// The function that is called when a ForwardOperation RPC is received.
+	forwardOperationHandler func(context.Context, *ForwardOperationRequest, *ForwardOperationResponse) error
+
+	// The function that is called when a ForwardMembershipChange RPC is received.
+	forwardMembershipChangeHandler func(
+		context.Context,
+		*ForwardMembershipChangeRequest,
+		*ForwardMembershipChangeResponse,
+	) error
+
+	mu sync.Mutex
+}
+
+func (t *InmemTransport) Run() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		return nil
+	}
+	if err := t.network.register(t); err != nil {
+		return fmt.Errorf("could not run transport: %w", err)
+	}
+	t.running = true
+	return nil
+}
+
+func (t *InmemTransport) Shutdown() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.running {
+		return nil
+	}
+	t.network.deregister(t)
+	t.running = false
+	return nil
+}
+
+func (t *InmemTransport) SendAppendEntries(
+	ctx context.Context,
+	address string,
+	request AppendEntriesRequest,
+) (AppendEntriesResponse, error) {
+	peer, err := t.network.deliver(ctx, t.address, address)
+	if err != nil {
+		return AppendEntriesResponse{}, fmt.Errorf("could not send AppendEntries RPC: %w", err)
+	}
+
+	entries := make([]*LogEntry, len(request.Entries))
+	for i, entry := range request.Entries {
+		entries[i] = NewLogEntry(entry.Index, entry.Term, bytes.Clone(entry.Data), entry.EntryType)
+	}
+	request.Entries = entries
+
+	var response AppendEntriesResponse
+	peer.mu.Lock()
+	handler := peer.appendEntriesHandler
+	peer.mu.Unlock()
+	if handler == nil {
+		return AppendEntriesResponse{}, fmt.Errorf(
+			"could not send AppendEntries RPC: %s has no handler",
+			address,
+		)
+	}
+	if err := handler(ctx, &request, &response); err != nil {
+		return AppendEntriesResponse{}, fmt.Errorf("could not send AppendEntries RPC: %w", err)
+	}
+
+	if _, err := t.network.deliver(ctx, address, t.address); err != nil {
+		return AppendEntriesResponse{}, fmt.Errorf("could not send AppendEntries RPC: %w", err)
+	}
+
+	return response, nil
+}
+
+func (t *InmemTransport) SendRequestVote(
+	ctx context.Context,
+	address string,
+	request RequestVoteRequest,
+) (RequestVoteResponse, error) {
+	peer, err := t.network.deliver(ctx, t.address, address)
+	if err != nil {
+		return RequestVoteResponse{}, fmt.Errorf("could not send RequestVote RPC: %w", err)
+	}
+
+	var response RequestVoteResponse
+	peer.mu.Lock()
+	handler := peer.requestVoteHandler
+	peer.mu.Unlock()
+	if handler == nil {
+		return RequestVoteResponse{}, fmt.Errorf(
+			"could not send RequestVote RPC: %s has no handler",
+			address,
+		)
+	}
+	if err := handler(ctx, &request, &response); err != nil {
+		return RequestVoteResponse{}, fmt.Errorf("could not send RequestVote RPC: %w", err)
+	}
+
+	if _, err := t.network.deliver(ctx, address, t.address); err != nil {
+		return RequestVoteResponse{}, fmt.Errorf("could not send RequestVote RPC: %w", err)
+	}
+
+	return response, nil
+}
+
+func (t *InmemTransport) SendInstallSnapshot(
+	ctx context.Context,
+	address string,
+	request InstallSnapshotRequest,
+) (InstallSnapshotResponse, error) {
+	peer, err := t.network.deliver(ctx, t.address, address)
+	if err != nil {
+		return InstallSnapshotResponse{}, fmt.Errorf("could not send InstallSnapshot RPC: %w", err)
+	}
+
+	request.Configuration = bytes.Clone(request.Configuration)
+	request.Bytes = bytes.Clone(request.Bytes)
+
+	var response InstallSnapshotResponse
+	peer.mu.Lock()
+	handler := peer.installSnapshotHandler
+	peer.mu.Unlock()
+	if handler == nil {
+		return InstallSnapshotResponse{}, fmt.Errorf(
+			"could not send InstallSnapshot RPC: %s has no handler",
+			address,
+		)
+	}
+	if err := handler(ctx, &request, &response); err != nil {
+		return InstallSnapshotResponse{}, fmt.Errorf("could not send InstallSnapshot RPC: %w", err)
+	}
+
+	if _, err := t.network.deliver(ctx, address, t.address); err != nil {
+		return InstallSnapshotResponse{}, fmt.Errorf("could not send InstallSnapshot RPC: %w", err)
+	}
+
+	return response, nil
+}
+
+func (t *InmemTransport) SendFetchSnapshot(
+	ctx context.Context,
+	address string,
+	request FetchSnapshotRequest,
+) (FetchSnapshotResponse, error) {
+	peer, err := t.network.deliver(ctx, t.address, address)
+	if err != nil {
+		return FetchSnapshotResponse{}, fmt.Errorf("could not send FetchSnapshot RPC: %w", err)
+	}
+
+	var response FetchSnapshotResponse
+	peer.mu.Lock()
+	handler := peer.fetchSnapshotHandler
+	peer.mu.Unlock()
+	if handler == nil {
+		return FetchSnapshotResponse{}, fmt.Errorf(
+			"could not send FetchSnapshot RPC: %s has no handler",
+			address,
+		)
+	}
+	if err := handler(ctx, &request, &response); err != nil {
+		return FetchSnapshotResponse{}, fmt.Errorf("could not send FetchSnapshot RPC: %w", err)
+	}
+
+	if _, err := t.network.deliver(ctx, address, t.address); err != nil {
+		return FetchSnapshotResponse{}, fmt.Errorf("could not send FetchSnapshot RPC: %w", err)
+	}
+
+	response.Configuration = bytes.Clone(response.Configuration)
+	response.Bytes = bytes.Clone(response.Bytes)
+
+	return response, nil
+}
+
+func (t *InmemTransport) SendTimeoutNow(
+	ctx context.Context,
+	address string,
+	request TimeoutNowRequest,
+) (TimeoutNowResponse, error) {
+	peer, err := t.network.deliver(ctx, t.address, address)
+	if err != nil {
+		return TimeoutNowResponse{}, fmt.Errorf("could not send TimeoutNow RPC: %w", err)
+	}
+
+	var response TimeoutNowResponse
+	peer.mu.Lock()
+	handler := peer.timeoutNowHandler
+	peer.mu.Unlock()
+	if handler == nil {
+		return TimeoutNowResponse{}, fmt.Errorf(
+			"could not send TimeoutNow RPC: %s has no handler",
+			address,
+		)
+	}
+	if err := handler(ctx, &request, &response); err != nil {
+		return TimeoutNowResponse{}, fmt.Errorf("could not send TimeoutNow RPC: %w", err)
+	}
+
+	if _, err := t.network.deliver(ctx, address, t.address); err != nil {
+		return TimeoutNowResponse{}, fmt.Errorf("could not send TimeoutNow RPC: %w", err)
+	}
+
+	return response, nil
+}
+
+func (t *InmemTransport) SendForwardOperation(
+	ctx context.Context,
+	address string,
+	request ForwardOperationRequest,
+) (ForwardOperationResponse, error) {
+	peer, err := t.network.deliver(ctx, t.address, address)
+	if err != nil {
+		return ForwardOperationResponse{}, fmt.Errorf("could not send ForwardOperation RPC: %w", err)
+	}
+
+	request.Operation = bytes.Clone(request.Operation)
+
+	var response ForwardOperationResponse
+	peer.mu.Lock()
+	handler := peer.forwardOperationHandler
+	peer.mu.Unlock()
+	if handler == nil {
+		return ForwardOperationResponse{}, fmt.Errorf(
+			"could not send ForwardOperation RPC: %s has no handler",
+			address,
+		)
+	}
+	if err := handler(ctx, &request, &response); err != nil {
+		return ForwardOperationResponse{}, fmt.Errorf("could not send ForwardOperation RPC: %w", err)
+	}
+
+	if _, err := t.network.deliver(ctx, address, t.address); err != nil {
+		return ForwardOperationResponse{}, fmt.Errorf("could not send ForwardOperation RPC: %w", err)
+	}
+
+	response.ApplicationResponse = bytes.Clone(response.ApplicationResponse)
+
+	return response, nil
+}
+
+func (t *InmemTransport) SendForwardMembershipChange(
+	ctx context.Context,
+	address string,
+	request ForwardMembershipChangeRequest,
+) (ForwardMembershipChangeResponse, error) {
+	peer, err := t.network.deliver(ctx, t.address, address)
+	if err != nil {
+		return ForwardMembershipChangeResponse{}, fmt.Errorf(
+			"could not send ForwardMembershipChange RPC: %w",
+			err,
+		)
+	}
+
+	var response ForwardMembershipChangeResponse
+	peer.mu.Lock()
+	handler := peer.forwardMembershipChangeHandler
+	peer.mu.Unlock()
+	if handler == nil {
+		return ForwardMembershipChangeResponse{}, fmt.Errorf(
+			"could not send ForwardMembershipChange RPC: %s has no handler",
+			address,
+		)
+	}
+	if err := handler(ctx, &request, &response); err != nil {
+		return ForwardMembershipChangeResponse{}, fmt.Errorf(
+			"could not send ForwardMembershipChange RPC: %w",
+			err,
+		)
+	}
+
+	if _, err := t.network.deliver(ctx, address, t.address); err != nil {
+		return ForwardMembershipChangeResponse{}, fmt.Errorf(
+			"could not send ForwardMembershipChange RPC: %w",
+			err,
+		)
+	}
+
+	response.Configuration = bytes.Clone(response.Configuration)
+
+	return response, nil
+}
+
+func (t *InmemTransport) RegisterAppendEntriesHandler(
+	handler func(context.Context, *AppendEntriesRequest, *AppendEntriesResponse) error,
+) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.appendEntriesHandler = handler
+}
+
+func (t *InmemTransport) RegisterRequestVoteHandler(
+	handler func(context.Context, *RequestVoteRequest, *RequestVoteResponse) error,
+) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requestVoteHandler = handler
+}
+
+func (t *InmemTransport) RegsiterInstallSnapshotHandler(
+	handler func(context.Context, *InstallSnapshotRequest, *InstallSnapshotResponse) error,
+) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.installSnapshotHandler = handler
+}
+
+func (t *InmemTransport) RegisterFetchSnapshotHandler(
+	handler func(context.Context, *FetchSnapshotRequest, *FetchSnapshotResponse) error,
+) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.fetchSnapshotHandler = handler
+}
+
+func (t *InmemTransport) RegisterTimeoutNowHandler(
+	handler func(context.Context, *TimeoutNowRequest, *TimeoutNowResponse) error,
+) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timeoutNowHandler = handler
+}
+
+func (t *InmemTransport) RegisterForwardOperationHandler(
+	handler func(context.Context, *ForwardOperationRequest, *ForwardOperationResponse) error,
+) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.forwardOperationHandler = handler
+}
+
+func (t *InmemTransport) RegisterForwardMembershipChangeHandler(
+	handler func(
+		context.Context,
+		*ForwardMembershipChangeRequest,
+		*ForwardMembershipChangeResponse,
+	) error,
+) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.forwardMembershipChangeHandler = handler
+}
+
+func (t *InmemTransport) EncodeConfiguration(configuration *Configuration) ([]byte, error) {
+	data, err := encodeConfiguration(configuration)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode configuration: %w", err)
+	}
+	return data, nil
+}
+
+func (t *InmemTransport) DecodeConfiguration(data []byte) (Configuration, error) {
+	configuration, err := decodeConfiguration(data)
+	if err != nil {
+		return Configuration{}, fmt.Errorf("could not decode configuration: %w", err)
+	}
+	return configuration, nil
+}
+
+func (t *InmemTransport) RemovePeer(address string) {}
+
+func (t *InmemTransport) Address() string {
+	return t.address
+}
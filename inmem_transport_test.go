@@ -0,0 +1,145 @@
+package raft
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func startInmemTransport(t *testing.T, network *InmemNetwork, address string) *InmemTransport {
+	transport := network.NewTransport(address)
+	transport.RegisterRequestVoteHandler(
+		func(ctx context.Context, request *RequestVoteRequest, response *RequestVoteResponse) error {
+			response.Term = request.Term
+			response.VoteGranted = true
+			return nil
+		},
+	)
+	transport.RegisterAppendEntriesHandler(
+		func(ctx context.Context, request *AppendEntriesRequest, response *AppendEntriesResponse) error {
+			response.Term = request.Term
+			response.Success = true
+			response.Index = request.PrevLogIndex + uint64(len(request.Entries))
+			return nil
+		},
+	)
+	require.NoError(t, transport.Run())
+	t.Cleanup(func() { transport.Shutdown() })
+	return transport
+}
+
+func TestInmemTransportSend(t *testing.T) {
+	network := NewInmemNetwork()
+	transport1 := startInmemTransport(t, network, "node1")
+	startInmemTransport(t, network, "node2")
+
+	response, err := transport1.SendRequestVote(
+		context.Background(),
+		"node2",
+		RequestVoteRequest{CandidateID: "node1", Term: 2},
+	)
+	require.NoError(t, err)
+	require.True(t, response.VoteGranted)
+	require.Equal(t, uint64(2), response.Term)
+
+	entries := []*LogEntry{NewLogEntry(1, 1, []byte("entry"), OperationEntry)}
+	appendResponse, err := transport1.SendAppendEntries(
+		context.Background(),
+		"node2",
+		AppendEntriesRequest{Term: 1, Entries: entries},
+	)
+	require.NoError(t, err)
+	require.True(t, appendResponse.Success)
+	require.Equal(t, uint64(1), appendResponse.Index)
+
+	// A transport that is not running is unreachable.
+	_, err = transport1.SendRequestVote(context.Background(), "node3", RequestVoteRequest{})
+	require.Error(t, err)
+
+	// Two transports may not run at the same address.
+	require.Error(t, network.NewTransport("node2").Run())
+
+	// A peer without a handler for an RPC returns an error.
+	_, err = transport1.SendTimeoutNow(context.Background(), "node2", TimeoutNowRequest{})
+	require.Error(t, err)
+
+	// A transport that is not running may not send RPCs.
+	require.NoError(t, transport1.Shutdown())
+	_, err = transport1.SendRequestVote(context.Background(), "node2", RequestVoteRequest{})
+	require.Error(t, err)
+}
+
+func TestInmemTransportPartition(t *testing.T) {
+	network := NewInmemNetwork()
+	transport1 := startInmemTransport(t, network, "node1")
+	transport2 := startInmemTransport(t, network, "node2")
+
+	// Requests from node1 to node2 are dropped, as are the responses to requests
+	// from node2 to node1.
+	network.Partition("node1", "node2")
+	_, err := transport1.SendRequestVote(context.Background(), "node2", RequestVoteRequest{})
+	require.Error(t, err)
+	_, err = transport2.SendRequestVote(context.Background(), "node1", RequestVoteRequest{})
+	require.Error(t, err)
+
+	network.Heal("node1", "node2")
+	_, err = transport1.SendRequestVote(context.Background(), "node2", RequestVoteRequest{})
+	require.NoError(t, err)
+
+	// A disconnected transport may not send or receive messages.
+	network.Disconnect("node2")
+	_, err = transport1.SendRequestVote(context.Background(), "node2", RequestVoteRequest{})
+	require.Error(t, err)
+	_, err = transport2.SendRequestVote(context.Background(), "node1", RequestVoteRequest{})
+	require.Error(t, err)
+
+	network.Connect("node2")
+	_, err = transport2.SendRequestVote(context.Background(), "node1", RequestVoteRequest{})
+	require.NoError(t, err)
+}
+
+func TestInmemTransportLink(t *testing.T) {
+	network := NewInmemNetwork()
+	transport1 := startInmemTransport(t, network, "node1")
+	startInmemTransport(t, network, "node2")
+
+	// Every message is dropped.
+	network.SetLink("node1", "node2", LinkConfig{DropRate: 1})
+	_, err := transport1.SendRequestVote(context.Background(), "node2", RequestVoteRequest{})
+	require.Error(t, err)
+
+	// Messages are delayed in both directions.
+	delayed := LinkConfig{MinDelay: 50 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	network.SetDefaultLink(delayed)
+	network.SetLink("node1", "node2", delayed)
+	start := time.Now()
+	_, err = transport1.SendRequestVote(context.Background(), "node2", RequestVoteRequest{})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// The RPC is abandoned if the deadline is exceeded before the message is delivered.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = transport1.SendRequestVote(ctx, "node2", RequestVoteRequest{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// A reordered message is delivered after a message that is sent later.
+	network.Reset()
+	network.SetLink(
+		"node1",
+		"node2",
+		LinkConfig{ReorderRate: 1, ReorderDelay: 100 * time.Millisecond},
+	)
+	reordered := make(chan time.Time)
+	go func() {
+		transport1.SendRequestVote(context.Background(), "node2", RequestVoteRequest{})
+		reordered <- time.Now()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	network.SetLink("node1", "node2", LinkConfig{})
+	_, err = transport1.SendRequestVote(context.Background(), "node2", RequestVoteRequest{})
+	require.NoError(t, err)
+	require.True(t, time.Now().Before(<-reordered))
+}
@@ -23,8 +23,14 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type RaftClient interface {
 	AppendEntries(ctx context.Context, in *AppendEntriesRequest, opts ...grpc.CallOption) (*AppendEntriesResponse, error)
+	AppendEntriesStream(ctx context.Context, opts ...grpc.CallOption) (Raft_AppendEntriesStreamClient, error)
 	RequestVote(ctx context.Context, in *RequestVoteRequest, opts ...grpc.CallOption) (*RequestVoteResponse, error)
 	InstallSnapshot(ctx context.Context, in *InstallSnapshotRequest, opts ...grpc.CallOption) (*InstallSnapshotResponse, error)
+	FetchSnapshot(ctx context.Context, in *FetchSnapshotRequest, opts ...grpc.CallOption) (*FetchSnapshotResponse, error)
+	TimeoutNow(ctx context.Context, in *TimeoutNowRequest, opts ...grpc.CallOption) (*TimeoutNowResponse, error)
+	BatchAppendEntries(ctx context.Context, in *BatchAppendEntriesRequest, opts ...grpc.CallOption) (*BatchAppendEntriesResponse, error)
+	ForwardOperation(ctx context.Context, in *ForwardOperationRequest, opts ...grpc.CallOption) (*ForwardOperationResponse, error)
+	ForwardMembershipChange(ctx context.Context, in *ForwardMembershipChangeRequest, opts ...grpc.CallOption) (*ForwardMembershipChangeResponse, error)
 }
 
 type raftClient struct {
@@ -44,6 +50,37 @@ func (c *raftClient) AppendEntries(ctx context.Context, in *AppendEntriesRequest
 	return out, nil
 }
 
+func (c *raftClient) AppendEntriesStream(ctx context.Context, opts ...grpc.CallOption) (Raft_AppendEntriesStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Raft_ServiceDesc.Streams[0], "/Raft/AppendEntriesStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &raftAppendEntriesStreamClient{stream}
+	return x, nil
+}
+
+type Raft_AppendEntriesStreamClient interface {
+	Send(*AppendEntriesRequest) error
+	Recv() (*AppendEntriesResponse, error)
+	grpc.ClientStream
+}
+
+type raftAppendEntriesStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *raftAppendEntriesStreamClient) Send(m *AppendEntriesRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *raftAppendEntriesStreamClient) Recv() (*AppendEntriesResponse, error) {
+	m := new(AppendEntriesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *raftClient) RequestVote(ctx context.Context, in *RequestVoteRequest, opts ...grpc.CallOption) (*RequestVoteResponse, error) {
 	out := new(RequestVoteResponse)
 	err := c.cc.Invoke(ctx, "/Raft/RequestVote", in, out, opts...)
@@ -62,13 +99,64 @@ func (c *raftClient) InstallSnapshot(ctx context.Context, in *InstallSnapshotReq
 	return out, nil
 }
 
+func (c *raftClient) FetchSnapshot(ctx context.Context, in *FetchSnapshotRequest, opts ...grpc.CallOption) (*FetchSnapshotResponse, error) {
+	out := new(FetchSnapshotResponse)
+	err := c.cc.Invoke(ctx, "/Raft/FetchSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftClient) TimeoutNow(ctx context.Context, in *TimeoutNowRequest, opts ...grpc.CallOption) (*TimeoutNowResponse, error) {
+	out := new(TimeoutNowResponse)
+	err := c.cc.Invoke(ctx, "/Raft/TimeoutNow", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftClient) BatchAppendEntries(ctx context.Context, in *BatchAppendEntriesRequest, opts ...grpc.CallOption) (*BatchAppendEntriesResponse, error) {
+	out := new(BatchAppendEntriesResponse)
+	err := c.cc.Invoke(ctx, "/Raft/BatchAppendEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftClient) ForwardOperation(ctx context.Context, in *ForwardOperationRequest, opts ...grpc.CallOption) (*ForwardOperationResponse, error) {
+	out := new(ForwardOperationResponse)
+	err := c.cc.Invoke(ctx, "/Raft/ForwardOperation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftClient) ForwardMembershipChange(ctx context.Context, in *ForwardMembershipChangeRequest, opts ...grpc.CallOption) (*ForwardMembershipChangeResponse, error) {
+	out := new(ForwardMembershipChangeResponse)
+	err := c.cc.Invoke(ctx, "/Raft/ForwardMembershipChange", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RaftServer is the server API for Raft service.
 // All implementations must embed UnimplementedRaftServer
 // for forward compatibility
 type RaftServer interface {
 	AppendEntries(context.Context, *AppendEntriesRequest) (*AppendEntriesResponse, error)
+	AppendEntriesStream(Raft_AppendEntriesStreamServer) error
 	RequestVote(context.Context, *RequestVoteRequest) (*RequestVoteResponse, error)
 	InstallSnapshot(context.Context, *InstallSnapshotRequest) (*InstallSnapshotResponse, error)
+	FetchSnapshot(context.Context, *FetchSnapshotRequest) (*FetchSnapshotResponse, error)
+	TimeoutNow(context.Context, *TimeoutNowRequest) (*TimeoutNowResponse, error)
+	BatchAppendEntries(context.Context, *BatchAppendEntriesRequest) (*BatchAppendEntriesResponse, error)
+	ForwardOperation(context.Context, *ForwardOperationRequest) (*ForwardOperationResponse, error)
+	ForwardMembershipChange(context.Context, *ForwardMembershipChangeRequest) (*ForwardMembershipChangeResponse, error)
 	mustEmbedUnimplementedRaftServer()
 }
 
@@ -79,12 +167,30 @@ type UnimplementedRaftServer struct {
 func (UnimplementedRaftServer) AppendEntries(context.Context, *AppendEntriesRequest) (*AppendEntriesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AppendEntries not implemented")
 }
+func (UnimplementedRaftServer) AppendEntriesStream(Raft_AppendEntriesStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method AppendEntriesStream not implemented")
+}
 func (UnimplementedRaftServer) RequestVote(context.Context, *RequestVoteRequest) (*RequestVoteResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RequestVote not implemented")
 }
 func (UnimplementedRaftServer) InstallSnapshot(context.Context, *InstallSnapshotRequest) (*InstallSnapshotResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method InstallSnapshot not implemented")
 }
+func (UnimplementedRaftServer) FetchSnapshot(context.Context, *FetchSnapshotRequest) (*FetchSnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FetchSnapshot not implemented")
+}
+func (UnimplementedRaftServer) TimeoutNow(context.Context, *TimeoutNowRequest) (*TimeoutNowResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TimeoutNow not implemented")
+}
+func (UnimplementedRaftServer) BatchAppendEntries(context.Context, *BatchAppendEntriesRequest) (*BatchAppendEntriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchAppendEntries not implemented")
+}
+func (UnimplementedRaftServer) ForwardOperation(context.Context, *ForwardOperationRequest) (*ForwardOperationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForwardOperation not implemented")
+}
+func (UnimplementedRaftServer) ForwardMembershipChange(context.Context, *ForwardMembershipChangeRequest) (*ForwardMembershipChangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForwardMembershipChange not implemented")
+}
 func (UnimplementedRaftServer) mustEmbedUnimplementedRaftServer() {}
 
 // UnsafeRaftServer may be embedded to opt out of forward compatibility for this service.
@@ -116,6 +222,32 @@ func _Raft_AppendEntries_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Raft_AppendEntriesStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RaftServer).AppendEntriesStream(&raftAppendEntriesStreamServer{stream})
+}
+
+type Raft_AppendEntriesStreamServer interface {
+	Send(*AppendEntriesResponse) error
+	Recv() (*AppendEntriesRequest, error)
+	grpc.ServerStream
+}
+
+type raftAppendEntriesStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *raftAppendEntriesStreamServer) Send(m *AppendEntriesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *raftAppendEntriesStreamServer) Recv() (*AppendEntriesRequest, error) {
+	m := new(AppendEntriesRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _Raft_RequestVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RequestVoteRequest)
 	if err := dec(in); err != nil {
@@ -152,6 +284,96 @@ func _Raft_InstallSnapshot_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Raft_FetchSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchSnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftServer).FetchSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Raft/FetchSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftServer).FetchSnapshot(ctx, req.(*FetchSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Raft_TimeoutNow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TimeoutNowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftServer).TimeoutNow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Raft/TimeoutNow",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftServer).TimeoutNow(ctx, req.(*TimeoutNowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Raft_BatchAppendEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchAppendEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftServer).BatchAppendEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Raft/BatchAppendEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftServer).BatchAppendEntries(ctx, req.(*BatchAppendEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Raft_ForwardOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForwardOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftServer).ForwardOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Raft/ForwardOperation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftServer).ForwardOperation(ctx, req.(*ForwardOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Raft_ForwardMembershipChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForwardMembershipChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftServer).ForwardMembershipChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Raft/ForwardMembershipChange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftServer).ForwardMembershipChange(ctx, req.(*ForwardMembershipChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Raft_ServiceDesc is the grpc.ServiceDesc for Raft service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -171,7 +393,34 @@ var Raft_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "InstallSnapshot",
 			Handler:    _Raft_InstallSnapshot_Handler,
 		},
+		{
+			MethodName: "FetchSnapshot",
+			Handler:    _Raft_FetchSnapshot_Handler,
+		},
+		{
+			MethodName: "TimeoutNow",
+			Handler:    _Raft_TimeoutNow_Handler,
+		},
+		{
+			MethodName: "BatchAppendEntries",
+			Handler:    _Raft_BatchAppendEntries_Handler,
+		},
+		{
+			MethodName: "ForwardOperation",
+			Handler:    _Raft_ForwardOperation_Handler,
+		},
+		{
+			MethodName: "ForwardMembershipChange",
+			Handler:    _Raft_ForwardMembershipChange_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "AppendEntriesStream",
+			Handler:       _Raft_AppendEntriesStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/protobuf/raft.proto",
 }
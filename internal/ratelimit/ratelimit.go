@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket that limits the rate at which some resource is used.
+// Tokens are added to the bucket at a fixed rate up to the size of the bucket.
+// It is safe for concurrent use.
+type Limiter struct {
+	// The number of tokens added to the bucket per second.
+	rate float64
+
+	// The maximum number of tokens that the bucket may hold.
+	burst float64
+
+	// The number of tokens in the bucket. This is negative if tokens
+	// have been reserved that have not yet been added.
+	tokens float64
+
+	// The last time that the tokens in the bucket were updated.
+	last time.Time
+
+	mu sync.Mutex
+}
+
+// NewLimiter creates a new limiter that adds the provided number of tokens per second
+// to a bucket that may hold at most burst tokens. The bucket starts full.
+func NewLimiter(rate int64, burst int64) *Limiter {
+	return &Limiter{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Reserve takes the provided number of tokens from the bucket and returns how long the
+// caller must wait before the tokens may be used. The number of tokens may exceed the
+// size of the bucket, in which case the caller must wait for the bucket to refill.
+func (l *Limiter) Reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
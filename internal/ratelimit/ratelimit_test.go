@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLimiterReserve checks that tokens may be used immediately while the bucket has them
+// and that the caller must wait for the bucket to refill once it is empty.
+func TestLimiterReserve(t *testing.T) {
+	limiter := NewLimiter(1000, 1000)
+
+	// The bucket starts full.
+	require.Zero(t, limiter.Reserve(600))
+	require.Zero(t, limiter.Reserve(400))
+
+	// The bucket is empty, so the caller must wait roughly one second for 1000 tokens.
+	wait := limiter.Reserve(1000)
+	require.Greater(t, wait, 900*time.Millisecond)
+	require.LessOrEqual(t, wait, time.Second)
+
+	// Reservations that exceed the bucket wait for each other.
+	wait = limiter.Reserve(2000)
+	require.Greater(t, wait, 2900*time.Millisecond)
+	require.LessOrEqual(t, wait, 3*time.Second)
+}
@@ -0,0 +1,515 @@
+// Package s3 provides a minimal client for S3-compatible object stores. Only the
+// operations required for storing snapshots are supported. Requests are signed
+// using AWS Signature Version 4 and use path-style addressing so that the client
+// works with both AWS and self-hosted S3-compatible stores.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The algorithm used to sign requests.
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+
+	// The service name used when signing requests.
+	signingService = "s3"
+
+	// The format of the timestamp used when signing requests.
+	amzDateFormat = "20060102T150405Z"
+
+	// The format of the date used in the credential scope.
+	shortDateFormat = "20060102"
+
+	// The default region used when one is not provided.
+	defaultRegion = "us-east-1"
+)
+
+// ErrNotFound is returned when the requested object or upload does not exist.
+var ErrNotFound = errors.New("the specified key does not exist")
+
+// Config contains the information required to connect to a bucket.
+type Config struct {
+	// The URL of the S3-compatible endpoint (e.g. https://s3.us-east-1.amazonaws.com).
+	Endpoint string
+
+	// The name of the bucket.
+	Bucket string
+
+	// The region of the bucket. Defaults to us-east-1.
+	Region string
+
+	// The access key ID used to sign requests.
+	AccessKeyID string
+
+	// The secret access key used to sign requests.
+	SecretAccessKey string
+
+	// The HTTP client used to make requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Part is a part of a multipart upload that has been uploaded.
+type Part struct {
+	// The part number of the part.
+	PartNumber int `xml:"PartNumber"`
+
+	// The entity tag returned when the part was uploaded.
+	ETag string `xml:"ETag"`
+}
+
+// Upload is a multipart upload that has been created but not yet completed or aborted.
+type Upload struct {
+	// The key of the object that the upload is for.
+	Key string `xml:"Key"`
+
+	// The ID of the upload.
+	UploadID string `xml:"UploadId"`
+}
+
+// Client is a client for an S3-compatible bucket. It is safe for concurrent use.
+type Client struct {
+	endpoint        *url.URL
+	bucket          string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	httpClient      *http.Client
+
+	// Returns the current time. Replaceable for testing.
+	now func() time.Time
+}
+
+// NewClient creates a new client for the bucket described by the provided configuration.
+func NewClient(config Config) (*Client, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("bucket must not be empty")
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse endpoint: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("endpoint must use http or https: endpoint = %s", config.Endpoint)
+	}
+	region := config.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		endpoint:        endpoint,
+		bucket:          config.Bucket,
+		region:          region,
+		accessKeyID:     config.AccessKeyID,
+		secretAccessKey: config.SecretAccessKey,
+		httpClient:      httpClient,
+		now:             time.Now,
+	}, nil
+}
+
+// PutObject writes the provided data to the object with the provided key.
+func (c *Client) PutObject(ctx context.Context, key string, data []byte) error {
+	response, err := c.do(ctx, http.MethodPut, key, nil, nil, data)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// GetObject returns a reader for the content of the object with the provided key starting
+// at the provided offset. It is the caller's responsibility to close the reader.
+func (c *Client) GetObject(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	var header http.Header
+	if offset > 0 {
+		header = http.Header{"Range": []string{fmt.Sprintf("bytes=%d-", offset)}}
+	}
+	response, err := c.do(ctx, http.MethodGet, key, nil, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// HeadObject returns the size of the object with the provided key.
+func (c *Client) HeadObject(ctx context.Context, key string) (int64, error) {
+	response, err := c.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	return response.ContentLength, nil
+}
+
+// ListObjects returns the keys of all objects that begin with the provided prefix in
+// lexicographical order.
+func (c *Client) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	var continuationToken string
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		var result listBucketResult
+		if err := c.doXML(ctx, http.MethodGet, "", query, nil, &result); err != nil {
+			return nil, err
+		}
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated {
+			return keys, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// CreateMultipartUpload starts a multipart upload for the object with the provided key and
+// returns the ID of the upload.
+func (c *Client) CreateMultipartUpload(ctx context.Context, key string) (string, error) {
+	var result initiateMultipartUploadResult
+	if err := c.doXML(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, &result); err != nil {
+		return "", err
+	}
+	return result.UploadID, nil
+}
+
+// UploadPart uploads a part of a multipart upload and returns the uploaded part.
+// Part numbers start at one.
+func (c *Client) UploadPart(
+	ctx context.Context,
+	key string,
+	uploadID string,
+	partNumber int,
+	data []byte,
+) (Part, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+	response, err := c.do(ctx, http.MethodPut, key, query, nil, data)
+	if err != nil {
+		return Part{}, err
+	}
+	defer response.Body.Close()
+	return Part{PartNumber: partNumber, ETag: response.Header.Get("ETag")}, nil
+}
+
+// CompleteMultipartUpload completes a multipart upload by assembling the provided parts
+// into a single object.
+func (c *Client) CompleteMultipartUpload(
+	ctx context.Context,
+	key string,
+	uploadID string,
+	parts []Part,
+) error {
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("could not marshal parts: %w", err)
+	}
+	var result completeMultipartUploadResult
+	return c.doXML(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, &result)
+}
+
+// AbortMultipartUpload aborts a multipart upload and discards any uploaded parts.
+func (c *Client) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	response, err := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// ListMultipartUploads returns all in-progress multipart uploads for objects that begin
+// with the provided prefix.
+func (c *Client) ListMultipartUploads(ctx context.Context, prefix string) ([]Upload, error) {
+	var uploads []Upload
+	query := url.Values{"uploads": {""}, "prefix": {prefix}}
+	for {
+		var result listMultipartUploadsResult
+		if err := c.doXML(ctx, http.MethodGet, "", query, nil, &result); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, result.Uploads...)
+		if !result.IsTruncated {
+			return uploads, nil
+		}
+		query.Set("key-marker", result.NextKeyMarker)
+		query.Set("upload-id-marker", result.NextUploadIDMarker)
+	}
+}
+
+// doXML makes a request and decodes the XML response body into the provided value.
+func (c *Client) doXML(
+	ctx context.Context,
+	method string,
+	key string,
+	query url.Values,
+	body []byte,
+	v interface{},
+) error {
+	response, err := c.do(ctx, method, key, query, nil, body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if err := xml.NewDecoder(response.Body).Decode(v); err != nil {
+		return fmt.Errorf("could not decode response: %w", err)
+	}
+	return nil
+}
+
+// do makes a signed request for the provided key. An empty key addresses the bucket itself.
+// If the response does not have a successful status code, an error is returned and the
+// response body is closed.
+func (c *Client) do(
+	ctx context.Context,
+	method string,
+	key string,
+	query url.Values,
+	header http.Header,
+	body []byte,
+) (*http.Response, error) {
+	requestURL := *c.endpoint
+	requestURL.Path = strings.TrimSuffix(c.endpoint.Path, "/") + "/" + c.bucket
+	if key != "" {
+		requestURL.Path += "/" + key
+	}
+	requestURL.RawPath = ""
+	requestURL.RawQuery = canonicalQuery(query)
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+	request.ContentLength = int64(len(body))
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	sign(request, body, c.accessKeyID, c.secretAccessKey, c.region, c.now())
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not make request: %w", err)
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+
+	defer response.Body.Close()
+	var errorResponse errorResult
+	_ = xml.NewDecoder(response.Body).Decode(&errorResponse)
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: key = %s", ErrNotFound, key)
+	}
+	return nil, fmt.Errorf(
+		"request failed: method = %s, key = %s, status = %d, code = %s, message = %s",
+		method,
+		key,
+		response.StatusCode,
+		errorResponse.Code,
+		errorResponse.Message,
+	)
+}
+
+// sign signs the provided request using AWS Signature Version 4. The host header and any
+// range or x-amz-* headers are included in the signature.
+func sign(
+	request *http.Request,
+	body []byte,
+	accessKeyID string,
+	secretAccessKey string,
+	region string,
+	now time.Time,
+) {
+	now = now.UTC()
+	payloadHash := hashHex(body)
+	request.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	scope := credentialScope(now, region)
+	signedHeaders, signature := computeSignature(request, payloadHash, secretAccessKey, region, now)
+	request.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			signingAlgorithm,
+			accessKeyID,
+			scope,
+			signedHeaders,
+			signature,
+		),
+	)
+}
+
+// computeSignature computes the signature of a request and returns the signed headers
+// along with the signature.
+func computeSignature(
+	request *http.Request,
+	payloadHash string,
+	secretAccessKey string,
+	region string,
+	now time.Time,
+) (string, string) {
+	// Collect the headers to sign.
+	headers := map[string]string{"host": request.Host}
+	if request.Host == "" {
+		headers["host"] = request.URL.Host
+	}
+	for name, values := range request.Header {
+		lower := strings.ToLower(name)
+		if lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		encodePath(request.URL.Path),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		signingAlgorithm,
+		now.Format(amzDateFormat),
+		credentialScope(now, region),
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), []byte(now.Format(shortDateFormat)))
+	key = hmacSHA256(key, []byte(region))
+	key = hmacSHA256(key, []byte(signingService))
+	key = hmacSHA256(key, []byte("aws4_request"))
+
+	return signedHeaders, hex.EncodeToString(hmacSHA256(key, []byte(stringToSign)))
+}
+
+// credentialScope returns the scope of the credentials used to sign a request.
+func credentialScope(now time.Time, region string) string {
+	return fmt.Sprintf("%s/%s/%s/aws4_request", now.Format(shortDateFormat), region, signingService)
+}
+
+// canonicalQuery encodes the provided query parameters sorted by key.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, encode(key, true)+"="+encode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// encodePath URI-encodes each segment of the provided path.
+func encodePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return encode(path, false)
+}
+
+// encode URI-encodes the provided string as required by AWS Signature Version 4.
+// Slashes are only encoded if encodeSlash is true.
+func encode(s string, encodeSlash bool) string {
+	var builder strings.Builder
+	for _, b := range []byte(s) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' || (b == '/' && !encodeSlash) {
+			builder.WriteByte(b)
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%%%02X", b))
+	}
+	return builder.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Contents              []objectResult `xml:"Contents"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	NextContinuationToken string         `xml:"NextContinuationToken"`
+}
+
+type objectResult struct {
+	Key  string `xml:"Key"`
+	Size int64  `xml:"Size"`
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []Part   `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Bucket  string   `xml:"Bucket"`
+	Key     string   `xml:"Key"`
+	ETag    string   `xml:"ETag"`
+}
+
+type listMultipartUploadsResult struct {
+	XMLName            xml.Name `xml:"ListMultipartUploadsResult"`
+	Uploads            []Upload `xml:"Upload"`
+	IsTruncated        bool     `xml:"IsTruncated"`
+	NextKeyMarker      string   `xml:"NextKeyMarker"`
+	NextUploadIDMarker string   `xml:"NextUploadIdMarker"`
+}
+
+type errorResult struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
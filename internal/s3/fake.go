@@ -0,0 +1,315 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The maximum number of keys returned by the fake server in a single list request.
+// Kept small so that pagination is exercised.
+const fakeMaxKeys = 100
+
+// FakeServer is an in-memory, in-process S3-compatible server intended for testing.
+// It supports a single bucket and only the operations used by Client. All requests
+// must be signed with the credentials that the server was created with.
+type FakeServer struct {
+	server *httptest.Server
+
+	bucket          string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+
+	// Maps object key to object content.
+	objects map[string][]byte
+
+	// Maps upload ID to the upload.
+	uploads map[string]*fakeUpload
+
+	// Used to generate upload IDs.
+	nextUploadID int
+
+	mu sync.Mutex
+}
+
+type fakeUpload struct {
+	key   string
+	parts map[int][]byte
+}
+
+// NewFakeServer creates and starts a new fake server for a bucket with the provided name that
+// accepts requests signed with the provided credentials and region.
+func NewFakeServer(bucket, region, accessKeyID, secretAccessKey string) *FakeServer {
+	f := &FakeServer{
+		bucket:          bucket,
+		region:          region,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		objects:         make(map[string][]byte),
+		uploads:         make(map[string]*fakeUpload),
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+// URL returns the endpoint of the server.
+func (f *FakeServer) URL() string {
+	return f.server.URL
+}
+
+// Close shuts down the server.
+func (f *FakeServer) Close() {
+	f.server.Close()
+}
+
+// Objects returns the keys of all objects in the bucket in lexicographical order.
+func (f *FakeServer) Objects() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Uploads returns the number of multipart uploads that have not been completed or aborted.
+func (f *FakeServer) Uploads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.uploads)
+}
+
+func (f *FakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	if err := f.authenticate(r, body); err != nil {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+	if bucket != f.bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "the bucket does not exist")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	switch {
+	case key == "" && r.Method == http.MethodGet && query.Has("uploads"):
+		f.listUploads(w, query)
+	case key == "" && r.Method == http.MethodGet:
+		f.listObjects(w, query)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.createUpload(w, key)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		f.uploadPart(w, query, body)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		f.completeUpload(w, key, query, body)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		f.abortUpload(w, query)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		f.getObject(w, r, key)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", "operation is not supported")
+	}
+}
+
+// authenticate verifies the signature of the request.
+func (f *FakeServer) authenticate(r *http.Request, body []byte) error {
+	authorization := r.Header.Get("Authorization")
+	prefix := fmt.Sprintf("%s Credential=%s/", signingAlgorithm, f.accessKeyID)
+	if !strings.HasPrefix(authorization, prefix) {
+		return fmt.Errorf("unknown credentials: authorization = %s", authorization)
+	}
+	now, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return fmt.Errorf("could not parse date: %w", err)
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != hashHex(body) {
+		return fmt.Errorf("payload hash does not match body")
+	}
+	_, signature := computeSignature(r, payloadHash, f.secretAccessKey, f.region, now)
+	if !strings.HasSuffix(authorization, "Signature="+signature) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+func (f *FakeServer) getObject(w http.ResponseWriter, r *http.Request, key string) {
+	data, ok := f.objects[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "the specified key does not exist")
+		return
+	}
+	status := http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		var offset int
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-", &offset); err != nil || offset > len(data) {
+			writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "invalid range")
+			return
+		}
+		data = data[offset:]
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("ETag", etag(f.objects[key]))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(data)
+	}
+}
+
+func (f *FakeServer) listObjects(w http.ResponseWriter, query map[string][]string) {
+	prefix := first(query["prefix"])
+	start := first(query["continuation-token"])
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > start {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := listBucketResult{}
+	if len(keys) > fakeMaxKeys {
+		keys = keys[:fakeMaxKeys]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		result.Contents = append(result.Contents, objectResult{Key: key, Size: int64(len(f.objects[key]))})
+	}
+	writeXML(w, result)
+}
+
+func (f *FakeServer) listUploads(w http.ResponseWriter, query map[string][]string) {
+	prefix := first(query["prefix"])
+	result := listMultipartUploadsResult{}
+	for uploadID, upload := range f.uploads {
+		if strings.HasPrefix(upload.key, prefix) {
+			result.Uploads = append(result.Uploads, Upload{Key: upload.key, UploadID: uploadID})
+		}
+	}
+	sort.Slice(result.Uploads, func(i, j int) bool {
+		return result.Uploads[i].UploadID < result.Uploads[j].UploadID
+	})
+	writeXML(w, result)
+}
+
+func (f *FakeServer) createUpload(w http.ResponseWriter, key string) {
+	f.nextUploadID++
+	uploadID := fmt.Sprintf("upload-%08d", f.nextUploadID)
+	f.uploads[uploadID] = &fakeUpload{key: key, parts: make(map[int][]byte)}
+	writeXML(w, initiateMultipartUploadResult{Bucket: f.bucket, Key: key, UploadID: uploadID})
+}
+
+func (f *FakeServer) uploadPart(w http.ResponseWriter, query map[string][]string, body []byte) {
+	upload, ok := f.uploads[first(query["uploadId"])]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+		return
+	}
+	partNumber, err := strconv.Atoi(first(query["partNumber"]))
+	if err != nil || partNumber < 1 {
+		writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+		return
+	}
+	upload.parts[partNumber] = body
+	w.Header().Set("ETag", etag(body))
+}
+
+func (f *FakeServer) completeUpload(
+	w http.ResponseWriter,
+	key string,
+	query map[string][]string,
+	body []byte,
+) {
+	uploadID := first(query["uploadId"])
+	upload, ok := f.uploads[uploadID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+		return
+	}
+	var request completeMultipartUpload
+	if err := xml.Unmarshal(body, &request); err != nil || len(request.Parts) == 0 {
+		writeError(w, http.StatusBadRequest, "MalformedXML", "invalid parts")
+		return
+	}
+	var data []byte
+	for i, part := range request.Parts {
+		content, ok := upload.parts[part.PartNumber]
+		if !ok || part.ETag != etag(content) || (i > 0 && part.PartNumber <= request.Parts[i-1].PartNumber) {
+			writeError(w, http.StatusBadRequest, "InvalidPart", "invalid part")
+			return
+		}
+		data = append(data, content...)
+	}
+	f.objects[key] = data
+	delete(f.uploads, uploadID)
+	writeXML(w, completeMultipartUploadResult{Bucket: f.bucket, Key: key, ETag: etag(data)})
+}
+
+func (f *FakeServer) abortUpload(w http.ResponseWriter, query map[string][]string) {
+	uploadID := first(query["uploadId"])
+	if _, ok := f.uploads[uploadID]; !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+		return
+	}
+	delete(f.uploads, uploadID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write(append([]byte(xml.Header), data...))
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	data, _ := xml.Marshal(errorResult{Code: code, Message: message})
+	_, _ = w.Write(data)
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -118,12 +118,12 @@ func (r *operationManager) appliableReadOnlyOperations(
 	return appliableOperations
 }
 
-func (r *operationManager) notifyLostLeaderShip(id string, knownLeader string) {
+func (r *operationManager) notifyLostLeaderShip(err *NotLeaderError) {
 	for _, responseCh := range r.pendingReadOnly {
-		respond(responseCh, OperationResponse{}, ErrNotLeader)
+		respond(responseCh, OperationResponse{}, err)
 	}
 	for _, responseCh := range r.pendingReplicated {
-		respond(responseCh, OperationResponse{}, ErrNotLeader)
+		respond(responseCh, OperationResponse{}, err)
 	}
 	r.pendingReadOnly = make(map[*Operation]chan Result[OperationResponse])
 	r.pendingReplicated = make(map[uint64]chan Result[OperationResponse])
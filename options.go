@@ -2,15 +2,21 @@ package raft
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jmsadair/raft/logging"
 )
 
 const (
-	defaultElectionTimeout = time.Duration(300 * time.Millisecond)
-	defaultHeartbeat       = time.Duration(50 * time.Millisecond)
-	defaultLeaseDuration   = time.Duration(100 * time.Millisecond)
+	defaultElectionTimeout   = time.Duration(300 * time.Millisecond)
+	defaultHeartbeat         = time.Duration(50 * time.Millisecond)
+	defaultLeaseDuration     = time.Duration(100 * time.Millisecond)
+	defaultMaxSnapshotDeltas = 8
+	defaultMaxInflight       = 4
+	defaultMaxEntries        = 512
+	defaultMaxEntriesBytes   = 1024 * 1024
+	defaultMaxCatchUpRounds  = 10
 )
 
 type options struct {
@@ -43,6 +49,43 @@ type options struct {
 
 	// A provided network transport that can be used by raft.
 	transport Transport
+
+	// The maximum number of consecutive incremental snapshots taken
+	// before a full snapshot is taken.
+	maxSnapshotDeltas int
+
+	// Indicates if the maximum number of incremental snapshots was set or not.
+	maxSnapshotDeltasSet bool
+
+	// Indicates whether the leader may direct followers to fetch
+	// snapshots from other followers.
+	peerSnapshotTransfer bool
+
+	// The maximum number of bytes of snapshot data per second that
+	// the leader will send to followers. Zero indicates no limit.
+	snapshotBandwidth int64
+
+	// The maximum number of AppendEntries RPCs containing log entries
+	// that may be outstanding to a follower at once.
+	maxInflight int
+
+	// The maximum number of log entries sent in a single AppendEntries RPC.
+	maxEntries int
+
+	// The maximum number of bytes of log entry data sent in a single
+	// AppendEntries RPC. A single entry that exceeds this is still sent.
+	maxEntriesBytes int
+
+	// Indicates whether operations and membership changes submitted to
+	// a follower are forwarded to the leader.
+	forwardProposals bool
+
+	// The newest protocol version advertised to peers.
+	maxProtocolVersion uint32
+
+	// The maximum number of catch-up rounds a non-voter is given to catch up
+	// with the leader before its promotion to a voter is abandoned.
+	maxCatchUpRounds int
 }
 
 // Option is a function that updates the options associated with Raft.
@@ -130,3 +173,128 @@ func WithTransport(transport Transport) Option {
 		return nil
 	}
 }
+
+// WithMaxSnapshotDeltas sets the maximum number of consecutive incremental snapshots
+// that will be taken before a full snapshot is taken. Incremental snapshots are only
+// taken if the state machine implements IncrementalStateMachine and the snapshot
+// storage implements IncrementalSnapshotStorage. A value of zero disables incremental
+// snapshots.
+func WithMaxSnapshotDeltas(maxSnapshotDeltas int) Option {
+	return func(options *options) error {
+		if maxSnapshotDeltas < 0 {
+			return errors.New("maximum number of snapshot deltas must not be negative")
+		}
+		options.maxSnapshotDeltas = maxSnapshotDeltas
+		options.maxSnapshotDeltasSet = true
+		return nil
+	}
+}
+
+// WithPeerSnapshotTransfer sets whether the leader may direct a follower that needs a snapshot
+// to fetch it from another follower that is up-to-date instead of sending the snapshot itself.
+// This spreads the cost of bringing lagging followers up-to-date across the cluster. If the
+// follower is unable to fetch the snapshot from its peer, the leader will send the snapshot.
+func WithPeerSnapshotTransfer(enabled bool) Option {
+	return func(options *options) error {
+		options.peerSnapshotTransfer = enabled
+		return nil
+	}
+}
+
+// WithMaxInflightAppendEntries sets the maximum number of AppendEntries RPCs containing log
+// entries that the leader may have outstanding to a follower at once. Allowing more than one
+// outstanding RPC lets the leader send new entries without waiting for the previous ones to
+// be acknowledged, which improves throughput on high-latency links.
+func WithMaxInflightAppendEntries(maxInflight int) Option {
+	return func(options *options) error {
+		if maxInflight < 1 {
+			return errors.New("maximum number of in-flight AppendEntries RPCs must be at least one")
+		}
+		options.maxInflight = maxInflight
+		return nil
+	}
+}
+
+// WithMaxAppendEntries sets the maximum number of log entries that the leader will send
+// in a single AppendEntries RPC. A follower that is far behind is brought up to date over
+// multiple RPCs rather than in one large one.
+func WithMaxAppendEntries(maxEntries int) Option {
+	return func(options *options) error {
+		if maxEntries < 1 {
+			return errors.New("maximum number of entries per AppendEntries RPC must be at least one")
+		}
+		options.maxEntries = maxEntries
+		return nil
+	}
+}
+
+// WithMaxAppendEntriesBytes sets the maximum number of bytes of log entry data that the leader
+// will send in a single AppendEntries RPC. This should be kept well below the maximum message
+// size accepted by the transport. An entry larger than the limit is sent in an RPC by itself.
+func WithMaxAppendEntriesBytes(maxBytes int) Option {
+	return func(options *options) error {
+		if maxBytes < 1 {
+			return errors.New("maximum number of bytes per AppendEntries RPC must be at least one")
+		}
+		options.maxEntriesBytes = maxBytes
+		return nil
+	}
+}
+
+// WithSnapshotBandwidth sets the maximum number of bytes of snapshot data per second that the
+// leader will send to followers. The limit is shared by all followers that are being sent a
+// snapshot, which leaves room for AppendEntries RPCs. A value of zero disables the limit.
+func WithSnapshotBandwidth(bytesPerSecond int64) Option {
+	return func(options *options) error {
+		if bytesPerSecond < 0 {
+			return errors.New("snapshot bandwidth must not be negative")
+		}
+		options.snapshotBandwidth = bytesPerSecond
+		return nil
+	}
+}
+
+// WithProposalForwarding sets whether operations and membership changes submitted to a node that
+// is not the leader are forwarded to the node it believes is the leader instead of failing with
+// ErrNotLeader. The result of a forwarded operation or membership change is returned through the
+// future as if it had been submitted to the leader directly. The state machine must implement
+// ForwardingStateMachine so that the responses to forwarded operations can be returned.
+func WithProposalForwarding(enabled bool) Option {
+	return func(options *options) error {
+		options.forwardProposals = enabled
+		return nil
+	}
+}
+
+// WithMaxProtocolVersion sets the newest protocol version that the node advertises to its peers.
+// By default, the node advertises MaxProtocolVersion. Advertising an older version prevents the
+// cluster from using newer features, which may be used to defer them until the upgrade of every
+// node has been verified.
+func WithMaxProtocolVersion(version uint32) Option {
+	return func(options *options) error {
+		if version < MinProtocolVersion || version > MaxProtocolVersion {
+			return fmt.Errorf(
+				"protocol version must be between %d and %d",
+				MinProtocolVersion,
+				MaxProtocolVersion,
+			)
+		}
+		options.maxProtocolVersion = version
+		return nil
+	}
+}
+
+// WithMaxCatchUpRounds sets the maximum number of rounds that a node added with AddLearner is given
+// to catch up with the leader before it is promoted to a voter. In each round, the node is sent the
+// entries that were in the leader's log at the start of the round. The node is considered caught
+// up once it completes a round within the election timeout. If the node does not catch up within
+// the maximum number of rounds, it remains a non-voter and the promotion fails with ErrCatchUpFailed.
+func WithMaxCatchUpRounds(rounds int) Option {
+	return func(options *options) error {
+		if rounds < 1 {
+			return errors.New("maximum number of catch-up rounds must be at least one")
+		}
+		options.maxCatchUpRounds = rounds
+		return nil
+	}
+}
@@ -53,3 +53,61 @@ func TestWithTransport(t *testing.T) {
 	transport := &transport{}
 	require.NoError(t, WithTransport(transport)(options))
 }
+
+// TestWithMaxInflightAppendEntries checks that the maximum number of in-flight AppendEntries
+// RPCs option only accepts positive values.
+func TestWithMaxInflightAppendEntries(t *testing.T) {
+	options := &options{}
+
+	// Test invalid input
+	require.Error(t, WithMaxInflightAppendEntries(0)(options))
+
+	// Test valid input
+	require.NoError(t, WithMaxInflightAppendEntries(4)(options))
+	require.Equal(t, 4, options.maxInflight)
+}
+
+func TestWithMaxAppendEntries(t *testing.T) {
+	options := &options{}
+
+	// Test invalid input
+	require.Error(t, WithMaxAppendEntries(0)(options))
+
+	// Test valid input
+	require.NoError(t, WithMaxAppendEntries(100)(options))
+	require.Equal(t, 100, options.maxEntries)
+}
+
+func TestWithMaxAppendEntriesBytes(t *testing.T) {
+	options := &options{}
+
+	// Test invalid input
+	require.Error(t, WithMaxAppendEntriesBytes(0)(options))
+
+	// Test valid input
+	require.NoError(t, WithMaxAppendEntriesBytes(1024)(options))
+	require.Equal(t, 1024, options.maxEntriesBytes)
+}
+
+func TestWithMaxProtocolVersion(t *testing.T) {
+	options := &options{}
+
+	// Test invalid input
+	require.Error(t, WithMaxProtocolVersion(0)(options))
+	require.Error(t, WithMaxProtocolVersion(MaxProtocolVersion+1)(options))
+
+	// Test valid input
+	require.NoError(t, WithMaxProtocolVersion(MinProtocolVersion)(options))
+	require.Equal(t, MinProtocolVersion, options.maxProtocolVersion)
+}
+
+func TestWithMaxCatchUpRounds(t *testing.T) {
+	options := &options{}
+
+	// Test invalid input
+	require.Error(t, WithMaxCatchUpRounds(0)(options))
+
+	// Test valid input
+	require.NoError(t, WithMaxCatchUpRounds(5)(options))
+	require.Equal(t, 5, options.maxCatchUpRounds)
+}
@@ -0,0 +1,139 @@
+package raft
+
+import (
+	"fmt"
+
+	"github.com/jmsadair/raft/internal/numeric"
+)
+
+// The versions of the protocol used by nodes to communicate with each other. Each node
+// advertises the range of versions it supports in its RPCs, and the leader only uses the
+// features of a version once every voting member of the cluster supports it. This allows
+// a cluster to be upgraded one node at a time.
+//
+// Version 1 is the original protocol. Nodes that do not advertise a range of versions are
+// assumed to only support it.
+//
+// Version 2 adds incremental snapshots and the transfer of snapshots between followers.
+//
+// Version 3 adds membership changes using joint consensus.
+//
+// Version 4 adds nodes as non-voters that are promoted to voters once they have caught up.
+const (
+	// MinProtocolVersion is the oldest protocol version supported by this package.
+	MinProtocolVersion uint32 = 1
+
+	// MaxProtocolVersion is the newest protocol version supported by this package.
+	MaxProtocolVersion uint32 = 4
+)
+
+const (
+	// protocolVersionSnapshotTransfer is the protocol version that introduced incremental
+	// snapshots and the transfer of snapshots between followers.
+	protocolVersionSnapshotTransfer uint32 = 2
+
+	// protocolVersionJointConsensus is the protocol version that introduced membership
+	// changes using joint consensus.
+	protocolVersionJointConsensus uint32 = 3
+
+	// protocolVersionLearnerPromotion is the protocol version that introduced adding nodes as
+	// non-voters that are promoted to voters once they have caught up.
+	protocolVersionLearnerPromotion uint32 = 4
+)
+
+// protocolVersionRange returns the range of protocol versions advertised by a peer. A peer
+// that does not advertise a range is assumed to only support the original protocol.
+func protocolVersionRange(minVersion uint32, maxVersion uint32) (uint32, uint32) {
+	if maxVersion == 0 {
+		return MinProtocolVersion, MinProtocolVersion
+	}
+	if minVersion == 0 {
+		minVersion = MinProtocolVersion
+	}
+	return minVersion, maxVersion
+}
+
+// checkProtocolVersion returns an error if this node does not support any of the protocol
+// versions in the range advertised by a peer.
+func (r *Raft) checkProtocolVersion(minVersion uint32, maxVersion uint32) error {
+	minVersion, maxVersion = protocolVersionRange(minVersion, maxVersion)
+	if minVersion > r.options.maxProtocolVersion || maxVersion < MinProtocolVersion {
+		return fmt.Errorf(
+			"incompatible protocol version: local = [%d, %d], remote = [%d, %d]",
+			MinProtocolVersion,
+			r.options.maxProtocolVersion,
+			minVersion,
+			maxVersion,
+		)
+	}
+	return nil
+}
+
+// recordProtocolVersion records the newest protocol version supported by the peer with the
+// provided ID, as advertised in one of its RPCs, and updates the protocol version in use by
+// the cluster if this node is the leader. The lock must be held when this is called.
+func (r *Raft) recordProtocolVersion(id string, minVersion uint32, maxVersion uint32) {
+	follower, ok := r.followers[id]
+	if !ok {
+		return
+	}
+	_, follower.maxProtocolVersion = protocolVersionRange(minVersion, maxVersion)
+	if r.state == Leader {
+		r.updateProtocolVersion()
+	}
+}
+
+// updateProtocolVersion sets the protocol version in use by the cluster to the newest version
+// supported by every voting member. The version is only raised once the versions supported by
+// all voting members are known, but it is lowered as soon as any voting member is known not
+// to support it. The lock must be held when this is called.
+func (r *Raft) updateProtocolVersion() {
+	version := r.options.maxProtocolVersion
+	known := true
+	for id := range r.configuration.Members {
+		if !r.isVoter(id) || id == r.id {
+			continue
+		}
+		follower, ok := r.followers[id]
+		if !ok || follower.maxProtocolVersion == 0 {
+			known = false
+			continue
+		}
+		version = numeric.Min(version, follower.maxProtocolVersion)
+	}
+
+	if version == r.protocolVersion || (version > r.protocolVersion && !known) {
+		return
+	}
+
+	r.logger.Infof(
+		"changing protocol version: previousVersion = %d, version = %d",
+		r.protocolVersion,
+		version,
+	)
+	r.protocolVersion = version
+}
+
+// requireProtocolVersion returns an error if the provided protocol version, which introduced
+// the provided feature, is not in use by the cluster. The lock must be held when this is called.
+func (r *Raft) requireProtocolVersion(version uint32, feature string) error {
+	if r.protocolVersion < version {
+		return fmt.Errorf(
+			"%w: feature = %s, requiredVersion = %d, version = %d",
+			ErrUnsupportedProtocolVersion,
+			feature,
+			version,
+			r.protocolVersion,
+		)
+	}
+	return nil
+}
+
+// peerSupportsProtocolVersion indicates whether the provided protocol version is in use by the
+// cluster and is supported by the peer with the provided ID. Non-voting members are not taken
+// into account when determining the version in use by the cluster, so they are checked
+// individually. The lock must be held when this is called.
+func (r *Raft) peerSupportsProtocolVersion(id string, version uint32) bool {
+	follower, ok := r.followers[id]
+	return r.protocolVersion >= version && ok && follower.maxProtocolVersion >= version
+}
@@ -2,6 +2,7 @@ package raft
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -10,13 +11,15 @@ import (
 
 	"github.com/jmsadair/raft/internal/numeric"
 	"github.com/jmsadair/raft/internal/random"
+	"github.com/jmsadair/raft/internal/ratelimit"
 	"github.com/jmsadair/raft/logging"
 )
 
 var (
 	// ErrNotLeader is returned when an operation or configuration change is
 	// submitted to a node that is not a leader. Operations may only be submitted
-	// to a node that is a leader.
+	// to a node that is a leader unless proposal forwarding is enabled. It is
+	// wrapped by a NotLeaderError that identifies the known leader.
 	ErrNotLeader = errors.New("this node is not the leader")
 
 	// ErrInvalidLease is returned when a lease-based read-only operation is
@@ -33,11 +36,82 @@ var (
 	// to be committed in the current term. The membership change may be submitted once a log entry
 	// has been committed this term.
 	ErrNoCommitThisTerm = errors.New("a log entry has not been committed in this term")
+
+	// ErrLeadershipTransferInProgress is returned when an operation, membership change, or
+	// leadership transfer is submitted to a leader that is transferring leadership to another
+	// node. The request should be submitted to the new leader once the transfer completes.
+	ErrLeadershipTransferInProgress = errors.New("a leadership transfer is in progress")
+
+	// ErrCatchUpFailed is returned when a node added with AddLearner does not catch up with the
+	// leader within the maximum number of catch-up rounds. The node remains a non-voter and may
+	// be promoted by submitting the membership change again.
+	ErrCatchUpFailed = errors.New("the node did not catch up with the leader")
+
+	// ErrCatchUpSuperseded is returned when a node added with AddLearner is added again before
+	// it has been promoted. The node continues to be caught up, and the configuration in which
+	// it is a voter is returned to the most recent request instead.
+	ErrCatchUpSuperseded = errors.New("the node was added again before it was promoted")
+
+	// ErrUnsupportedProtocolVersion is returned when a request relies on a feature that was
+	// introduced in a protocol version that is not yet in use by the cluster, usually because
+	// some voting members have not been upgraded. The request may be submitted again once
+	// every voting member supports the protocol version.
+	ErrUnsupportedProtocolVersion = errors.New("the protocol version in use does not support the request")
 )
 
+// NotLeaderError is returned when an operation or configuration change is submitted to a node
+// that is not the leader. It identifies the leader known to the node, if there is one, so that
+// the operation may be resubmitted to the leader. It wraps ErrNotLeader.
+type NotLeaderError struct {
+	// The ID of the leader known to the node. Empty if the leader is not known.
+	LeaderID string
+
+	// The address of the leader known to the node. Empty if the leader is not
+	// known or is not a member of the node's configuration.
+	LeaderAddress string
+
+	// The term of the node when the error occurred.
+	Term uint64
+}
+
+func (e *NotLeaderError) Error() string {
+	if e.LeaderID == "" {
+		return fmt.Sprintf("%s: leader is unknown, term = %d", ErrNotLeader.Error(), e.Term)
+	}
+	return fmt.Sprintf(
+		"%s: leaderID = %s, leaderAddress = %s, term = %d",
+		ErrNotLeader.Error(),
+		e.LeaderID,
+		e.LeaderAddress,
+		e.Term,
+	)
+}
+
+func (e *NotLeaderError) Unwrap() error {
+	return ErrNotLeader
+}
+
+// The errors that may be returned by the leader for a forwarded proposal. These are
+// reconstructed by the follower so that callers may check for them. Each error is
+// identified by its position in the list plus one, so new errors must be appended.
+var forwardedErrors = []error{
+	ErrInvalidLease,
+	ErrPendingConfiguration,
+	ErrNoCommitThisTerm,
+	ErrLeadershipTransferInProgress,
+	ErrCatchUpFailed,
+	ErrCatchUpSuperseded,
+	ErrUnsupportedProtocolVersion,
+	ErrTimeout,
+}
+
 // The default chunk size for InstallSnapshot RPCs.
 const snapshotChunkSize = 32 * 1024
 
+// The number of heartbeat intervals that the leader waits for a response to an
+// AppendEntries RPC before abandoning it.
+const appendEntriesTimeoutHeartbeats = 3
+
 // State represents the current state of a node.
 // A node may either be shutdown, the leader, or a followers.
 type State uint32
@@ -102,18 +176,200 @@ type Status struct {
 
 	// The current state of the node: leader, followers, shutdown.
 	State State
+
+	// The ID of the peer that this node is fetching a snapshot from.
+	// Empty if this node is not fetching a snapshot from a peer.
+	SnapshotSource string
+
+	// The progress of the snapshots being sent to followers by this node,
+	// keyed by follower ID. Empty if this node is not the leader.
+	SnapshotTransfers map[string]SnapshotTransfer
+
+	// The protocol version in use by the cluster as known to this node.
+	ProtocolVersion uint32
+}
+
+// SnapshotTransfer contains the progress of a snapshot being sent to a follower.
+type SnapshotTransfer struct {
+	// The last index included in the snapshot.
+	LastIncludedIndex uint64
+
+	// The number of bytes of the snapshot received by the follower.
+	BytesSent int64
+
+	// The size of the snapshot in bytes.
+	TotalBytes int64
+
+	// The average number of bytes per second received by the follower.
+	Rate float64
+
+	// The estimated time remaining until the follower has received the
+	// entire snapshot. Zero if the rate is not yet known.
+	ETA time.Duration
+}
+
+// LeadershipTransferResponse is the result of a successful leadership transfer.
+type LeadershipTransferResponse struct {
+	// The ID of the node that leadership was transferred to.
+	TargetID string
+
+	// The address of the node that leadership was transferred to.
+	TargetAddress string
+
+	// The term in which the node that transferred leadership stepped down.
+	Term uint64
+}
+
+// leadershipTransfer contains the state of a leadership transfer in progress.
+type leadershipTransfer struct {
+	// The ID of the node that leadership is being transferred to.
+	targetID string
+
+	// The address of the node that leadership is being transferred to.
+	targetAddress string
+
+	// The term in which the transfer was started.
+	term uint64
+
+	// Indicates that a TimeoutNow RPC has been sent to the target.
+	timeoutNowSent bool
+
+	// Abandons the transfer if it does not complete in time.
+	timer *time.Timer
+
+	// A channel used to respond to the leadership transfer request.
+	responseCh chan Result[LeadershipTransferResponse]
+}
+
+// catchUp tracks the progress of a non-voter that will be promoted to a voter once
+// it has caught up with the leader.
+type catchUp struct {
+	// The log index that the non-voter must replicate to complete the current round.
+	roundIndex uint64
+
+	// The time at which the current round started.
+	roundStart time.Time
+
+	// The number of rounds that have been started.
+	rounds int
+
+	// Indicates that the non-voter completed a round within the election timeout
+	// and will be promoted once there is no pending membership change.
+	caughtUp bool
+
+	// A channel used to respond to the request to add the non-voter.
+	responseCh chan Result[Configuration]
 }
 
 // follower contains all state associated with followers.
 type follower struct {
-	// The next log index that should be sent to this node.
+	// The next log index that should be sent to this node. This is advanced
+	// as soon as entries are sent, before they are acknowledged.
 	nextIndex uint64
 
+	// The most recent heartbeat round in which this node responded to an
+	// AppendEntries RPC from the leader.
+	ackedRound uint64
+
+	// The last time this node responded to an AppendEntries or InstallSnapshot
+	// RPC from the leader.
+	lastContact time.Time
+
 	// The highest log index known to be replicated on this node.
 	matchIndex uint64
 
 	// The snapshot file to read when sending a snapshot to this node.
 	snapshot SnapshotFile
+
+	// Indicates that this node does not have the base of the most recent
+	// snapshot and must be sent a full snapshot.
+	needsFullSnapshot bool
+
+	// The ID of the peer that this node has been directed to fetch a
+	// snapshot from. Empty if this node has not been directed to a peer.
+	snapshotSource string
+
+	// Indicates that this node failed to fetch a snapshot from a peer
+	// and must be sent the snapshot by the leader.
+	fetchFailed bool
+
+	// Indicates that a chunk of the snapshot has been sent to this
+	// node and a response has not yet been received.
+	sendingSnapshot bool
+
+	// The size in bytes of the snapshot being sent to this node.
+	snapshotSize int64
+
+	// The number of bytes of the snapshot received by this node.
+	snapshotBytesSent int64
+
+	// The time at which the leader started sending the snapshot.
+	snapshotStart time.Time
+
+	// The newest protocol version supported by this node. Zero if
+	// this node has not advertised the versions it supports.
+	maxProtocolVersion uint32
+
+	// The catch-up progress of this node if it is a non-voter that will
+	// be promoted to a voter. Nil if this node is not being promoted.
+	catchUp *catchUp
+}
+
+// replicator sends log entries and snapshots to a single follower. It is run by a single
+// long-lived loop that owns the window of AppendEntries RPCs outstanding to the follower:
+// the loop builds each batch of entries, sends it, and handles its response. Only the
+// blocking transport call is made off of the loop so that the batches are pipelined.
+// The fields following stop are only accessed by the loop.
+type replicator struct {
+	// The address of the follower.
+	address string
+
+	// Notifies the loop that there may be log entries or a heartbeat to send to the
+	// follower. This is buffered so that notifications sent while the loop is busy
+	// are coalesced.
+	trigger chan struct{}
+
+	// Receives the results of the AppendEntries RPCs sent to the follower. This is
+	// buffered so that every outstanding RPC can deliver its result without blocking,
+	// even once the loop has stopped.
+	results chan appendEntriesResult
+
+	// Closed to stop the loop.
+	stop chan struct{}
+
+	// The number of AppendEntries RPCs containing log entries that have
+	// been sent to the follower and have not yet received a response.
+	inflight int
+
+	// Indicates that an AppendEntries RPC without log entries has been sent
+	// to the follower and has not yet received a response.
+	heartbeating bool
+
+	// Indicates that the loop has been notified and has not yet sent an RPC.
+	pending bool
+
+	// Indicates that the most recent AppendEntries RPC failed. Entries are not sent
+	// again until the loop is notified so that an unreachable follower is not retried
+	// more than once per heartbeat.
+	failed bool
+}
+
+// appendEntriesResult is the outcome of an AppendEntries RPC sent by a replicator.
+type appendEntriesResult struct {
+	// The request that was sent.
+	request AppendEntriesRequest
+
+	// The response to the request. Only valid if err is nil.
+	response AppendEntriesResponse
+
+	// Any error that occurred while sending the request.
+	err error
+
+	// Indicates that the request only checked the entries known to be replicated.
+	probe bool
+
+	// The heartbeat round in which the request was sent.
+	round uint64
 }
 
 // Raft implements the raft consensus protocol.
@@ -146,10 +402,25 @@ type Raft struct {
 	// A channel used to respond to membership change requests.
 	configurationResponseCh chan Result[Configuration]
 
+	// The leadership transfer in progress. Nil if leadership is not
+	// being transferred. Only set while this node is the leader.
+	leadershipTransfer *leadershipTransfer
+
 	// Maps ID to the state of the other nodes in the cluster.
 	// Maintained by the leader.
 	followers map[string]*follower
 
+	// Maps ID to the replicator for the other nodes in the cluster.
+	// Only populated while this node is the leader.
+	replicators map[string]*replicator
+
+	// The current heartbeat round. This is incremented each time the leader
+	// notifies the replicators to send AppendEntries RPCs.
+	round uint64
+
+	// The most recent heartbeat round that a quorum of the cluster has responded to.
+	verifiedRound uint64
+
 	// Manages both read-only and replicated operations.
 	operationManager *operationManager
 
@@ -165,6 +436,15 @@ type Raft struct {
 	// A writer for a snapshot file if one is being installed.
 	snapshot SnapshotFile
 
+	// The ID of the peer that this node is fetching a snapshot from.
+	// Empty if this node is not fetching a snapshot from a peer.
+	snapshotSource string
+
+	// Indicates that the most recent attempt by this node to fetch a
+	// snapshot from a peer failed. This is reported to the leader the
+	// next time it directs this node to fetch a snapshot.
+	snapshotFetchFailed bool
+
 	// The state machine provided by the client that operations will be applied to.
 	fsm StateMachine
 
@@ -185,6 +465,29 @@ type Raft struct {
 	// Notifies snapshot loop that a snapshot should be taken.
 	snapshotCond *sync.Cond
 
+	// Indicates that a full snapshot should be taken regardless of whether
+	// the state machine needs one. Set when a follower needs a full snapshot
+	// but the most recent snapshot is incremental.
+	fullSnapshotRequested bool
+
+	// The protocol version in use by the cluster. The leader determines the
+	// version from the versions supported by the voting members and followers
+	// learn it from the leader.
+	protocolVersion uint32
+
+	// Cancelled when this node is stopped to cancel any in-flight RPCs.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Cancelled when this node loses leadership to cancel any in-flight
+	// RPCs sent as the leader.
+	leaderCtx    context.Context
+	leaderCancel context.CancelFunc
+
+	// Limits the rate at which snapshot data is sent to followers.
+	// Nil if the rate is not limited.
+	snapshotLimiter *ratelimit.Limiter
+
 	// The current state of this raft node: leader, followers, or shutdown.
 	state State
 
@@ -244,6 +547,29 @@ func NewRaft(
 	if options.leaseDuration == 0 {
 		options.leaseDuration = defaultLeaseDuration
 	}
+	if options.maxInflight == 0 {
+		options.maxInflight = defaultMaxInflight
+	}
+	if options.maxEntries == 0 {
+		options.maxEntries = defaultMaxEntries
+	}
+	if options.maxEntriesBytes == 0 {
+		options.maxEntriesBytes = defaultMaxEntriesBytes
+	}
+	if !options.maxSnapshotDeltasSet {
+		options.maxSnapshotDeltas = defaultMaxSnapshotDeltas
+	}
+	if options.maxProtocolVersion == 0 {
+		options.maxProtocolVersion = MaxProtocolVersion
+	}
+	if options.maxCatchUpRounds == 0 {
+		options.maxCatchUpRounds = defaultMaxCatchUpRounds
+	}
+	if _, ok := fsm.(ForwardingStateMachine); options.forwardProposals && !ok {
+		return nil, errors.New(
+			"state machine must implement ForwardingStateMachine to forward proposals",
+		)
+	}
 	if options.log == nil {
 		log, err := NewLog(dataPath)
 		if err != nil {
@@ -290,6 +616,7 @@ func NewRaft(
 		operationManager: newOperationManager(options.leaseDuration),
 		state:            Shutdown,
 		fsm:              fsm,
+		protocolVersion:  MinProtocolVersion,
 	}
 
 	raft.applyCond = sync.NewCond(&raft.mu)
@@ -298,6 +625,10 @@ func NewRaft(
 	raft.electionCond = sync.NewCond(&raft.mu)
 	raft.snapshotCond = sync.NewCond(&raft.mu)
 
+	if options.snapshotBandwidth > 0 {
+		raft.snapshotLimiter = ratelimit.NewLimiter(options.snapshotBandwidth, options.snapshotBandwidth)
+	}
+
 	if err := raft.restore(); err != nil {
 		return nil, err
 	}
@@ -322,7 +653,16 @@ func (r *Raft) restore() error {
 	r.currentTerm = currentTerm
 	r.votedFor = votedFor
 
-	// Restore the state machine from the most recent snapshot.
+	// A state machine that persists itself may have already applied operations.
+	var appliedIndex uint64
+	if fsm, ok := r.fsm.(PersistentStateMachine); ok {
+		if appliedIndex, err = fsm.AppliedIndex(); err != nil {
+			return fmt.Errorf("could not get applied index of state machine: %w", err)
+		}
+	}
+
+	// Restore the state machine from the most recent snapshot unless it already
+	// contains the state in the snapshot.
 	file, err := r.snapshotStorage.SnapshotFile()
 	if err != nil {
 		return fmt.Errorf("could not get snapshot file: %w", err)
@@ -333,7 +673,16 @@ func (r *Raft) restore() error {
 		r.lastIncludedTerm = metadata.LastIncludedTerm
 		r.commitIndex = metadata.LastIncludedIndex
 		r.lastApplied = metadata.LastIncludedIndex
-		if err := r.fsm.Restore(file); err != nil {
+		if appliedIndex >= metadata.LastIncludedIndex {
+			r.logger.Infof(
+				"skipped restoring state machine from snapshot: appliedIndex = %d, lastIndex = %d",
+				appliedIndex,
+				metadata.LastIncludedIndex,
+			)
+			if err := file.Close(); err != nil {
+				return fmt.Errorf("could not close snapshot file: %w", err)
+			}
+		} else if err := r.restoreStateMachine(file); err != nil {
 			return fmt.Errorf("could not restore state machine with snapshot: %w", err)
 		}
 		configuration, err := r.transport.DecodeConfiguration(metadata.Configuration)
@@ -342,9 +691,19 @@ func (r *Raft) restore() error {
 		}
 		r.configuration = &configuration
 		r.committedConfiguration = &configuration
-		if err := file.Close(); err != nil {
-			return fmt.Errorf("could not close snapshot file: %w", err)
+	}
+
+	// Only the operations that the state machine has not already applied will be applied.
+	if appliedIndex > r.lastApplied {
+		if appliedIndex > r.log.LastIndex() {
+			return fmt.Errorf(
+				"state machine applied index %d exceeds last log index %d",
+				appliedIndex,
+				r.log.LastIndex(),
+			)
 		}
+		r.commitIndex = appliedIndex
+		r.lastApplied = appliedIndex
 	}
 
 	// Use the most recent configuration from the log.
@@ -455,15 +814,21 @@ func (r *Raft) start(restore bool) error {
 	r.transport.RegisterAppendEntriesHandler(r.AppendEntries)
 	r.transport.RegisterRequestVoteHandler(r.RequestVote)
 	r.transport.RegsiterInstallSnapshotHandler(r.InstallSnapshot)
+	r.transport.RegisterFetchSnapshotHandler(r.FetchSnapshot)
+	r.transport.RegisterTimeoutNowHandler(r.TimeoutNow)
+	r.transport.RegisterForwardOperationHandler(r.ForwardOperation)
+	r.transport.RegisterForwardMembershipChangeHandler(r.ForwardMembershipChange)
 
 	// Initialize the follower state.
 	r.followers = make(map[string]*follower)
 	for id := range r.configuration.Members {
 		r.followers[id] = new(follower)
 	}
+	r.replicators = make(map[string]*replicator)
 
 	r.lastContact = time.Now()
 	r.state = Follower
+	r.ctx, r.cancel = context.WithCancel(context.Background())
 
 	r.wg.Add(7)
 	go r.readOnlyLoop()
@@ -500,6 +865,9 @@ func (r *Raft) Stop() {
 	}
 
 	r.state = Shutdown
+	r.cancel()
+	r.stopReplicators()
+	r.abortLeadershipTransfer(r.notLeaderError())
 	r.applyCond.Broadcast()
 	r.commitCond.Broadcast()
 	r.readOnlyCond.Broadcast()
@@ -524,18 +892,44 @@ func (r *Raft) Stop() {
 
 // Status returns the status of this node. The status includes
 // the ID, address, term, commit index, last applied index, and
-// state of this node.
+// state of this node as well as the progress of any snapshots
+// being sent to or fetched by this node.
 func (r *Raft) Status() Status {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	transfers := make(map[string]SnapshotTransfer)
+	if r.state == Leader {
+		for id, follower := range r.followers {
+			if follower.snapshot == nil {
+				continue
+			}
+			transfer := SnapshotTransfer{
+				LastIncludedIndex: follower.snapshot.Metadata().LastIncludedIndex,
+				BytesSent:         follower.snapshotBytesSent,
+				TotalBytes:        follower.snapshotSize,
+			}
+			if elapsed := time.Since(follower.snapshotStart).Seconds(); elapsed > 0 {
+				transfer.Rate = float64(transfer.BytesSent) / elapsed
+			}
+			if transfer.Rate > 0 {
+				remaining := float64(transfer.TotalBytes-transfer.BytesSent) / transfer.Rate
+				transfer.ETA = time.Duration(remaining * float64(time.Second))
+			}
+			transfers[id] = transfer
+		}
+	}
+
 	return Status{
-		ID:          r.id,
-		Address:     r.transport.Address(),
-		Term:        r.currentTerm,
-		CommitIndex: r.commitIndex,
-		LastApplied: r.lastApplied,
-		State:       r.state,
+		ID:                r.id,
+		Address:           r.transport.Address(),
+		Term:              r.currentTerm,
+		CommitIndex:       r.commitIndex,
+		LastApplied:       r.lastApplied,
+		State:             r.state,
+		SnapshotSource:    r.snapshotSource,
+		SnapshotTransfers: transfers,
+		ProtocolVersion:   r.protocolVersion,
 	}
 }
 
@@ -554,7 +948,7 @@ func (r *Raft) Configuration() Configuration {
 // AddServer will add a node with the provided ID and address to the cluster
 // and return a future for the resulting configuration. It is generally recommended
 // to add a new node as a non-voting member before adding it as a voting member so that
-// it can become synced with the rest of the cluster.
+// it can become synced with the rest of the cluster. AddLearner does this automatically.
 //
 // The provided ID must be unique from the existing nodes in the cluster.
 // If the configuration change was not successful, the returned future will
@@ -574,21 +968,32 @@ func (r *Raft) AddServer(
 
 	configurationFuture := newFuture[Configuration](timeout)
 
-	// Only the leader can make membership changes.
-	if r.state != Leader {
-		respond(configurationFuture.responseCh, Configuration{}, ErrNotLeader)
+	// Forward the membership change to the leader if this node is not the leader.
+	if leaderAddress, ok := r.forwardingAddress(); ok {
+		request := ForwardMembershipChangeRequest{
+			ID:      id,
+			Address: address,
+			IsVoter: isVoter,
+			Timeout: timeout,
+		}
+		r.wg.Add(1)
+		go r.forwardMembershipChange(leaderAddress, request, configurationFuture.responseCh)
 		return configurationFuture
 	}
 
-	// Membership changes may not be submitted until a log entry for this term is committed.
-	if !r.committedThisTerm() {
-		respond(configurationFuture.responseCh, Configuration{}, ErrNoCommitThisTerm)
-		return configurationFuture
-	}
+	return r.addServer(id, address, isVoter, configurationFuture)
+}
 
-	// The membership change is still pending - wait until it completes.
-	if r.pendingConfigurationChange() {
-		respond(configurationFuture.responseCh, Configuration{}, ErrPendingConfiguration)
+// addServer adds the node with the provided ID and address to the cluster and populates
+// the provided future with the resulting configuration.
+func (r *Raft) addServer(
+	id string,
+	address string,
+	isVoter bool,
+	configurationFuture *future[Configuration],
+) Future[Configuration] {
+	if err := r.checkMembershipChangeAllowed(0); err != nil {
+		respond(configurationFuture.responseCh, Configuration{}, err)
 		return configurationFuture
 	}
 
@@ -607,7 +1012,8 @@ func (r *Raft) AddServer(
 	r.appendConfiguration(&configuration)
 
 	r.configuration = &configuration
-	r.followers[id] = &follower{nextIndex: 1}
+	r.configurationResponseCh = configurationFuture.responseCh
+	r.followers[id] = &follower{nextIndex: 1, lastContact: time.Now()}
 
 	r.sendAppendEntriesToPeers()
 
@@ -622,154 +1028,705 @@ func (r *Raft) AddServer(
 	return configurationFuture
 }
 
-// RemoveServer will remove the node with the provided ID from the cluster
-// and returns a future for the resulting configuration. Once removed, the node
-// will remain online as a non-voter and may safely be shutdown.
+// AddLearner will add a node with the provided ID and address to the cluster as a non-voter
+// and promote it to a voter once it has caught up with the leader. It returns a future for
+// the configuration in which the node is a voter.
+//
+// The leader brings the node up to date in rounds. In each round, the node is sent the entries
+// that were in the leader's log at the start of the round. The node is promoted once it completes
+// a round within the election timeout, so that it does not stall commitment once it is a voter.
+// If the node does not catch up within the maximum number of rounds, it remains a non-voter and
+// the returned future is populated with ErrCatchUpFailed. If the node is added again before it
+// is promoted, the returned future is populated with ErrCatchUpSuperseded.
+//
+// Adding a non-voter that is promoted once it has caught up requires protocol version 4. If the
+// cluster is not yet using it, the returned future is populated with ErrUnsupportedProtocolVersion.
 //
 // If the configuration change was not successful, the returned future will be populated
 // with an error. It may be necessary to resubmit the configuration change to this node
 // or a different node. It is safe to call this function as many times as necessary.
 //
 // It is the caller's responsibility to implement retry logic.
-func (r *Raft) RemoveServer(id string, timeout time.Duration) Future[Configuration] {
+func (r *Raft) AddLearner(id string, address string, timeout time.Duration) Future[Configuration] {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	configurationFuture := newFuture[Configuration](timeout)
 
-	// Only the leader can make membership changes.
-	if r.state != Leader {
-		respond(configurationFuture.responseCh, Configuration{}, ErrNotLeader)
+	// Forward the membership change to the leader if this node is not the leader. A leader
+	// that does not support promoting non-voters would add the node as a voter immediately.
+	if leaderAddress, ok := r.forwardingAddress(); ok {
+		if err := r.requireProtocolVersion(protocolVersionLearnerPromotion, "learner promotion"); err != nil {
+			respond(configurationFuture.responseCh, Configuration{}, err)
+			return configurationFuture
+		}
+		request := ForwardMembershipChangeRequest{
+			ID:      id,
+			Address: address,
+			Promote: true,
+			Timeout: timeout,
+		}
+		r.wg.Add(1)
+		go r.forwardMembershipChange(leaderAddress, request, configurationFuture.responseCh)
 		return configurationFuture
 	}
 
-	// Membership changes may not be submitted until a log entry for this term is committed.
-	if !r.committedThisTerm() {
-		respond(configurationFuture.responseCh, Configuration{}, ErrNoCommitThisTerm)
-		return configurationFuture
-	}
+	return r.addLearner(id, address, configurationFuture)
+}
 
-	// The membership change is still pending - wait until it completes.
-	if r.pendingConfigurationChange() {
-		respond(configurationFuture.responseCh, Configuration{}, ErrPendingConfiguration)
+// addLearner adds the node with the provided ID and address to the cluster as a non-voter,
+// starts bringing it up to date, and populates the provided future with the configuration
+// in which it has been promoted to a voter.
+func (r *Raft) addLearner(
+	id string,
+	address string,
+	configurationFuture *future[Configuration],
+) Future[Configuration] {
+	if err := r.checkMembershipChangeAllowed(protocolVersionLearnerPromotion); err != nil {
+		respond(configurationFuture.responseCh, Configuration{}, err)
 		return configurationFuture
 	}
 
-	// The provided node is already removed from the cluster.
-	if !r.isMember(id) {
+	// The provided node is already a voting member of the cluster.
+	if r.isMember(id) && r.isVoter(id) {
 		respond(configurationFuture.responseCh, *r.configuration, nil)
 		return configurationFuture
 	}
 
-	// Create the configuration without the node.
-	configuration := r.configuration.Clone()
-	delete(configuration.Members, id)
-	delete(configuration.IsVoter, id)
+	// Add the node as a non-voter if it is not already a member of the cluster. The
+	// configuration in which it is a voter is added to the log once it has caught up.
+	if !r.isMember(id) {
+		configuration := r.configuration.Clone()
+		configuration.Members[id] = address
+		configuration.IsVoter[id] = false
 
-	// Add the configuration to the log.
-	r.appendConfiguration(&configuration)
+		r.appendConfiguration(&configuration)
+
+		r.configuration = &configuration
+		r.configurationResponseCh = nil
+		r.followers[id] = &follower{nextIndex: 1, lastContact: time.Now()}
+	}
+
+	// Start the first catch-up round. If the node is already being caught up, the
+	// progress it has made is discarded and the request that started it is superseded.
+	if previous := r.followers[id].catchUp; previous != nil {
+		respond(previous.responseCh, Configuration{}, ErrCatchUpSuperseded)
+	}
+	r.followers[id].catchUp = &catchUp{
+		roundIndex: r.log.LastIndex(),
+		roundStart: time.Now(),
+		rounds:     1,
+		responseCh: configurationFuture.responseCh,
+	}
 
 	r.sendAppendEntriesToPeers()
 
 	r.logger.Debugf(
-		"request to remove node submitted: id = %s, logIndex = %d",
+		"request to add learner submitted: id = %s, address = %s, logIndex = %d",
 		id,
-		configuration.Index,
+		address,
+		r.configuration.Index,
 	)
 
 	return configurationFuture
 }
 
-// SubmitOperation accepts an operation for application to the state machine and returns a
-// future for the response to the operation. Once the operation has been applied to the state
-// machine, the returned future will be populated with the response.
-//
-// Even if the operation is submitted successfully, is not guaranteed that it will be applied
-// to the state machine if there are failures. If the operation was unable to be applied
-// to the state machine or the operation times out, the future will be populated with
-// an error.
-//
-// It may be necessary to resubmit the operation to this node or a different node if it failed.
-// It is the caller's responsibility to implement retry logic and to handle duplicate operations.
-func (r *Raft) SubmitOperation(
-	operation []byte,
-	operationType OperationType,
-	timeout time.Duration,
-) Future[OperationResponse] {
-	switch operationType {
-	case Replicated:
-		return r.submitReplicatedOperation(operation, timeout)
-	case LeaseBasedReadOnly, LinearizableReadOnly:
-		return r.submitReadOnlyOperation(operation, operationType, timeout)
-	default:
-		operationFuture := newFuture[OperationResponse](timeout)
-		respond(
-			operationFuture.responseCh,
-			OperationResponse{},
-			errors.New("operation type is not valid"),
-		)
-		return operationFuture
+// maybePromote advances the catch-up rounds of the non-voter with the provided ID and promotes
+// it to a voter once it has caught up with the leader. Expects the mutex to be locked.
+func (r *Raft) maybePromote(id string) {
+	follower, ok := r.followers[id]
+	if !ok || follower.catchUp == nil {
+		return
 	}
-}
+	catchUp := follower.catchUp
 
-// submitReplicatedOperation submits a replicated operation to be applied to the state machine.
-func (r *Raft) submitReplicatedOperation(
-	operationBytes []byte,
-	timeout time.Duration,
-) Future[OperationResponse] {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	// The node may have been made a voter by another membership change.
+	if r.isVoter(id) {
+		follower.catchUp = nil
+		respond(catchUp.responseCh, *r.configuration, nil)
+		return
+	}
 
-	operationFuture := newFuture[OperationResponse](timeout)
+	if !catchUp.caughtUp {
+		// The node has not replicated all of the entries for this round.
+		if follower.matchIndex < catchUp.roundIndex {
+			return
+		}
 
-	if r.state != Leader {
-		respond(operationFuture.responseCh, OperationResponse{}, ErrNotLeader)
-		return operationFuture
+		// A round that completes within the election timeout indicates that the node is close
+		// enough to the leader that it will not stall commitment once it is a voter. Otherwise,
+		// start another round with the entries that were appended during this one.
+		if time.Since(catchUp.roundStart) <= r.options.electionTimeout {
+			catchUp.caughtUp = true
+		} else if catchUp.rounds < r.options.maxCatchUpRounds {
+			catchUp.roundIndex = r.log.LastIndex()
+			catchUp.roundStart = time.Now()
+			catchUp.rounds++
+			return
+		} else {
+			follower.catchUp = nil
+			respond(catchUp.responseCh, Configuration{}, ErrCatchUpFailed)
+			r.logger.Warnf("non-voter did not catch up: id = %s, rounds = %d", id, catchUp.rounds)
+			return
+		}
 	}
 
-	entry := NewLogEntry(r.log.NextIndex(), r.currentTerm, operationBytes, OperationEntry)
-	if err := r.log.AppendEntry(entry); err != nil {
-		r.logger.Fatalf("failed to append entry to log: error = %v", err)
+	// The promotion must wait until there is no pending membership change.
+	if r.leadershipTransfer != nil || r.pendingConfigurationChange() {
+		return
 	}
 
-	r.operationManager.pendingReplicated[entry.Index] = operationFuture.responseCh
+	configuration := r.configuration.Clone()
+	configuration.IsVoter[id] = true
+
+	r.appendConfiguration(&configuration)
+
+	r.configuration = &configuration
+	r.configurationResponseCh = catchUp.responseCh
+	follower.catchUp = nil
 
 	r.sendAppendEntriesToPeers()
 
 	r.logger.Debugf(
-		"operation submitted: logIndex = %d, logTerm = %d, type = %s",
-		entry.Index,
-		entry.Term,
-		Replicated.String(),
+		"request to promote non-voter submitted: id = %s, rounds = %d, logIndex = %d",
+		id,
+		catchUp.rounds,
+		configuration.Index,
 	)
+}
 
-	return operationFuture
+// abortCatchUps abandons the promotion of every non-voter that is being caught up and
+// responds to each with the provided error. Expects the mutex to be locked.
+func (r *Raft) abortCatchUps(err error) {
+	for _, follower := range r.followers {
+		if follower.catchUp != nil {
+			respond(follower.catchUp.responseCh, Configuration{}, err)
+			follower.catchUp = nil
+		}
+	}
 }
 
-// submitReadOnlyOperation submits a read-only operation to be applied to the state machine.
-func (r *Raft) submitReadOnlyOperation(
-	operationBytes []byte,
-	readOnlyType OperationType,
-	timeout time.Duration,
-) Future[OperationResponse] {
+// RemoveServer will remove the node with the provided ID from the cluster
+// and returns a future for the resulting configuration. Once removed, the node
+// will remain online as a non-voter and may safely be shutdown.
+//
+// If the configuration change was not successful, the returned future will be populated
+// with an error. It may be necessary to resubmit the configuration change to this node
+// or a different node. It is safe to call this function as many times as necessary.
+//
+// It is the caller's responsibility to implement retry logic.
+func (r *Raft) RemoveServer(id string, timeout time.Duration) Future[Configuration] {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	operationFuture := newFuture[OperationResponse](timeout)
+	configurationFuture := newFuture[Configuration](timeout)
 
-	if r.state != Leader {
-		respond(operationFuture.responseCh, OperationResponse{}, ErrNotLeader)
-		return operationFuture
+	// Forward the membership change to the leader if this node is not the leader.
+	if leaderAddress, ok := r.forwardingAddress(); ok {
+		request := ForwardMembershipChangeRequest{ID: id, Remove: true, Timeout: timeout}
+		r.wg.Add(1)
+		go r.forwardMembershipChange(leaderAddress, request, configurationFuture.responseCh)
+		return configurationFuture
 	}
 
-	operation := &Operation{
-		Bytes:         operationBytes,
-		OperationType: readOnlyType,
-		readIndex:     r.commitIndex,
+	return r.removeServer(id, configurationFuture)
+}
+
+// removeServer removes the node with the provided ID from the cluster and populates
+// the provided future with the resulting configuration.
+func (r *Raft) removeServer(id string, configurationFuture *future[Configuration]) Future[Configuration] {
+	if err := r.checkMembershipChangeAllowed(0); err != nil {
+		respond(configurationFuture.responseCh, Configuration{}, err)
+		return configurationFuture
 	}
-	r.operationManager.pendingReadOnly[operation] = operationFuture.responseCh
 
-	// If the last applied index is at least as the large as the read index, the
+	// The provided node is already removed from the cluster.
+	if !r.isMember(id) {
+		respond(configurationFuture.responseCh, *r.configuration, nil)
+		return configurationFuture
+	}
+
+	// Create the configuration without the node.
+	configuration := r.configuration.Clone()
+	delete(configuration.Members, id)
+	delete(configuration.IsVoter, id)
+
+	// Add the configuration to the log.
+	r.appendConfiguration(&configuration)
+	r.configurationResponseCh = configurationFuture.responseCh
+
+	r.sendAppendEntriesToPeers()
+
+	r.logger.Debugf(
+		"request to remove node submitted: id = %s, logIndex = %d",
+		id,
+		configuration.Index,
+	)
+
+	return configurationFuture
+}
+
+// ChangeMembership applies the provided membership changes to the cluster atomically and
+// returns a future for the resulting configuration. Any number of nodes may be added,
+// removed, or have their voting status changed.
+//
+// The cluster first transitions to a joint configuration in which elections and commitment
+// require a majority of the voting members of both the current and the new configuration.
+// Once the joint configuration is committed, the cluster transitions to the new configuration.
+// The returned future is populated once the new configuration is committed.
+//
+// Membership changes require protocol version 3. If the cluster is not yet using it, the
+// returned future is populated with ErrUnsupportedProtocolVersion.
+//
+// If the configuration change was not successful, the returned future will be populated
+// with an error. It may be necessary to resubmit the configuration change to this node
+// or a different node. It is safe to call this function as many times as necessary.
+//
+// It is the caller's responsibility to implement retry logic.
+func (r *Raft) ChangeMembership(changes []MembershipChange, timeout time.Duration) Future[Configuration] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	configurationFuture := newFuture[Configuration](timeout)
+
+	// Forward the membership change to the leader if this node is not the leader. A leader
+	// that does not support joint consensus would not understand the membership changes.
+	if leaderAddress, ok := r.forwardingAddress(); ok {
+		if err := r.requireProtocolVersion(protocolVersionJointConsensus, "joint consensus"); err != nil {
+			respond(configurationFuture.responseCh, Configuration{}, err)
+			return configurationFuture
+		}
+		request := ForwardMembershipChangeRequest{Changes: changes, Timeout: timeout}
+		r.wg.Add(1)
+		go r.forwardMembershipChange(leaderAddress, request, configurationFuture.responseCh)
+		return configurationFuture
+	}
+
+	return r.changeMembership(changes, configurationFuture)
+}
+
+// changeMembership applies the provided membership changes to the cluster using joint consensus
+// and populates the provided future with the resulting configuration.
+func (r *Raft) changeMembership(
+	changes []MembershipChange,
+	configurationFuture *future[Configuration],
+) Future[Configuration] {
+	if err := validateMembershipChanges(changes); err != nil {
+		respond(configurationFuture.responseCh, Configuration{}, err)
+		return configurationFuture
+	}
+
+	if err := r.checkMembershipChangeAllowed(protocolVersionJointConsensus); err != nil {
+		respond(configurationFuture.responseCh, Configuration{}, err)
+		return configurationFuture
+	}
+
+	// Create the joint configuration and check that the membership changes do something.
+	configuration := r.configuration.joint(changes)
+	next := configuration.next()
+	if next.equal(r.configuration) {
+		respond(configurationFuture.responseCh, *r.configuration, nil)
+		return configurationFuture
+	}
+
+	// The new configuration must be able to elect a leader.
+	if !next.hasVoter() {
+		respond(
+			configurationFuture.responseCh,
+			Configuration{},
+			errors.New("membership change would leave the cluster without a voting member"),
+		)
+		return configurationFuture
+	}
+
+	// Add the joint configuration to the log. The new configuration is added
+	// to the log once the joint configuration is committed.
+	r.appendConfiguration(&configuration)
+
+	r.configuration = &configuration
+	r.configurationResponseCh = configurationFuture.responseCh
+	for id := range configuration.Members {
+		if _, ok := r.followers[id]; !ok {
+			r.followers[id] = &follower{nextIndex: 1, lastContact: time.Now()}
+		}
+	}
+
+	r.sendAppendEntriesToPeers()
+
+	r.logger.Debugf(
+		"request to change membership submitted: changes = %d, logIndex = %d",
+		len(changes),
+		configuration.Index,
+	)
+
+	return configurationFuture
+}
+
+// validateMembershipChanges returns an error if the provided membership changes are not valid.
+func validateMembershipChanges(changes []MembershipChange) error {
+	if len(changes) == 0 {
+		return errors.New("no membership changes were provided")
+	}
+	seen := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		if change.ID == "" {
+			return errors.New("membership change has an empty ID")
+		}
+		if !change.Remove && change.Address == "" {
+			return fmt.Errorf("membership change has an empty address: id = %s", change.ID)
+		}
+		if seen[change.ID] {
+			return fmt.Errorf("node has more than one membership change: id = %s", change.ID)
+		}
+		seen[change.ID] = true
+	}
+	return nil
+}
+
+// leaveJointConfiguration transitions the cluster from a joint configuration to the new
+// configuration once the joint configuration has been committed. This does nothing if this
+// node is not the leader or the current configuration is not a committed joint configuration.
+// Expects the mutex to be locked.
+func (r *Raft) leaveJointConfiguration() {
+	if r.state != Leader || !r.configuration.IsJoint() || r.pendingConfigurationChange() {
+		return
+	}
+
+	configuration := r.configuration.next()
+	r.appendConfiguration(&configuration)
+	r.configuration = &configuration
+
+	r.sendAppendEntriesToPeers()
+
+	r.logger.Debugf(
+		"leaving joint configuration: logIndex = %d",
+		configuration.Index,
+	)
+}
+
+// TransferLeadership transfers leadership from this node to the voting member with the
+// provided ID and returns a future for the result of the transfer. If the provided ID is
+// empty, leadership is transferred to the voting member with the most up-to-date log.
+//
+// The target is first brought up to date with the log of this node and then directed to
+// start an election immediately. This node will not accept operations or membership changes
+// while the transfer is in progress. If the transfer does not complete within the provided
+// timeout, it is abandoned and this node resumes accepting operations and membership changes.
+//
+// It is the caller's responsibility to implement retry logic.
+func (r *Raft) TransferLeadership(
+	targetID string,
+	timeout time.Duration,
+) Future[LeadershipTransferResponse] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transferFuture := newFuture[LeadershipTransferResponse](timeout)
+
+	// Only the leader can transfer leadership.
+	if r.state != Leader {
+		respond(transferFuture.responseCh, LeadershipTransferResponse{}, r.notLeaderError())
+		return transferFuture
+	}
+
+	// Only one leadership transfer may be in progress at a time.
+	if r.leadershipTransfer != nil {
+		respond(transferFuture.responseCh, LeadershipTransferResponse{}, ErrLeadershipTransferInProgress)
+		return transferFuture
+	}
+
+	if targetID == "" {
+		targetID = r.mostUpToDateVoter()
+		if targetID == "" {
+			respond(
+				transferFuture.responseCh,
+				LeadershipTransferResponse{},
+				errors.New("there is no voting member to transfer leadership to"),
+			)
+			return transferFuture
+		}
+	}
+
+	// Leadership may only be transferred to another voting member.
+	if targetID == r.id || !r.isVoter(targetID) {
+		respond(
+			transferFuture.responseCh,
+			LeadershipTransferResponse{},
+			fmt.Errorf("could not transfer leadership: %s is not another voting member", targetID),
+		)
+		return transferFuture
+	}
+
+	transfer := &leadershipTransfer{
+		targetID:      targetID,
+		targetAddress: r.configuration.Members[targetID],
+		term:          r.currentTerm,
+		responseCh:    transferFuture.responseCh,
+	}
+	r.leadershipTransfer = transfer
+
+	// Abandon the transfer if it does not complete in time so that this node
+	// may resume accepting operations. The timer is stopped once the transfer
+	// ends, but it may have already fired, so the transfer must still be the
+	// one in progress.
+	transfer.timer = time.AfterFunc(timeout, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.leadershipTransfer == transfer && r.state == Leader && r.currentTerm == transfer.term {
+			r.abortLeadershipTransfer(ErrTimeout)
+		}
+	})
+
+	r.logger.Infof("leadership transfer started: targetID = %s", targetID)
+
+	// Send the TimeoutNow RPC immediately if the target is already up to date.
+	// Otherwise, it is sent once the target has caught up.
+	r.maybeSendTimeoutNow()
+	r.triggerReplication(targetID)
+
+	return transferFuture
+}
+
+// mostUpToDateVoter returns the ID of the voting member, other than this node, with the
+// highest match index. Ties are broken by ID. Empty is returned if there is no other
+// voting member. Expects the mutex to be locked.
+func (r *Raft) mostUpToDateVoter() string {
+	var targetID string
+	var matchIndex uint64
+	for id, follower := range r.followers {
+		if id == r.id || !r.isVoter(id) {
+			continue
+		}
+		if targetID == "" || follower.matchIndex > matchIndex ||
+			(follower.matchIndex == matchIndex && id < targetID) {
+			targetID = id
+			matchIndex = follower.matchIndex
+		}
+	}
+	return targetID
+}
+
+// maybeSendTimeoutNow sends a TimeoutNow RPC to the target of the leadership transfer in
+// progress if the target has every log entry and one has not already been sent. Expects
+// the mutex to be locked.
+func (r *Raft) maybeSendTimeoutNow() {
+	transfer := r.leadershipTransfer
+	if transfer == nil || transfer.timeoutNowSent {
+		return
+	}
+	follower, ok := r.followers[transfer.targetID]
+	if !ok || follower.matchIndex < r.log.LastIndex() {
+		return
+	}
+	transfer.timeoutNowSent = true
+
+	// The target may be elected before the lease of this node expires, so
+	// lease-based reads must no longer be served.
+	r.operationManager.leaderLease = newLease(r.options.leaseDuration)
+
+	r.wg.Add(1)
+	go r.sendTimeoutNow(transfer)
+}
+
+// sendTimeoutNow sends a TimeoutNow RPC to the target of the provided leadership transfer.
+func (r *Raft) sendTimeoutNow(transfer *leadershipTransfer) {
+	defer r.wg.Done()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state != Leader || r.leadershipTransfer != transfer {
+		return
+	}
+
+	request := TimeoutNowRequest{Term: r.currentTerm, LeaderID: r.id}
+
+	ctx, cancel := r.leaderRPCContext(r.options.electionTimeout)
+	defer cancel()
+
+	r.mu.Unlock()
+	response, err := r.transport.SendTimeoutNow(ctx, transfer.targetAddress, request)
+	r.mu.Lock()
+
+	// Leadership was lost or the transfer was abandoned while the RPC was in-flight.
+	if r.state != Leader || r.currentTerm != request.Term || r.leadershipTransfer != transfer {
+		return
+	}
+
+	if err != nil {
+		r.abortLeadershipTransfer(fmt.Errorf("could not transfer leadership: %w", err))
+		return
+	}
+
+	// The target has started an election. Leadership is lost once this node learns of it.
+	if response.Term > r.currentTerm {
+		r.becomeFollower(transfer.targetID, response.Term)
+	}
+}
+
+// abortLeadershipTransfer abandons the leadership transfer in progress and responds to
+// it with the provided error. Expects the mutex to be locked.
+func (r *Raft) abortLeadershipTransfer(err error) {
+	transfer := r.leadershipTransfer
+	if transfer == nil {
+		return
+	}
+	r.leadershipTransfer = nil
+	transfer.timer.Stop()
+	respond(transfer.responseCh, LeadershipTransferResponse{}, err)
+	r.logger.Warnf("leadership transfer abandoned: targetID = %s, error = %v", transfer.targetID, err)
+}
+
+// completeLeadershipTransfer responds to the leadership transfer in progress, if there is one,
+// once this node has lost leadership. The transfer is only successful if the target was
+// directed to start an election. Expects the mutex to be locked.
+func (r *Raft) completeLeadershipTransfer() {
+	transfer := r.leadershipTransfer
+	if transfer == nil {
+		return
+	}
+	r.leadershipTransfer = nil
+	transfer.timer.Stop()
+	if !transfer.timeoutNowSent {
+		respond(transfer.responseCh, LeadershipTransferResponse{}, r.notLeaderError())
+		return
+	}
+	response := LeadershipTransferResponse{
+		TargetID:      transfer.targetID,
+		TargetAddress: transfer.targetAddress,
+		Term:          r.currentTerm,
+	}
+	respond(transfer.responseCh, response, nil)
+	r.logger.Infof("leadership transfer completed: targetID = %s, term = %d", transfer.targetID, r.currentTerm)
+}
+
+// SubmitOperation accepts an operation for application to the state machine and returns a
+// future for the response to the operation. Once the operation has been applied to the state
+// machine, the returned future will be populated with the response.
+//
+// Even if the operation is submitted successfully, is not guaranteed that it will be applied
+// to the state machine if there are failures. If the operation was unable to be applied
+// to the state machine or the operation times out, the future will be populated with
+// an error.
+//
+// It may be necessary to resubmit the operation to this node or a different node if it failed.
+// It is the caller's responsibility to implement retry logic and to handle duplicate operations.
+func (r *Raft) SubmitOperation(
+	operation []byte,
+	operationType OperationType,
+	timeout time.Duration,
+) Future[OperationResponse] {
+	// Forward the operation to the leader if this node is not the leader.
+	r.mu.Lock()
+	if leaderAddress, ok := r.forwardingAddress(); ok {
+		operationFuture := newFuture[OperationResponse](timeout)
+		request := ForwardOperationRequest{
+			Operation:     operation,
+			OperationType: operationType,
+			Timeout:       timeout,
+		}
+		r.wg.Add(1)
+		go r.forwardOperation(leaderAddress, request, operationFuture.responseCh)
+		r.mu.Unlock()
+		return operationFuture
+	}
+	r.mu.Unlock()
+
+	return r.submitOperation(operation, operationType, timeout)
+}
+
+// submitOperation submits an operation of the provided type to be applied to the state machine.
+func (r *Raft) submitOperation(
+	operation []byte,
+	operationType OperationType,
+	timeout time.Duration,
+) *future[OperationResponse] {
+	switch operationType {
+	case Replicated:
+		return r.submitReplicatedOperation(operation, timeout)
+	case LeaseBasedReadOnly, LinearizableReadOnly:
+		return r.submitReadOnlyOperation(operation, operationType, timeout)
+	default:
+		operationFuture := newFuture[OperationResponse](timeout)
+		respond(
+			operationFuture.responseCh,
+			OperationResponse{},
+			errors.New("operation type is not valid"),
+		)
+		return operationFuture
+	}
+}
+
+// submitReplicatedOperation submits a replicated operation to be applied to the state machine.
+func (r *Raft) submitReplicatedOperation(
+	operationBytes []byte,
+	timeout time.Duration,
+) *future[OperationResponse] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	operationFuture := newFuture[OperationResponse](timeout)
+
+	if r.state != Leader {
+		respond(operationFuture.responseCh, OperationResponse{}, r.notLeaderError())
+		return operationFuture
+	}
+
+	// Operations may not be submitted while leadership is being transferred.
+	if r.leadershipTransfer != nil {
+		respond(operationFuture.responseCh, OperationResponse{}, ErrLeadershipTransferInProgress)
+		return operationFuture
+	}
+
+	entry := NewLogEntry(r.log.NextIndex(), r.currentTerm, operationBytes, OperationEntry)
+	if err := r.log.AppendEntry(entry); err != nil {
+		r.logger.Fatalf("failed to append entry to log: error = %v", err)
+	}
+
+	r.operationManager.pendingReplicated[entry.Index] = operationFuture.responseCh
+
+	r.sendAppendEntriesToPeers()
+
+	r.logger.Debugf(
+		"operation submitted: logIndex = %d, logTerm = %d, type = %s",
+		entry.Index,
+		entry.Term,
+		Replicated.String(),
+	)
+
+	return operationFuture
+}
+
+// submitReadOnlyOperation submits a read-only operation to be applied to the state machine.
+func (r *Raft) submitReadOnlyOperation(
+	operationBytes []byte,
+	readOnlyType OperationType,
+	timeout time.Duration,
+) *future[OperationResponse] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	operationFuture := newFuture[OperationResponse](timeout)
+
+	if r.state != Leader {
+		respond(operationFuture.responseCh, OperationResponse{}, r.notLeaderError())
+		return operationFuture
+	}
+
+	// Operations may not be submitted while leadership is being transferred.
+	if r.leadershipTransfer != nil {
+		respond(operationFuture.responseCh, OperationResponse{}, ErrLeadershipTransferInProgress)
+		return operationFuture
+	}
+
+	operation := &Operation{
+		Bytes:         operationBytes,
+		OperationType: readOnlyType,
+		readIndex:     r.commitIndex,
+	}
+	r.operationManager.pendingReadOnly[operation] = operationFuture.responseCh
+
+	// If the last applied index is at least as the large as the read index, the
 	// lease-based read can be served immediately.
 	if readOnlyType == LeaseBasedReadOnly && operation.readIndex <= r.lastApplied {
 		r.readOnlyCond.Broadcast()
@@ -791,16 +1748,302 @@ func (r *Raft) submitReadOnlyOperation(
 	return operationFuture
 }
 
+// forwardingAddress returns the address of the leader that proposals submitted to this node
+// should be forwarded to. False is returned if proposal forwarding is disabled, this node is
+// the leader or is shutdown, or the leader is not known. Expects the mutex to be locked.
+func (r *Raft) forwardingAddress() (string, bool) {
+	if !r.options.forwardProposals || r.state == Leader || r.state == Shutdown {
+		return "", false
+	}
+	if r.leaderID == "" || r.leaderID == r.id {
+		return "", false
+	}
+	address, ok := r.configuration.Members[r.leaderID]
+	return address, ok
+}
+
+// notLeaderError returns an error that identifies the leader known to this node.
+// Expects the mutex to be locked.
+func (r *Raft) notLeaderError() *NotLeaderError {
+	err := &NotLeaderError{Term: r.currentTerm}
+	if r.leaderID != "" && r.leaderID != r.id {
+		err.LeaderID = r.leaderID
+		if r.configuration != nil {
+			err.LeaderAddress = r.configuration.Members[r.leaderID]
+		}
+	}
+	return err
+}
+
+// forwardOperation forwards an operation to the leader at the provided address and sends
+// the result to the provided response channel.
+func (r *Raft) forwardOperation(
+	leaderAddress string,
+	request ForwardOperationRequest,
+	responseCh chan Result[OperationResponse],
+) {
+	defer r.wg.Done()
+
+	ctx, cancel := context.WithTimeout(r.ctx, request.Timeout)
+	defer cancel()
+
+	response, err := r.transport.SendForwardOperation(ctx, leaderAddress, request)
+	if err != nil {
+		respond(responseCh, OperationResponse{}, forwardingFailure(ctx, "operation", err))
+		return
+	}
+	if response.NotLeader != nil {
+		respond(responseCh, OperationResponse{}, response.NotLeader)
+		return
+	}
+	if response.Error != "" {
+		respond(responseCh, OperationResponse{}, forwardedError(response.ErrorCode, response.Error))
+		return
+	}
+
+	applicationResponse, err := r.fsm.(ForwardingStateMachine).DecodeResponse(
+		response.ApplicationResponse,
+	)
+	if err != nil {
+		respond(
+			responseCh,
+			OperationResponse{},
+			fmt.Errorf("could not decode response to forwarded operation: %w", err),
+		)
+		return
+	}
+
+	operation := Operation{
+		Bytes:         request.Operation,
+		OperationType: request.OperationType,
+		LogIndex:      response.LogIndex,
+		LogTerm:       response.LogTerm,
+	}
+	respond(
+		responseCh,
+		OperationResponse{Operation: operation, ApplicationResponse: applicationResponse},
+		nil,
+	)
+}
+
+// forwardMembershipChange forwards a membership change to the leader at the provided address
+// and sends the resulting configuration to the provided response channel.
+func (r *Raft) forwardMembershipChange(
+	leaderAddress string,
+	request ForwardMembershipChangeRequest,
+	responseCh chan Result[Configuration],
+) {
+	defer r.wg.Done()
+
+	ctx, cancel := context.WithTimeout(r.ctx, request.Timeout)
+	defer cancel()
+
+	response, err := r.transport.SendForwardMembershipChange(ctx, leaderAddress, request)
+	if err != nil {
+		respond(responseCh, Configuration{}, forwardingFailure(ctx, "membership change", err))
+		return
+	}
+	if response.NotLeader != nil {
+		respond(responseCh, Configuration{}, response.NotLeader)
+		return
+	}
+	if response.Error != "" {
+		respond(responseCh, Configuration{}, forwardedError(response.ErrorCode, response.Error))
+		return
+	}
+
+	configuration, err := r.transport.DecodeConfiguration(response.Configuration)
+	if err != nil {
+		respond(
+			responseCh,
+			Configuration{},
+			fmt.Errorf("could not decode configuration from forwarded membership change: %w", err),
+		)
+		return
+	}
+
+	respond(responseCh, configuration, nil)
+}
+
+// forwardedErrorCode returns the code that identifies the provided error to the follower that
+// forwarded a proposal, or zero if it is not one that callers may check for.
+func forwardedErrorCode(err error) uint32 {
+	for i, forwardedErr := range forwardedErrors {
+		if errors.Is(err, forwardedErr) {
+			return uint32(i + 1)
+		}
+	}
+	return 0
+}
+
+// forwardedError returns the error with the provided code and message that was returned by the
+// leader for a forwarded proposal.
+func forwardedError(code uint32, message string) error {
+	if code == 0 || int(code) > len(forwardedErrors) {
+		return errors.New(message)
+	}
+	err := forwardedErrors[code-1]
+	if err.Error() == message {
+		return err
+	}
+	return &remoteError{message: message, err: err}
+}
+
+// remoteError is an error returned by the leader for a forwarded proposal that
+// wraps one of the errors that callers may check for.
+type remoteError struct {
+	message string
+	err     error
+}
+
+func (e *remoteError) Error() string {
+	return e.message
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.err
+}
+
+// forwardingFailure returns the error for a proposal that could not be forwarded to the leader
+// using the provided context. ErrTimeout is returned if the proposal timed out.
+func forwardingFailure(ctx context.Context, proposal string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return fmt.Errorf("could not forward %s to leader: %w", proposal, err)
+}
+
+// ForwardOperation handles operations forwarded from followers. It takes a request containing the
+// operation, submits it, and fills the response with the result once the operation has been applied
+// or has failed. The operation is not forwarded again if this node is not the leader.
+func (r *Raft) ForwardOperation(
+	ctx context.Context,
+	request *ForwardOperationRequest,
+	response *ForwardOperationResponse,
+) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("could not execute ForwardOperation RPC: %w", err)
+	}
+
+	r.logger.Debugf(
+		"ForwardOperation RPC received: type = %d, timeout = %v",
+		request.OperationType,
+		request.Timeout,
+	)
+
+	operationFuture := r.submitOperation(request.Operation, request.OperationType, request.Timeout)
+	result, err := operationFuture.awaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("could not execute ForwardOperation RPC: %w", err)
+	}
+	if err := result.Error(); err != nil {
+		var notLeader *NotLeaderError
+		if errors.As(err, &notLeader) {
+			response.NotLeader = notLeader
+			return nil
+		}
+		response.Error = err.Error()
+		response.ErrorCode = forwardedErrorCode(err)
+		return nil
+	}
+
+	applicationResponse, err := r.fsm.(ForwardingStateMachine).EncodeResponse(
+		result.Success().ApplicationResponse,
+	)
+	if err != nil {
+		response.Error = fmt.Sprintf("could not encode response to operation: %v", err)
+		return nil
+	}
+
+	response.LogIndex = result.Success().Operation.LogIndex
+	response.LogTerm = result.Success().Operation.LogTerm
+	response.ApplicationResponse = applicationResponse
+
+	return nil
+}
+
+// ForwardMembershipChange handles membership changes forwarded from followers. It takes a request
+// containing the membership change, submits it, and fills the response with the resulting
+// configuration once the change has completed or has failed. The membership change is not
+// forwarded again if this node is not the leader.
+func (r *Raft) ForwardMembershipChange(
+	ctx context.Context,
+	request *ForwardMembershipChangeRequest,
+	response *ForwardMembershipChangeResponse,
+) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("could not execute ForwardMembershipChange RPC: %w", err)
+	}
+
+	r.logger.Debugf(
+		"ForwardMembershipChange RPC received: id = %s, address = %s, voter = %t, remove = %t, promote = %t, changes = %d",
+		request.ID,
+		request.Address,
+		request.IsVoter,
+		request.Remove,
+		request.Promote,
+		len(request.Changes),
+	)
+
+	r.mu.Lock()
+	configurationFuture := newFuture[Configuration](request.Timeout)
+	if len(request.Changes) > 0 {
+		r.changeMembership(request.Changes, configurationFuture)
+	} else if request.Remove {
+		r.removeServer(request.ID, configurationFuture)
+	} else if request.Promote {
+		r.addLearner(request.ID, request.Address, configurationFuture)
+	} else {
+		r.addServer(request.ID, request.Address, request.IsVoter, configurationFuture)
+	}
+	r.mu.Unlock()
+
+	result, err := configurationFuture.awaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("could not execute ForwardMembershipChange RPC: %w", err)
+	}
+	if err := result.Error(); err != nil {
+		var notLeader *NotLeaderError
+		if errors.As(err, &notLeader) {
+			response.NotLeader = notLeader
+			return nil
+		}
+		response.Error = err.Error()
+		response.ErrorCode = forwardedErrorCode(err)
+		return nil
+	}
+
+	configuration := result.Success()
+	data, err := r.transport.EncodeConfiguration(&configuration)
+	if err != nil {
+		response.Error = fmt.Sprintf("could not encode configuration: %v", err)
+		return nil
+	}
+	response.Configuration = data
+
+	return nil
+}
+
 // AppendEntries handles log replication requests from the leader. It takes a request to append
 // entries and fills the response with the result of the append operation. This will return an error
 // if the node is shutdown.
-func (r *Raft) AppendEntries(request *AppendEntriesRequest, response *AppendEntriesResponse) error {
+func (r *Raft) AppendEntries(
+	ctx context.Context,
+	request *AppendEntriesRequest,
+	response *AppendEntriesResponse,
+) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if r.state == Shutdown {
 		return fmt.Errorf("could not execute RequestVote RPC: %s is shutdown", r.id)
 	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("could not execute AppendEntries RPC: %w", err)
+	}
+	if err := r.checkProtocolVersion(request.MinProtocolVersion, request.MaxProtocolVersion); err != nil {
+		return fmt.Errorf("could not execute AppendEntries RPC: %w", err)
+	}
 
 	r.logger.Debugf(
 		"AppendEntries RPC received: leaderID = %s, leaderCommit = %d, term = %d, prevLogIndex = %d, prevLogTerm = %d",
@@ -813,6 +2056,8 @@ func (r *Raft) AppendEntries(request *AppendEntriesRequest, response *AppendEntr
 
 	response.Term = r.currentTerm
 	response.Success = false
+	response.MinProtocolVersion = MinProtocolVersion
+	response.MaxProtocolVersion = r.options.maxProtocolVersion
 
 	// Reject any requests with an out-of-date term.
 	if request.Term < r.currentTerm {
@@ -831,6 +2076,14 @@ func (r *Raft) AppendEntries(request *AppendEntriesRequest, response *AppendEntr
 	// Update the ID of the node that this node recognizes as the leader.
 	r.leaderID = request.LeaderID
 
+	// Use the protocol version chosen by the leader. A leader that does not
+	// advertise a version only supports the original protocol.
+	if request.ProtocolVersion == 0 {
+		r.protocolVersion = MinProtocolVersion
+	} else {
+		r.protocolVersion = numeric.Min(request.ProtocolVersion, r.options.maxProtocolVersion)
+	}
+
 	// If the request has a more up-to-date term, update current term and
 	// become a followers.
 	if request.Term > r.currentTerm {
@@ -931,11 +2184,17 @@ func (r *Raft) AppendEntries(request *AppendEntriesRequest, response *AppendEntr
 			r.logger.Fatalf("failed to truncate log: %v", err)
 		}
 
-		// Fall back to the committed configuration if the current one is
-		// truncated. This is necessary since a partitioned leader may have
-		// received a membership change request.
-		if entry.Index <= r.configuration.Index {
-			r.nextConfiguration(r.committedConfiguration)
+		// Fall back to the most recent configuration remaining in the log if the current
+		// one is truncated. This is necessary since a partitioned leader may have received
+		// a membership change request. If there is no such configuration, this node has
+		// no configuration until the leader sends one.
+		if r.configuration != nil && entry.Index <= r.configuration.Index {
+			if configuration := r.latestConfiguration(); configuration != nil {
+				r.nextConfiguration(configuration)
+			} else {
+				r.logger.Warnf("clearing truncated configuration: index = %d", r.configuration.Index)
+				r.configuration = nil
+			}
 		}
 
 		toAppend = request.Entries[i:]
@@ -946,35 +2205,134 @@ func (r *Raft) AppendEntries(request *AppendEntriesRequest, response *AppendEntr
 		r.logger.Fatalf("failed to append entries to log: %v", err)
 	}
 
+	// Use the most recent configuration as soon as it is appended. Otherwise, a node leaving
+	// a joint configuration may be unable to elect a leader without the removed nodes if the
+	// leader is removed before this node learns that the configuration was committed.
+	for _, entry := range toAppend {
+		if entry.EntryType == ConfigurationEntry {
+			configuration := r.decodeConfiguration(entry.Data)
+			r.nextConfiguration(&configuration)
+		}
+	}
+
 	if request.LeaderCommit > r.commitIndex {
 		r.commitIndex = numeric.Min(request.LeaderCommit, r.log.LastIndex())
 		r.applyCond.Broadcast()
 	}
 
-	return nil
+	return nil
+}
+
+// sendAppendEntriesToPeers notifies the replicators to send an AppendEntries RPC to all nodes.
+func (r *Raft) sendAppendEntriesToPeers() {
+	// Handle the single node cluster case.
+	if r.isSingleServerCluster() {
+		if r.log.LastIndex() > r.commitIndex {
+			r.commitCond.Broadcast()
+		}
+		r.tryApplyReadOnlyOperations()
+	}
+
+	if r.state != Leader {
+		return
+	}
+
+	r.startReplicators()
+	r.round++
+	for id := range r.replicators {
+		r.triggerReplication(id)
+	}
+}
+
+// startReplicators starts a replicator for each member of the cluster that does not
+// already have one and stops the replicators for nodes that are no longer members.
+func (r *Raft) startReplicators() {
+	for id, replicator := range r.replicators {
+		if r.configuration.Members[id] != replicator.address {
+			r.stopReplicator(id)
+		}
+	}
+
+	for id, address := range r.configuration.Members {
+		if _, ok := r.replicators[id]; ok || id == r.id {
+			continue
+		}
+
+		// At most one RPC without entries is outstanding in addition to the window.
+		replicator := &replicator{
+			address: address,
+			trigger: make(chan struct{}, 1),
+			results: make(chan appendEntriesResult, r.options.maxInflight+1),
+			stop:    make(chan struct{}),
+		}
+		r.replicators[id] = replicator
+
+		r.wg.Add(1)
+		go r.replicate(id, replicator)
+	}
+}
+
+// stopReplicator stops the replicator for the node with the provided ID. Any RPCs
+// that are currently being sent by the replicator are allowed to complete, but their
+// results are discarded.
+func (r *Raft) stopReplicator(id string) {
+	if replicator, ok := r.replicators[id]; ok {
+		close(replicator.stop)
+		delete(r.replicators, id)
+	}
+}
+
+// stopReplicators stops the replicators for all nodes.
+func (r *Raft) stopReplicators() {
+	for id := range r.replicators {
+		r.stopReplicator(id)
+	}
+}
+
+// triggerReplication notifies the replicator for the node with the provided ID
+// that there may be log entries or a heartbeat to send to it.
+func (r *Raft) triggerReplication(id string) {
+	replicator, ok := r.replicators[id]
+	if !ok {
+		return
+	}
+	select {
+	case replicator.trigger <- struct{}{}:
+	default:
+	}
 }
 
-// sendAppendEntriesToPeers sends an AppendEntries RPC to all nodes.
-func (r *Raft) sendAppendEntriesToPeers() {
-	// Handle the single node cluster case.
-	if r.isSingleServerCluster() {
-		if r.log.LastIndex() > r.commitIndex {
-			r.commitCond.Broadcast()
+// replicate is a long running loop that sends log entries, heartbeats, and snapshots to the
+// node with the provided ID and handles the responses until the provided replicator is stopped.
+func (r *Raft) replicate(id string, replicator *replicator) {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-replicator.stop:
+			return
+		case <-replicator.trigger:
+			replicator.pending = true
+		case result := <-replicator.results:
+			r.handleAppendEntriesResult(id, replicator, result)
 		}
-		r.tryApplyReadOnlyOperations()
-	}
 
-	numResponses := 1
-	for id, address := range r.configuration.Members {
-		if id != r.id {
-			go r.sendAppendEntries(id, address, &numResponses)
+		// Do not send anything once stopped, even if a notification was pending.
+		select {
+		case <-replicator.stop:
+			return
+		default:
 		}
+
+		r.replicateOnce(id, replicator)
 	}
 }
 
-// sendAppendEntries sends an AppendEntries RPC to a node with the provided ID
-// and address.
-func (r *Raft) sendAppendEntries(id string, address string, numResponses *int) {
+// replicateOnce sends a snapshot to the node with the provided ID if its log no longer contains
+// the entries it needs. Otherwise, it sends batches of entries to the node until the window is
+// full or every entry has been sent, followed by a heartbeat if the replicator was notified and
+// no entries were sent. If the previous RPC failed, nothing is sent until the replicator is notified.
+func (r *Raft) replicateOnce(id string, replicator *replicator) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -988,11 +2346,43 @@ func (r *Raft) sendAppendEntries(id string, address string, numResponses *int) {
 
 	// Send a snapshot instead if the follower log no longer contains the previous log entry.
 	if follower.nextIndex <= r.lastIncludedIndex {
-		r.sendInstallSnapshot(id, address)
+		if replicator.pending {
+			replicator.pending = false
+			r.sendInstallSnapshot(id, replicator.address)
+		}
 		return
 	}
 
+	if replicator.failed && !replicator.pending {
+		return
+	}
+
+	for replicator.inflight < r.options.maxInflight && follower.nextIndex < r.log.NextIndex() {
+		r.sendAppendEntries(id, replicator, false)
+		replicator.pending = false
+	}
+
+	// If entries are outstanding, send an RPC without entries that only checks the entries
+	// known to be replicated. This maintains leadership without sending entries that may
+	// already be in-flight.
+	if replicator.pending && !replicator.heartbeating {
+		r.sendAppendEntries(id, replicator, replicator.inflight > 0)
+		replicator.pending = false
+	}
+}
+
+// sendAppendEntries sends an AppendEntries RPC to the node with the provided ID on behalf of
+// the provided replicator. The result is delivered to the replicator once a response is received.
+// If probe is true, the RPC does not contain any entries and only checks the entries known to be
+// replicated. Expects the mutex to be locked.
+func (r *Raft) sendAppendEntries(id string, replicator *replicator, probe bool) {
+	follower := r.followers[id]
+
 	nextIndex := follower.nextIndex
+	if probe {
+		nextIndex = numeric.Max(follower.matchIndex, r.lastIncludedIndex) + 1
+	}
+
 	prevLogIndex := numeric.Max(nextIndex-1, r.lastIncludedIndex)
 	prevLogTerm := r.lastIncludedTerm
 
@@ -1004,30 +2394,95 @@ func (r *Raft) sendAppendEntries(id string, address string, numResponses *int) {
 		prevLogTerm = prevEntry.Term
 	}
 
-	entries := make([]*LogEntry, 0, r.log.NextIndex()-nextIndex)
-	for index := nextIndex; index > r.lastIncludedIndex && index < r.log.NextIndex(); index++ {
-		entry, err := r.log.GetEntry(index)
-		if err != nil {
-			r.logger.Fatalf("failed getting entry from log: error = %v", err)
+	// Limit the number and size of the entries so that a follower that is far behind is
+	// brought up to date over multiple RPCs. At least one entry is always sent.
+	entries := make([]*LogEntry, 0)
+	size := 0
+	if !probe {
+		for index := nextIndex; index > r.lastIncludedIndex && index < r.log.NextIndex(); index++ {
+			if len(entries) == r.options.maxEntries {
+				break
+			}
+			entry, err := r.log.GetEntry(index)
+			if err != nil {
+				r.logger.Fatalf("failed getting entry from log: error = %v", err)
+			}
+			size += len(entry.Data)
+			if len(entries) > 0 && size > r.options.maxEntriesBytes {
+				break
+			}
+			entries = append(entries, entry)
 		}
-		entries = append(entries, entry)
 	}
 
 	request := AppendEntriesRequest{
-		Term:         r.currentTerm,
-		LeaderID:     r.id,
-		PrevLogIndex: prevLogIndex,
-		PrevLogTerm:  prevLogTerm,
-		Entries:      entries,
-		LeaderCommit: r.commitIndex,
-	}
+		Term:               r.currentTerm,
+		LeaderID:           r.id,
+		PrevLogIndex:       prevLogIndex,
+		PrevLogTerm:        prevLogTerm,
+		Entries:            entries,
+		LeaderCommit:       r.commitIndex,
+		ProtocolVersion:    r.protocolVersion,
+		MinProtocolVersion: MinProtocolVersion,
+		MaxProtocolVersion: r.options.maxProtocolVersion,
+	}
+
+	// The follower has only been verified to contain the entries up to the previous entry
+	// of a probe, so it must not commit any entries past it.
+	if probe {
+		request.LeaderCommit = numeric.Min(r.commitIndex, prevLogIndex)
+	}
+
+	// Optimistically assume the entries will be appended so that the next RPC sends
+	// the entries that follow them.
+	if len(entries) > 0 {
+		follower.nextIndex = prevLogIndex + uint64(len(entries)) + 1
+		replicator.inflight++
+	} else {
+		replicator.heartbeating = true
+	}
+
+	result := appendEntriesResult{request: request, probe: probe, round: r.round}
+	ctx, cancel := r.leaderRPCContext(r.appendEntriesTimeout())
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+		result.response, result.err = r.transport.SendAppendEntries(ctx, replicator.address, request)
+		replicator.results <- result
+	}()
+}
 
-	r.mu.Unlock()
-	response, err := r.transport.SendAppendEntries(address, request)
+// handleAppendEntriesResult updates the state of the node with the provided ID using the
+// result of an AppendEntries RPC sent by the provided replicator.
+func (r *Raft) handleAppendEntriesResult(id string, replicator *replicator, result appendEntriesResult) {
 	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	request, response, entries := result.request, result.response, result.request.Entries
+	if len(entries) > 0 {
+		replicator.inflight--
+	} else {
+		replicator.heartbeating = false
+	}
 
-	// Quit if RPC failed, leadership was lost, or the node was removed from the cluster.
-	if !r.isMember(id) || err != nil || r.state != Leader {
+	// Quit if leadership was lost or the node was removed from the cluster.
+	if !r.isMember(id) || r.state != Leader || r.currentTerm != request.Term {
+		return
+	}
+
+	follower := r.followers[id]
+
+	// The entries may not have been received, so they must be sent again.
+	if result.err != nil {
+		replicator.failed = true
+		if len(entries) > 0 {
+			follower.nextIndex = numeric.Max(
+				follower.matchIndex+1,
+				numeric.Min(follower.nextIndex, request.PrevLogIndex+1),
+			)
+		}
 		return
 	}
 
@@ -1037,27 +2492,40 @@ func (r *Raft) sendAppendEntries(id string, address string, numResponses *int) {
 		return
 	}
 
-	// If the majority of cluster acknowledges the request, this node is a legitimate leader.
-	// Try to apply pending read-only operations.
-	if numResponses != nil {
-		*numResponses += 1
-		if r.hasQuorum(*numResponses) {
-			r.tryApplyReadOnlyOperations()
-			numResponses = nil
-		}
+	replicator.failed = false
+	follower.lastContact = time.Now()
+	r.recordProtocolVersion(id, response.MinProtocolVersion, response.MaxProtocolVersion)
+
+	// If the majority of cluster has responded since this request was sent, this node is
+	// a legitimate leader. Try to apply pending read-only operations.
+	follower.ackedRound = numeric.Max(follower.ackedRound, result.round)
+	if result.round > r.verifiedRound && r.hasQuorum(r.acknowledgedRound(result.round)) {
+		r.verifiedRound = result.round
+		r.tryApplyReadOnlyOperations()
 	}
 
 	if !response.Success {
-		follower.nextIndex = response.Index
-
-		// Send a snapshot to the follower if the log no longer contains the previous entry.
-		if follower.nextIndex <= r.lastIncludedIndex {
-			r.sendInstallSnapshot(id, address)
+		// A probe may be rejected because the entries following the ones known to be
+		// replicated are still in-flight, so the rejection is ignored.
+		if result.probe {
+			return
 		}
 
+		// Roll back to the index provided by the follower. The follower is known to
+		// contain every entry up to the match index, so those are not sent again.
+		follower.nextIndex = numeric.Max(response.Index, follower.matchIndex+1)
+
+		// Send the entries following the index provided by the follower, or a snapshot
+		// if the log no longer contains them.
+		replicator.pending = true
+
 		return
 	}
 
+	// The follower no longer needs a snapshot.
+	follower.snapshotSource = ""
+	follower.fetchFailed = false
+
 	// Update the next and match index of the followers.
 	if request.PrevLogIndex+uint64(len(entries)) > follower.matchIndex {
 		follower.nextIndex = numeric.Max(
@@ -1069,18 +2537,46 @@ func (r *Raft) sendAppendEntries(id string, address string, numResponses *int) {
 			r.commitCond.Broadcast()
 		}
 	}
+
+	// The target of a leadership transfer may now be up to date.
+	r.maybeSendTimeoutNow()
+
+	// A non-voter that is being caught up may now be ready for promotion.
+	r.maybePromote(id)
+}
+
+// acknowledgedRound returns the members of the cluster, including this node, that have
+// responded to an AppendEntries RPC sent in the provided round or later.
+func (r *Raft) acknowledgedRound(round uint64) map[string]bool {
+	acknowledged := map[string]bool{r.id: true}
+	for id, follower := range r.followers {
+		if follower.ackedRound >= round {
+			acknowledged[id] = true
+		}
+	}
+	return acknowledged
 }
 
 // RequestVote handles vote requests from other nodes during elections. It takes a vote request
 // and fills the response with the result of the vote. This will return an error if the node is
 // shutdown.
-func (r *Raft) RequestVote(request *RequestVoteRequest, response *RequestVoteResponse) error {
+func (r *Raft) RequestVote(
+	ctx context.Context,
+	request *RequestVoteRequest,
+	response *RequestVoteResponse,
+) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if r.state == Shutdown {
 		return fmt.Errorf("could not execute RequestVote RPC: %s is shutdown", r.id)
 	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("could not execute RequestVote RPC: %w", err)
+	}
+	if err := r.checkProtocolVersion(request.MinProtocolVersion, request.MaxProtocolVersion); err != nil {
+		return fmt.Errorf("could not execute RequestVote RPC: %w", err)
+	}
 
 	r.logger.Debugf(
 		"RequestVote RPC received: candidateID = %s, prevote = %t, term = %d, lastLogIndex = %d, lastLogTerm = %d",
@@ -1093,6 +2589,8 @@ func (r *Raft) RequestVote(request *RequestVoteRequest, response *RequestVoteRes
 
 	response.Term = r.currentTerm
 	response.VoteGranted = false
+	response.MinProtocolVersion = MinProtocolVersion
+	response.MaxProtocolVersion = r.options.maxProtocolVersion
 
 	// This check is necessary to prevent disruptive servers.
 	//
@@ -1100,8 +2598,10 @@ func (r *Raft) RequestVote(request *RequestVoteRequest, response *RequestVoteRes
 	// 1. This node is a leader and it has recently has successful contact with
 	//    a majority of the cluster (it has a valid lease).
 	// 2. This node is a follower and it has been recently contacted by the leader.
-	if r.operationManager.leaderLease.isValid() ||
-		time.Since(r.lastContact) < r.options.electionTimeout {
+	//
+	// The check is skipped if the leader directed the candidate to start the election.
+	if !request.LeadershipTransfer && (r.operationManager.leaderLease.isValid() ||
+		time.Since(r.lastContact) < r.options.electionTimeout) {
 		r.logger.Debugf(
 			"RequestVote RPC rejected: reason = recent contact from leader, knownLeader = %s",
 			r.leaderID,
@@ -1222,12 +2722,13 @@ func (r *Raft) election() {
 		r.becomeCandidate()
 	}
 
-	r.sendRequestVoteToPeers()
+	r.sendRequestVoteToPeers(false)
 }
 
 // sendRequestVoteToPeers sends a RequestVoteRPC to all nodes in the cluster,
-// excluding those that are non-voters.
-func (r *Raft) sendRequestVoteToPeers() {
+// excluding those that are non-voters. If leadershipTransfer is true, the
+// election was started at the request of the leader.
+func (r *Raft) sendRequestVoteToPeers(leadershipTransfer bool) {
 	// Handle the single node cluster case.
 	if r.isSingleServerCluster() {
 		r.becomeLeader()
@@ -1235,18 +2736,24 @@ func (r *Raft) sendRequestVoteToPeers() {
 	}
 
 	// Send RequestVote RPCs to all voting members of the cluster.
-	votesRecieved := 1
+	votesRecieved := map[string]bool{r.id: true}
 	isPrevote := r.state == PreCandidate
 	for id, address := range r.configuration.Members {
 		if id != r.id && r.isVoter(id) {
-			go r.sendRequestVote(id, address, &votesRecieved, isPrevote)
+			go r.sendRequestVote(id, address, votesRecieved, isPrevote, leadershipTransfer)
 		}
 	}
 }
 
 // sendRequestVote sends a RequestVote RPC to the node with the provided
 // ID and address if it is a voting member.
-func (r *Raft) sendRequestVote(id string, address string, votes *int, prevote bool) {
+func (r *Raft) sendRequestVote(
+	id string,
+	address string,
+	votes map[string]bool,
+	prevote bool,
+	leadershipTransfer bool,
+) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -1257,11 +2764,14 @@ func (r *Raft) sendRequestVote(id string, address string, votes *int, prevote bo
 	}
 
 	request := RequestVoteRequest{
-		CandidateID:  r.id,
-		Term:         r.currentTerm,
-		LastLogIndex: r.log.LastIndex(),
-		LastLogTerm:  r.log.LastTerm(),
-		Prevote:      prevote,
+		CandidateID:        r.id,
+		Term:               r.currentTerm,
+		LastLogIndex:       r.log.LastIndex(),
+		LastLogTerm:        r.log.LastTerm(),
+		Prevote:            prevote,
+		LeadershipTransfer: leadershipTransfer,
+		MinProtocolVersion: MinProtocolVersion,
+		MaxProtocolVersion: r.options.maxProtocolVersion,
 	}
 
 	// Use the term that would be used in the election if this is a prevote.
@@ -1269,8 +2779,11 @@ func (r *Raft) sendRequestVote(id string, address string, votes *int, prevote bo
 		request.Term++
 	}
 
+	ctx, cancel := context.WithTimeout(r.ctx, r.options.electionTimeout)
+	defer cancel()
+
 	r.mu.Unlock()
-	response, err := r.transport.SendRequestVote(address, request)
+	response, err := r.transport.SendRequestVote(ctx, address, request)
 	r.mu.Lock()
 
 	if err != nil || r.state == Shutdown {
@@ -1282,9 +2795,11 @@ func (r *Raft) sendRequestVote(id string, address string, votes *int, prevote bo
 		return
 	}
 
+	r.recordProtocolVersion(id, response.MinProtocolVersion, response.MaxProtocolVersion)
+
 	// Increment vote count if vote is granted.
 	if response.VoteGranted {
-		*votes++
+		votes[id] = true
 	}
 
 	// Become a follower if a recipient has a more up-to-date term.
@@ -1295,7 +2810,7 @@ func (r *Raft) sendRequestVote(id string, address string, votes *int, prevote bo
 
 	// If this is a prevote and a majority of the cluster respond with success to this node's
 	// vote requests, become a candidate.
-	if r.hasQuorum(*votes) && r.state == PreCandidate {
+	if r.hasQuorum(votes) && r.state == PreCandidate {
 		// Signal to the election loop to start an election so that the real election
 		// does not have to wait until the election ticker goes off again.
 		r.state = Candidate
@@ -1303,15 +2818,70 @@ func (r *Raft) sendRequestVote(id string, address string, votes *int, prevote bo
 	}
 
 	// If this an election and a majority of the cluster vote for this node, become the leader.
-	if !prevote && r.hasQuorum(*votes) && r.state == Candidate {
+	if !prevote && r.hasQuorum(votes) && r.state == Candidate {
 		r.becomeLeader()
 	}
 }
 
+// TimeoutNow handles requests from the leader to start an election immediately so that
+// leadership is transferred to this node. The election skips the prevote and the other
+// nodes grant their vote even if they have recently been contacted by the leader. This
+// will return an error if the node is shutdown.
+func (r *Raft) TimeoutNow(
+	ctx context.Context,
+	request *TimeoutNowRequest,
+	response *TimeoutNowResponse,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state == Shutdown {
+		return fmt.Errorf("could not execute TimeoutNow RPC: %s is shutdown", r.id)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("could not execute TimeoutNow RPC: %w", err)
+	}
+
+	r.logger.Debugf(
+		"TimeoutNow RPC received: leaderID = %s, term = %d",
+		request.LeaderID,
+		request.Term,
+	)
+
+	response.Term = r.currentTerm
+
+	// Reject any requests with an out-of-date term.
+	if request.Term < r.currentTerm {
+		r.logger.Debugf(
+			"TimeoutNow RPC rejected: reason = out of date term, localTerm = %d, remoteTerm = %d",
+			r.currentTerm,
+			request.Term,
+		)
+		return nil
+	}
+
+	if request.Term > r.currentTerm {
+		r.becomeFollower(request.LeaderID, request.Term)
+	}
+
+	// Only voting members may become the leader.
+	if !r.isVoter(r.id) {
+		r.logger.Debugf("TimeoutNow RPC rejected: reason = not a voting member")
+		return nil
+	}
+
+	r.becomeCandidate()
+	r.sendRequestVoteToPeers(true)
+	response.Term = r.currentTerm
+
+	return nil
+}
+
 // InstallSnapshot handles snapshot installation requests from the leader. It takes a request to
 // install a snapshot and fills the response with the result of the installation. This will
 // return an error if the node is shutdown.
 func (r *Raft) InstallSnapshot(
+	ctx context.Context,
 	request *InstallSnapshotRequest,
 	response *InstallSnapshotResponse,
 ) error {
@@ -1321,6 +2891,12 @@ func (r *Raft) InstallSnapshot(
 	if r.state == Shutdown {
 		return fmt.Errorf("could not execute InstallSnapshot RPC: %s is shutdown", r.id)
 	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("could not execute InstallSnapshot RPC: %w", err)
+	}
+	if err := r.checkProtocolVersion(request.MinProtocolVersion, request.MaxProtocolVersion); err != nil {
+		return fmt.Errorf("could not execute InstallSnapshot RPC: %w", err)
+	}
 
 	r.logger.Debugf(
 		"InstallSnapshot RPC received: leaderID = %s, term = %d, lastIndex = %d, lastTerm = %d, offset = %d, done = %v",
@@ -1333,6 +2909,8 @@ func (r *Raft) InstallSnapshot(
 	)
 
 	response.Term = r.currentTerm
+	response.MinProtocolVersion = MinProtocolVersion
+	response.MaxProtocolVersion = r.options.maxProtocolVersion
 
 	// Reject the request if the term is out-of-date.
 	if r.currentTerm > request.Term {
@@ -1357,10 +2935,32 @@ func (r *Raft) InstallSnapshot(
 
 	r.lastContact = time.Now()
 
+	// The leader has directed this node to fetch the snapshot from a peer.
+	if request.SourceID != "" {
+		r.startSnapshotFetch(request, response)
+	} else {
+		r.installSnapshot(request, response)
+	}
+
+	// Let the leader know which entries this node has so that it does not direct this node
+	// to fetch a snapshot that it has already fetched.
+	response.LastIncludedIndex = numeric.Max(r.lastIncludedIndex, r.lastApplied)
+
+	return nil
+}
+
+// installSnapshot writes the chunk of the snapshot contained in the provided request to
+// the snapshot file and, if the chunk is the last one, installs the snapshot. It fills
+// the response with the result. The lock must be held when this is called, but it will
+// be released while the state machine is restored.
+func (r *Raft) installSnapshot(
+	request *InstallSnapshotRequest,
+	response *InstallSnapshotResponse,
+) {
 	// The received snapshot does not contain anything new.
 	if r.lastIncludedIndex >= request.LastIncludedIndex ||
 		r.lastApplied >= request.LastIncludedIndex {
-		return nil
+		return
 	}
 
 	// Discard an incomplete snapshot if this request has a greater last index.
@@ -1376,11 +2976,31 @@ func (r *Raft) InstallSnapshot(
 
 	// Create a new snapshot file if one has not already been created.
 	if r.snapshot == nil {
-		snapshot, err := r.snapshotStorage.NewSnapshotFile(
-			request.LastIncludedIndex,
-			request.LastIncludedTerm,
-			request.Configuration,
-		)
+		var snapshot SnapshotFile
+		var err error
+		if request.BaseIndex != 0 {
+			// An incremental snapshot can only be installed if its base is present.
+			if !r.hasSnapshotChain(request.BaseIndex) {
+				r.logger.Debugf(
+					"InstallSnapshot RPC rejected: reason = missing base snapshot, baseIndex = %d",
+					request.BaseIndex,
+				)
+				response.MissingBase = true
+				return
+			}
+			snapshot, err = r.snapshotStorage.(IncrementalSnapshotStorage).NewIncrementalSnapshotFile(
+				request.LastIncludedIndex,
+				request.LastIncludedTerm,
+				request.BaseIndex,
+				request.Configuration,
+			)
+		} else {
+			snapshot, err = r.snapshotStorage.NewSnapshotFile(
+				request.LastIncludedIndex,
+				request.LastIncludedTerm,
+				request.Configuration,
+			)
+		}
 		if err != nil {
 			r.logger.Fatalf("failed to create snapshot file: error = %v", err)
 		}
@@ -1399,7 +3019,7 @@ func (r *Raft) InstallSnapshot(
 			offset,
 			request.Offset,
 		)
-		return nil
+		return
 	}
 
 	// Write the snapshot chunk to the file.
@@ -1411,7 +3031,7 @@ func (r *Raft) InstallSnapshot(
 	response.BytesWritten += n
 
 	if !request.Done {
-		return nil
+		return
 	}
 
 	if err := r.snapshot.Close(); err != nil {
@@ -1433,7 +3053,7 @@ func (r *Raft) InstallSnapshot(
 
 		// It's possible that a snapshot was taken and the log was compacted while the lock was released.
 		if r.state == Shutdown || r.lastIncludedIndex > request.LastIncludedIndex {
-			return nil
+			return
 		}
 
 		r.logger.Warnf("compacting log: logIndex = %d", request.LastIncludedIndex)
@@ -1441,55 +3061,263 @@ func (r *Raft) InstallSnapshot(
 			r.logger.Fatalf("failed to compact log: error = %v", err)
 		}
 
-		return nil
+		return
+	}
+
+	snapshot, err := r.snapshotStorage.SnapshotFile()
+	if err != nil {
+		r.logger.Fatalf("failed to get snapshot file: error = %v", err)
+	}
+
+	// Restore the state machine with the snapshot.
+	// This could take a while so it's probably best that the lock is released.
+	r.mu.Unlock()
+	r.logger.Warnf(
+		"restoring state machine with snapshot: lastIndex = %d, lastTerm = %d",
+		request.LastIncludedIndex,
+		request.LastIncludedTerm,
+	)
+	if err := r.restoreStateMachine(snapshot); err != nil {
+		r.logger.Fatalf("failed to restore state machine with snapshot: error = %v", err)
+	}
+	r.mu.Lock()
+
+	if r.state == Shutdown {
+		return
+	}
+
+	r.lastApplied = request.LastIncludedIndex
+	r.commitIndex = request.LastIncludedIndex
+
+	// Discard the entire log.
+	r.logger.Warnf(
+		"discarding log: lastIndex = %d, lastTerm = %d",
+		request.LastIncludedIndex,
+		request.LastIncludedTerm,
+	)
+	if err := r.log.DiscardEntries(request.LastIncludedIndex, request.LastIncludedTerm); err != nil {
+		r.logger.Fatalf("failed to discard log entries: error = %v", err)
+	}
+
+	// Update the configuration.
+	r.applyConfiguration(request.Configuration)
+
+	r.logger.Infof(
+		"snapshot installation completed successfully: lastIndex = %d, lastTerm = %d",
+		request.LastIncludedIndex,
+		request.LastIncludedTerm,
+	)
+}
+
+// startSnapshotFetch starts fetching the snapshot from the peer specified in the provided request
+// if this node is not already doing so. If the previous attempt to fetch a snapshot failed, the
+// failure is reported in the response instead so that the leader will send the snapshot itself.
+func (r *Raft) startSnapshotFetch(
+	request *InstallSnapshotRequest,
+	response *InstallSnapshotResponse,
+) {
+	// The snapshot does not contain anything new.
+	if r.lastIncludedIndex >= request.LastIncludedIndex ||
+		r.lastApplied >= request.LastIncludedIndex {
+		return
+	}
+
+	// A snapshot is already being fetched.
+	if r.snapshotSource != "" {
+		return
+	}
+
+	if r.snapshotFetchFailed {
+		r.snapshotFetchFailed = false
+		response.FetchFailed = true
+		return
+	}
+
+	r.logger.Infof(
+		"fetching snapshot from peer: sourceID = %s, sourceAddress = %s, minIndex = %d",
+		request.SourceID,
+		request.SourceAddress,
+		request.LastIncludedIndex,
+	)
+
+	r.snapshotSource = request.SourceID
+	r.wg.Add(1)
+	go r.fetchSnapshot(request.SourceAddress, request.LastIncludedIndex, request.Term)
+}
+
+// fetchSnapshot fetches the most recent snapshot of the peer with the provided address chunk by
+// chunk and installs it. The last included index of the snapshot must be at least the provided
+// minimum index. Since the peer may not have taken a recent enough snapshot yet, the fetch is
+// retried for up to an election timeout before giving up. The fetch is abandoned if this node's
+// term changes or it is shutdown.
+func (r *Raft) fetchSnapshot(sourceAddress string, minIndex uint64, term uint64) {
+	defer r.wg.Done()
+
+	var lastIncludedIndex uint64
+	var offset int64
+	failed := false
+	deadline := time.Now().Add(r.options.electionTimeout)
+
+	for {
+		request := FetchSnapshotRequest{
+			MinIndex:          minIndex,
+			LastIncludedIndex: lastIncludedIndex,
+			Offset:            offset,
+		}
+		ctx, cancel := context.WithTimeout(r.ctx, r.options.electionTimeout)
+		response, err := r.transport.SendFetchSnapshot(ctx, sourceAddress, request)
+		cancel()
+
+		r.mu.Lock()
+
+		if r.state == Shutdown || r.currentTerm != term {
+			break
+		}
+
+		// Wait for the peer to take a recent enough snapshot.
+		if err == nil && response.Unavailable && lastIncludedIndex == 0 &&
+			time.Now().Before(deadline) {
+			r.mu.Unlock()
+			time.Sleep(r.options.heartbeatInterval)
+			continue
+		}
+
+		if err != nil || response.Unavailable {
+			r.logger.Warnf(
+				"failed to fetch snapshot from peer: sourceID = %s, unavailable = %v, error = %v",
+				r.snapshotSource,
+				response.Unavailable,
+				err,
+			)
+			failed = true
+			break
+		}
+
+		// The state contained in the snapshot was received some other way.
+		if r.lastIncludedIndex >= response.LastIncludedIndex ||
+			r.lastApplied >= response.LastIncludedIndex {
+			break
+		}
+
+		installRequest := InstallSnapshotRequest{
+			LeaderID:          r.leaderID,
+			Term:              term,
+			LastIncludedIndex: response.LastIncludedIndex,
+			LastIncludedTerm:  response.LastIncludedTerm,
+			Configuration:     response.Configuration,
+			Bytes:             response.Bytes,
+			Offset:            offset,
+			Done:              response.Done,
+			BaseIndex:         response.BaseIndex,
+		}
+		var installResponse InstallSnapshotResponse
+		r.installSnapshot(&installRequest, &installResponse)
+
+		if installResponse.MissingBase ||
+			installResponse.BytesWritten != offset+int64(len(response.Bytes)) {
+			r.logger.Warnf(
+				"failed to install snapshot fetched from peer: sourceID = %s, missingBase = %v",
+				r.snapshotSource,
+				installResponse.MissingBase,
+			)
+			failed = true
+			break
+		}
+
+		if response.Done {
+			r.logger.Infof(
+				"fetched snapshot from peer: sourceID = %s, lastIndex = %d, lastTerm = %d",
+				r.snapshotSource,
+				response.LastIncludedIndex,
+				response.LastIncludedTerm,
+			)
+			break
+		}
+
+		lastIncludedIndex = response.LastIncludedIndex
+		offset = installResponse.BytesWritten
+
+		r.mu.Unlock()
+	}
+
+	// Discard any part of the snapshot that was written so that the leader may send it instead.
+	if failed {
+		r.snapshotFetchFailed = true
+		if r.snapshot != nil {
+			if err := r.snapshot.Discard(); err != nil {
+				r.logger.Fatalf("failed to discard snapshot: error = %v", err)
+			}
+			r.snapshot = nil
+		}
+	}
+
+	r.snapshotSource = ""
+	r.mu.Unlock()
+}
+
+// FetchSnapshot handles requests from peers to fetch the most recent snapshot of this node. It takes
+// a request for a chunk of the snapshot and fills the response with the chunk. This will return an
+// error if the node is shutdown.
+func (r *Raft) FetchSnapshot(
+	ctx context.Context,
+	request *FetchSnapshotRequest,
+	response *FetchSnapshotResponse,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state == Shutdown {
+		return fmt.Errorf("could not execute FetchSnapshot RPC: %s is shutdown", r.id)
 	}
-
-	snapshot, err := r.snapshotStorage.SnapshotFile()
-	if err != nil {
-		r.logger.Fatalf("failed to get snapshot file: error = %v", err)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("could not execute FetchSnapshot RPC: %w", err)
 	}
 
-	// Restore the state machine with the snapshot.
-	// This could take a while so it's probably best that the lock is released.
-	r.mu.Unlock()
-	r.logger.Warnf(
-		"restoring state machine with snapshot: lastIndex = %d, lastTerm = %d",
+	r.logger.Debugf(
+		"FetchSnapshot RPC received: minIndex = %d, lastIndex = %d, offset = %d",
+		request.MinIndex,
 		request.LastIncludedIndex,
-		request.LastIncludedTerm,
+		request.Offset,
 	)
-	if err := r.fsm.Restore(snapshot); err != nil {
-		r.logger.Fatalf("failed to restore state machine with snapshot: error = %v", err)
+
+	snapshot, err := r.snapshotStorage.SnapshotFile()
+	if err != nil {
+		r.logger.Fatalf("failed to get snapshot file: error = %v", err)
 	}
-	if err := snapshot.Close(); err != nil {
-		r.logger.Fatalf("failed to close snapshot file: error = %v", err)
+	if snapshot == nil {
+		response.Unavailable = true
+		return nil
 	}
-	r.mu.Lock()
+	defer func() {
+		if err := snapshot.Close(); err != nil {
+			r.logger.Errorf("failed to close snapshot file: error = %v", err)
+		}
+	}()
 
-	if r.state == Shutdown {
+	// The most recent snapshot is either not recent enough or is not the one being fetched.
+	metadata := snapshot.Metadata()
+	if metadata.LastIncludedIndex < request.MinIndex ||
+		(request.LastIncludedIndex != 0 && metadata.LastIncludedIndex != request.LastIncludedIndex) {
+		response.Unavailable = true
 		return nil
 	}
 
-	r.lastApplied = request.LastIncludedIndex
-	r.commitIndex = request.LastIncludedIndex
-
-	// Discard the entire log.
-	r.logger.Warnf(
-		"discarding log: lastIndex = %d, lastTerm = %d",
-		request.LastIncludedIndex,
-		request.LastIncludedTerm,
-	)
-	if err := r.log.DiscardEntries(request.LastIncludedIndex, request.LastIncludedTerm); err != nil {
-		r.logger.Fatalf("failed to discard log entries: error = %v", err)
+	// Read the requested chunk of the snapshot from the file.
+	if _, err := snapshot.Seek(request.Offset, io.SeekStart); err != nil {
+		r.logger.Fatalf("failed to seek snapshot file: error = %v", err)
+	}
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, snapshot, snapshotChunkSize)
+	if err != nil && err != io.EOF {
+		r.logger.Fatalf("failed to read snapshot file: error = %v", err)
 	}
 
-	// Update the configuration.
-	r.applyConfiguration(request.Configuration)
-
-	r.logger.Infof(
-		"snapshot installation completed successfully: lastIndex = %d, lastTerm = %d",
-		request.LastIncludedIndex,
-		request.LastIncludedTerm,
-	)
+	response.LastIncludedIndex = metadata.LastIncludedIndex
+	response.LastIncludedTerm = metadata.LastIncludedTerm
+	response.BaseIndex = metadata.BaseIndex
+	response.Configuration = metadata.Configuration
+	response.Bytes = buf.Bytes()
+	response.Done = n < snapshotChunkSize
 
 	return nil
 }
@@ -1503,7 +3331,7 @@ func (r *Raft) snapshotLoop() {
 
 	for r.state != Shutdown {
 		r.snapshotCond.Wait()
-		if r.fsm.NeedSnapshot(r.log.Size()) {
+		if r.fullSnapshotRequested || r.fsm.NeedSnapshot(r.log.Size()) {
 			r.takeSnapshot()
 		}
 	}
@@ -1511,10 +3339,15 @@ func (r *Raft) snapshotLoop() {
 
 // takeSnapshot takes a snapshot of the state machine. A snapshot will
 // only be taken if there is new state since the previous snapshot and there
-// is not a pending configuration change.
+// is not a pending configuration change. If a full snapshot has been requested,
+// one will be taken even if there is no new state. Otherwise, the snapshot will
+// be incremental if possible.
 func (r *Raft) takeSnapshot() {
+	full := r.fullSnapshotRequested
+
 	// There is nothing new to snapshot.
-	if r.lastApplied <= r.lastIncludedIndex {
+	if r.lastApplied == 0 || r.lastApplied < r.lastIncludedIndex ||
+		(!full && r.lastApplied == r.lastIncludedIndex) {
 		return
 	}
 
@@ -1523,24 +3356,48 @@ func (r *Raft) takeSnapshot() {
 		return
 	}
 
-	lastAppliedEntry, err := r.log.GetEntry(r.lastApplied)
-	if err != nil {
-		r.logger.Fatalf("failed to get entry from log: error = %v", err)
+	r.fullSnapshotRequested = false
+
+	// The log has already been compacted through the last included index.
+	lastIndex, lastTerm := r.lastIncludedIndex, r.lastIncludedTerm
+	if r.lastApplied > r.lastIncludedIndex {
+		lastAppliedEntry, err := r.log.GetEntry(r.lastApplied)
+		if err != nil {
+			r.logger.Fatalf("failed to get entry from log: error = %v", err)
+		}
+		lastIndex, lastTerm = lastAppliedEntry.Index, lastAppliedEntry.Term
+	}
+
+	var baseIndex uint64
+	if !full && r.canTakeIncrementalSnapshot() {
+		baseIndex = r.lastIncludedIndex
 	}
 
 	r.logger.Infof(
-		"starting to take snapshot: lastIndex = %d, lastTerm = %d",
-		lastAppliedEntry.Index,
-		lastAppliedEntry.Term,
+		"starting to take snapshot: lastIndex = %d, lastTerm = %d, baseIndex = %d",
+		lastIndex,
+		lastTerm,
+		baseIndex,
 	)
 
 	// Create a new snapshot file.
+	var snapshot SnapshotFile
+	var err error
 	configurationData := r.encodeConfiguration(r.committedConfiguration)
-	snapshot, err := r.snapshotStorage.NewSnapshotFile(
-		lastAppliedEntry.Index,
-		lastAppliedEntry.Term,
-		configurationData,
-	)
+	if baseIndex != 0 {
+		snapshot, err = r.snapshotStorage.(IncrementalSnapshotStorage).NewIncrementalSnapshotFile(
+			lastIndex,
+			lastTerm,
+			baseIndex,
+			configurationData,
+		)
+	} else {
+		snapshot, err = r.snapshotStorage.NewSnapshotFile(
+			lastIndex,
+			lastTerm,
+			configurationData,
+		)
+	}
 	if err != nil {
 		r.logger.Fatalf("failed to create snapshot file: error = %v", err)
 	}
@@ -1548,7 +3405,12 @@ func (r *Raft) takeSnapshot() {
 	// Take a snapshot of the state machine.
 	// It's best that the lock is not held here since this might take a while.
 	r.mu.Unlock()
-	if err := r.fsm.Snapshot(snapshot); err != nil {
+	if baseIndex != 0 {
+		err = r.fsm.(IncrementalStateMachine).SnapshotDelta(baseIndex, snapshot)
+	} else {
+		err = r.fsm.Snapshot(snapshot)
+	}
+	if err != nil {
 		r.logger.Fatalf("failed to take snapshot of state machine: error = %v", err)
 	}
 	if err := snapshot.Close(); err != nil {
@@ -1556,14 +3418,21 @@ func (r *Raft) takeSnapshot() {
 	}
 	r.mu.Lock()
 
+	// Snapshot files that are open for followers may no longer be the most recent.
+	if lastIndex == r.lastIncludedIndex {
+		r.resetSnapshotFiles()
+		r.logger.Infof("full snapshot taken successfully: lastIndex = %d, lastTerm = %d", lastIndex, lastTerm)
+		return
+	}
+
 	// It's possible a snapshot was installed and the log was compacted while the lock was released.
-	if lastAppliedEntry.Index <= r.lastIncludedIndex {
+	if lastIndex <= r.lastIncludedIndex {
 		return
 	}
 
 	// Compact the log.
-	r.lastIncludedIndex = lastAppliedEntry.Index
-	r.lastIncludedTerm = lastAppliedEntry.Term
+	r.lastIncludedIndex = lastIndex
+	r.lastIncludedTerm = lastTerm
 	r.logger.Warnf("compacting log: logIndex = %d", r.lastIncludedIndex)
 	if err := r.log.Compact(r.lastIncludedIndex); err != nil {
 		r.logger.Fatalf("failed to compact log: error = %v", err)
@@ -1591,13 +3460,63 @@ func (r *Raft) sendInstallSnapshot(id, address string) {
 
 	follower := r.followers[id]
 
+	// Direct the follower to fetch the snapshot from another follower if possible.
+	if follower.snapshot == nil && !follower.fetchFailed &&
+		r.peerSupportsProtocolVersion(id, protocolVersionSnapshotTransfer) {
+		if follower.snapshotSource == "" {
+			follower.snapshotSource = r.snapshotSourceFor(id)
+		}
+		if follower.snapshotSource != "" {
+			r.directSnapshotFetch(id, address, follower)
+			return
+		}
+	}
+
+	// Only one chunk of the snapshot is sent to the follower at a time.
+	if follower.sendingSnapshot {
+		return
+	}
+
 	// Retrieve the most recent snapshot file to send to the follower if one is not already open.
 	if follower.snapshot == nil {
 		snapshot, err := r.snapshotStorage.SnapshotFile()
 		if err != nil {
 			r.logger.Fatalf("failed to get snapshot file: error = %v", err)
 		}
+
+		// The follower does not have the base of the most recent snapshot or does not
+		// support incremental snapshots, so a full snapshot must be taken before one
+		// can be sent to it.
+		needsFullSnapshot := follower.needsFullSnapshot ||
+			!r.peerSupportsProtocolVersion(id, protocolVersionSnapshotTransfer)
+		if needsFullSnapshot && snapshot.Metadata().BaseIndex != 0 {
+			if err := snapshot.Close(); err != nil {
+				r.logger.Fatalf("failed to close snapshot file: error = %v", err)
+			}
+			r.fullSnapshotRequested = true
+			r.snapshotCond.Broadcast()
+			return
+		}
+
+		size, err := snapshot.Seek(0, io.SeekEnd)
+		if err != nil {
+			r.logger.Fatalf("failed to seek snapshot file: error = %v", err)
+		}
+		if _, err := snapshot.Seek(0, io.SeekStart); err != nil {
+			r.logger.Fatalf("failed to seek snapshot file: error = %v", err)
+		}
+
+		r.logger.Infof(
+			"sending snapshot to follower: ID = %s, lastIndex = %d, size = %d",
+			id,
+			snapshot.Metadata().LastIncludedIndex,
+			size,
+		)
+
 		follower.snapshot = snapshot
+		follower.snapshotSize = size
+		follower.snapshotBytesSent = 0
+		follower.snapshotStart = time.Now()
 	}
 
 	metadata := follower.snapshot.Metadata()
@@ -1607,29 +3526,51 @@ func (r *Raft) sendInstallSnapshot(id, address string) {
 	}
 
 	request := InstallSnapshotRequest{
-		LeaderID:          r.id,
-		Term:              r.currentTerm,
-		LastIncludedIndex: metadata.LastIncludedIndex,
-		LastIncludedTerm:  metadata.LastIncludedTerm,
-		Configuration:     metadata.Configuration,
-		Offset:            offset,
+		LeaderID:           r.id,
+		Term:               r.currentTerm,
+		LastIncludedIndex:  metadata.LastIncludedIndex,
+		LastIncludedTerm:   metadata.LastIncludedTerm,
+		Configuration:      metadata.Configuration,
+		Offset:             offset,
+		BaseIndex:          metadata.BaseIndex,
+		MinProtocolVersion: MinProtocolVersion,
+		MaxProtocolVersion: r.options.maxProtocolVersion,
+	}
+
+	// Chunks are kept small enough to be sent within a heartbeat interval at the bandwidth
+	// limit so that the follower continues to hear from the leader.
+	chunkSize := int64(snapshotChunkSize)
+	if r.options.snapshotBandwidth > 0 {
+		heartbeatBytes := int64(float64(r.options.snapshotBandwidth) * r.options.heartbeatInterval.Seconds())
+		chunkSize = numeric.Max(numeric.Min(chunkSize, heartbeatBytes), 1)
 	}
 
 	// Read a chunk of the snapshot from the file.
 	var buf bytes.Buffer
-	n, err := io.Copy(&buf, follower.snapshot)
-	if err != nil {
+	n, err := io.CopyN(&buf, follower.snapshot, chunkSize)
+	if err != nil && err != io.EOF {
 		if err := follower.snapshot.Close(); err != nil {
 			r.logger.Errorf("failed to close snapshot file: error = %v", err)
 		}
 		r.logger.Fatalf("failed to read snapshot file: error = %v", err)
 	}
 	request.Bytes = buf.Bytes()
-	request.Done = n < snapshotChunkSize
+	request.Done = n < chunkSize
+
+	// Determine how long to wait before sending the chunk to stay within the bandwidth limit.
+	var delay time.Duration
+	if r.snapshotLimiter != nil {
+		delay = r.snapshotLimiter.Reserve(int(n))
+	}
+
+	ctx, cancel := r.leaderRPCContext(r.options.electionTimeout)
+	defer cancel()
 
+	follower.sendingSnapshot = true
 	r.mu.Unlock()
-	response, err := r.transport.SendInstallSnapshot(address, request)
+	response, err := r.sendThrottledInstallSnapshot(ctx, address, request, delay)
 	r.mu.Lock()
+	follower.sendingSnapshot = false
 
 	if follower.snapshot == nil || err != nil {
 		return
@@ -1641,25 +3582,259 @@ func (r *Raft) sendInstallSnapshot(id, address string) {
 		return
 	}
 
+	follower.lastContact = time.Now()
+	r.recordProtocolVersion(id, response.MinProtocolVersion, response.MaxProtocolVersion)
+
+	// The follower does not have the base of the snapshot and needs a full snapshot.
+	if response.MissingBase {
+		r.logger.Debugf("follower is missing base of snapshot: ID = %s, baseIndex = %d", id, metadata.BaseIndex)
+		if err := follower.snapshot.Close(); err != nil {
+			r.logger.Fatalf("failed to close snapshot file: error = %v", err)
+		}
+		follower.snapshot = nil
+		follower.needsFullSnapshot = true
+		return
+	}
+
 	// The follower is either missing part of the snapshot or already has this part.
 	// Reset to the follower's offset.
-	if response.BytesWritten != offset {
+	if response.BytesWritten != offset+n {
 		if _, err := follower.snapshot.Seek(response.BytesWritten, io.SeekStart); err != nil {
 			r.logger.Fatalf("failed to seek snapshot file: error = %v", err)
 		}
 		return
 	}
 
+	follower.snapshotBytesSent = response.BytesWritten
 	if !request.Done {
+		r.triggerReplication(id)
 		return
 	}
 
+	r.logger.Infof(
+		"sent snapshot to follower: ID = %s, lastIndex = %d, size = %d, duration = %v",
+		id,
+		metadata.LastIncludedIndex,
+		follower.snapshotSize,
+		time.Since(follower.snapshotStart),
+	)
+
 	if err := follower.snapshot.Close(); err != nil {
 		r.logger.Fatalf("failed to close snapshot file: error = %v", err)
 	}
 	follower.snapshot = nil
+	follower.needsFullSnapshot = false
+	follower.fetchFailed = false
 	follower.matchIndex = request.LastIncludedIndex
 	follower.nextIndex = request.LastIncludedIndex + 1
+
+	// Send any entries following the snapshot.
+	r.triggerReplication(id)
+}
+
+// sendThrottledInstallSnapshot waits for the provided delay and then sends the provided InstallSnapshot
+// request to the provided address. The request is not sent if the provided context is cancelled
+// during the delay.
+func (r *Raft) sendThrottledInstallSnapshot(
+	ctx context.Context,
+	address string,
+	request InstallSnapshotRequest,
+	delay time.Duration,
+) (InstallSnapshotResponse, error) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return InstallSnapshotResponse{}, ctx.Err()
+	case <-timer.C:
+		return r.transport.SendInstallSnapshot(ctx, address, request)
+	}
+}
+
+// snapshotSourceFor returns the ID of a follower that the follower with the provided ID may fetch
+// the most recent snapshot from. An empty string is returned if there is no such follower or peer
+// snapshot transfer is disabled. Only followers that are up-to-date and are not catching up
+// themselves are chosen, and followers serving the fewest other followers are preferred.
+func (r *Raft) snapshotSourceFor(id string) string {
+	if !r.options.peerSnapshotTransfer {
+		return ""
+	}
+
+	load := make(map[string]int)
+	for _, follower := range r.followers {
+		if follower.snapshotSource != "" {
+			load[follower.snapshotSource]++
+		}
+	}
+
+	var sourceID string
+	for peerID, peer := range r.followers {
+		if peerID == id || peerID == r.id || !r.isMember(peerID) {
+			continue
+		}
+		if peer.snapshot != nil || peer.snapshotSource != "" || peer.matchIndex < r.lastIncludedIndex {
+			continue
+		}
+		if !r.peerSupportsProtocolVersion(peerID, protocolVersionSnapshotTransfer) {
+			continue
+		}
+		if sourceID == "" || load[peerID] < load[sourceID] {
+			sourceID = peerID
+		}
+	}
+
+	if sourceID != "" {
+		r.logger.Infof("directing follower to fetch snapshot from peer: ID = %s, sourceID = %s", id, sourceID)
+	}
+
+	return sourceID
+}
+
+// directSnapshotFetch directs the follower with the provided ID and address to fetch the most
+// recent snapshot from the peer that it has been assigned. If the follower reports that it was
+// unable to fetch the snapshot, the leader will send the snapshot to it instead.
+func (r *Raft) directSnapshotFetch(id string, address string, follower *follower) {
+	sourceID := follower.snapshotSource
+	request := InstallSnapshotRequest{
+		LeaderID:           r.id,
+		Term:               r.currentTerm,
+		LastIncludedIndex:  r.lastIncludedIndex,
+		LastIncludedTerm:   r.lastIncludedTerm,
+		SourceID:           sourceID,
+		SourceAddress:      r.configuration.Members[sourceID],
+		MinProtocolVersion: MinProtocolVersion,
+		MaxProtocolVersion: r.options.maxProtocolVersion,
+	}
+
+	ctx, cancel := r.leaderRPCContext(r.options.electionTimeout)
+	defer cancel()
+
+	r.mu.Unlock()
+	response, err := r.transport.SendInstallSnapshot(ctx, address, request)
+	r.mu.Lock()
+
+	if err != nil || r.state != Leader {
+		return
+	}
+
+	// If the follower has a more up-to-date term, transition to the follower state.
+	if response.Term > r.currentTerm {
+		r.becomeFollower(id, response.Term)
+		return
+	}
+
+	follower.lastContact = time.Now()
+	r.recordProtocolVersion(id, response.MinProtocolVersion, response.MaxProtocolVersion)
+
+	if response.FetchFailed && follower.snapshotSource == sourceID {
+		r.logger.Warnf("follower failed to fetch snapshot from peer: ID = %s, sourceID = %s", id, sourceID)
+		follower.snapshotSource = ""
+		follower.fetchFailed = true
+		return
+	}
+
+	// The follower has fetched the snapshot, so it can be sent the entries that follow it.
+	if response.LastIncludedIndex >= request.LastIncludedIndex {
+		follower.snapshotSource = ""
+		follower.fetchFailed = false
+		follower.matchIndex = numeric.Max(follower.matchIndex, response.LastIncludedIndex)
+		follower.nextIndex = follower.matchIndex + 1
+		if follower.matchIndex > r.commitIndex {
+			r.commitCond.Broadcast()
+		}
+		r.triggerReplication(id)
+	}
+}
+
+// canTakeIncrementalSnapshot returns true if the next snapshot may be an incremental snapshot
+// of the most recent snapshot and false otherwise. This requires that the state machine and
+// the snapshot storage support incremental snapshots and that the most recent snapshot is not
+// already at the end of a chain of the maximum number of incremental snapshots.
+func (r *Raft) canTakeIncrementalSnapshot() bool {
+	if _, ok := r.fsm.(IncrementalStateMachine); !ok || r.options.maxSnapshotDeltas == 0 {
+		return false
+	}
+	storage, ok := r.snapshotStorage.(IncrementalSnapshotStorage)
+	if !ok || r.lastIncludedIndex == 0 {
+		return false
+	}
+	chain, err := storage.SnapshotChain(r.lastIncludedIndex)
+	if err != nil {
+		r.logger.Fatalf("failed to get snapshot chain: error = %v", err)
+	}
+	if err := closeSnapshotFiles(chain); err != nil {
+		r.logger.Fatalf("failed to close snapshot file: error = %v", err)
+	}
+	return len(chain) != 0 && len(chain)-1 < r.options.maxSnapshotDeltas
+}
+
+// hasSnapshotChain returns true if the snapshot with the provided last included index and
+// every snapshot that it is based on are present and false otherwise.
+func (r *Raft) hasSnapshotChain(lastIncludedIndex uint64) bool {
+	if _, ok := r.fsm.(IncrementalStateMachine); !ok {
+		return false
+	}
+	storage, ok := r.snapshotStorage.(IncrementalSnapshotStorage)
+	if !ok {
+		return false
+	}
+	chain, err := storage.SnapshotChain(lastIncludedIndex)
+	if err != nil {
+		r.logger.Fatalf("failed to get snapshot chain: error = %v", err)
+	}
+	if err := closeSnapshotFiles(chain); err != nil {
+		r.logger.Fatalf("failed to close snapshot file: error = %v", err)
+	}
+	return len(chain) != 0
+}
+
+// restoreStateMachine restores the state machine using the provided snapshot file. If the
+// snapshot is incremental, the state machine is restored using the full snapshot it is based
+// on and then each incremental snapshot in order. The provided file is closed.
+func (r *Raft) restoreStateMachine(file SnapshotFile) error {
+	metadata := file.Metadata()
+	if metadata.BaseIndex == 0 {
+		if err := r.fsm.Restore(file); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("could not close snapshot file: %w", err)
+		}
+		return nil
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not close snapshot file: %w", err)
+	}
+	fsm, ok := r.fsm.(IncrementalStateMachine)
+	if !ok {
+		return errors.New("state machine does not support incremental snapshots")
+	}
+	storage, ok := r.snapshotStorage.(IncrementalSnapshotStorage)
+	if !ok {
+		return errors.New("snapshot storage does not support incremental snapshots")
+	}
+	chain, err := storage.SnapshotChain(metadata.LastIncludedIndex)
+	if err != nil {
+		return fmt.Errorf("could not get snapshot chain: %w", err)
+	}
+	if len(chain) == 0 {
+		return fmt.Errorf("snapshot chain is incomplete: lastIncludedIndex = %d", metadata.LastIncludedIndex)
+	}
+	defer closeSnapshotFiles(chain)
+
+	if err := fsm.Restore(chain[0]); err != nil {
+		return err
+	}
+	for _, delta := range chain[1:] {
+		if err := fsm.RestoreDelta(delta); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // heartbeatLoop is a long running loop that periodically sends heartbeats to
@@ -1680,6 +3855,7 @@ func (r *Raft) heartbeatLoop() {
 			r.mu.Unlock()
 			continue
 		}
+		r.checkQuorum()
 		r.sendAppendEntriesToPeers()
 		r.mu.Unlock()
 	}
@@ -1715,14 +3891,10 @@ func (r *Raft) commitLoop() {
 
 			// Check whether the majority of nodes in the cluster agree on the entry.
 			// If they do, it is safe to commit.
-			matches := 1
+			matches := map[string]bool{r.id: true}
 			for id, follower := range r.followers {
-				// Ignore this node and any nodes which are not voting members.
-				if id == r.id || !r.configuration.IsVoter[id] {
-					continue
-				}
 				if follower.matchIndex >= index {
-					matches++
+					matches[id] = true
 				}
 			}
 
@@ -1759,8 +3931,12 @@ func (r *Raft) applyLoop() {
 			switch entry.EntryType {
 			case NoOpEntry:
 			case ConfigurationEntry:
-				r.applyConfiguration(entry.Data)
-				respond(r.configurationResponseCh, *r.configuration, nil)
+				// The membership change is complete once the cluster has left the joint configuration.
+				if configuration := r.applyConfiguration(entry.Data); configuration.IsJoint() {
+					r.leaveJointConfiguration()
+				} else {
+					respond(r.configurationResponseCh, *r.configuration, nil)
+				}
 			case OperationEntry:
 				responseCh := r.operationManager.pendingReplicated[entry.Index]
 				delete(r.operationManager.pendingReplicated, entry.Index)
@@ -1808,14 +3984,19 @@ func (r *Raft) applyLoop() {
 	}
 }
 
-// applyConfigurationEntry applies a log entry containing a configuration to this node.
-func (r *Raft) applyConfiguration(configurationData []byte) {
+// applyConfigurationEntry applies a log entry containing a configuration to this node
+// and returns the configuration.
+func (r *Raft) applyConfiguration(configurationData []byte) Configuration {
 	configuration := r.decodeConfiguration(configurationData)
 	if r.committedConfiguration != nil && configuration.Index <= r.committedConfiguration.Index {
-		return
+		return configuration
+	}
+	// A more recent configuration may have already been appended to the log.
+	if configuration.Index >= r.configuration.Index {
+		r.nextConfiguration(&configuration)
 	}
-	r.nextConfiguration(&configuration)
 	r.committedConfiguration = &configuration
+	return configuration
 }
 
 // readOnlyLoop is a long running loop that applies read-only operations to the state machine.
@@ -1880,19 +4061,30 @@ func (r *Raft) becomePreCandidate() {
 
 // becomeLeader transitions this node to the leader state.
 func (r *Raft) becomeLeader() {
+	r.cancelLeaderRPCs()
 	r.state = Leader
+	r.leaderCtx, r.leaderCancel = context.WithCancel(r.ctx)
 	r.operationManager = newOperationManager(r.options.leaseDuration)
+	r.round = 0
+	r.verifiedRound = 0
 	for _, follower := range r.followers {
 		follower.nextIndex = r.log.LastIndex() + 1
 		follower.matchIndex = 0
+		follower.ackedRound = 0
+		follower.lastContact = time.Now()
 	}
 	r.resetSnapshotFiles()
+	r.updateProtocolVersion()
 
 	// Append a new log entry for this term.
 	entry := NewLogEntry(r.log.NextIndex(), r.currentTerm, []byte{}, NoOpEntry)
 	if err := r.log.AppendEntry(entry); err != nil {
 		r.logger.Fatal("failed to append entry to log: error = %v", err)
 	}
+
+	// The previous leader may have lost leadership before leaving a committed joint configuration.
+	r.leaveJointConfiguration()
+
 	r.sendAppendEntriesToPeers()
 
 	r.logger.Infof("entered the leader state: term = %d", r.currentTerm)
@@ -1900,15 +4092,19 @@ func (r *Raft) becomeLeader() {
 
 // becomeFollower transitions this node to the follower state.
 func (r *Raft) becomeFollower(leaderID string, term uint64) {
+	r.cancelLeaderRPCs()
+	r.stopReplicators()
 	r.state = Follower
 	r.currentTerm = term
 	r.leaderID = leaderID
 	r.votedFor = ""
 	r.persistTermAndVote()
 	r.resetSnapshotFiles()
+	r.completeLeadershipTransfer()
+	r.abortCatchUps(r.notLeaderError())
 
 	// Cancel any pending operations.
-	r.operationManager.notifyLostLeaderShip(r.id, r.leaderID)
+	r.operationManager.notifyLostLeaderShip(r.notLeaderError())
 	r.operationManager = newOperationManager(r.options.leaseDuration)
 
 	r.logger.Infof("entered the follower state: term = %d", r.currentTerm)
@@ -1918,20 +4114,51 @@ func (r *Raft) becomeFollower(leaderID string, term uint64) {
 // has been removed from the cluster. Unlike becomeFollower, stepDown does not persist
 // the current term and vote.
 func (r *Raft) stepdown() {
+	r.cancelLeaderRPCs()
+	r.stopReplicators()
 	r.state = Follower
+	r.abortLeadershipTransfer(r.notLeaderError())
+	r.abortCatchUps(r.notLeaderError())
 
 	// Cancel any pending operations.
-	r.operationManager.notifyLostLeaderShip(r.id, r.leaderID)
+	r.operationManager.notifyLostLeaderShip(r.notLeaderError())
 	r.operationManager = newOperationManager(r.options.leaseDuration)
 
 	r.logger.Info("stepped down to the follower state")
 }
 
+// leaderRPCContext returns a context for an RPC sent as the leader. The context is cancelled
+// if this node loses leadership or the RPC does not complete within the provided timeout.
+func (r *Raft) leaderRPCContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.leaderCtx, timeout)
+}
+
+// appendEntriesTimeout returns the deadline for an AppendEntries RPC. It is derived from the
+// heartbeat interval so that a response that is lost does not hold up the follower's next
+// heartbeat for long, but it never exceeds the election timeout, at which point the follower
+// would have started an election without a response anyway.
+func (r *Raft) appendEntriesTimeout() time.Duration {
+	return numeric.Min(
+		appendEntriesTimeoutHeartbeats*r.options.heartbeatInterval,
+		r.options.electionTimeout,
+	)
+}
+
+// cancelLeaderRPCs cancels any in-flight RPCs sent by this node as the leader.
+func (r *Raft) cancelLeaderRPCs() {
+	if r.leaderCancel != nil {
+		r.leaderCancel()
+		r.leaderCancel = nil
+	}
+}
+
 // tryApplyReadOnlyOperations renews the lease and notifies the read-only
 // loop that it may be possible to apply some read-only operations.
 func (r *Raft) tryApplyReadOnlyOperations() {
 	r.operationManager.markAsVerified()
-	r.operationManager.leaderLease.renew()
+	if r.leadershipTransfer == nil || !r.leadershipTransfer.timeoutNowSent {
+		r.operationManager.leaderLease.renew()
+	}
 	r.operationManager.shouldVerifyQuorum = true
 	r.readOnlyCond.Broadcast()
 }
@@ -1955,14 +4182,57 @@ func (r *Raft) resetSnapshotFiles() {
 	}
 }
 
-// hasQuorum returns true if the provided count makes constitutes
+// checkQuorum steps down if this node is the leader and has not heard from a quorum of the
+// cluster within the election timeout. Otherwise, a leader that is partitioned from the rest of
+// the cluster would continue to accept operations that cannot be committed. Expects the mutex
+// to be locked.
+func (r *Raft) checkQuorum() {
+	if r.state != Leader {
+		return
+	}
+
+	contacted := map[string]bool{r.id: true}
+	for id, follower := range r.followers {
+		if time.Since(follower.lastContact) < r.options.electionTimeout {
+			contacted[id] = true
+		}
+	}
+	if r.hasQuorum(contacted) {
+		return
+	}
+
+	r.logger.Warnf("lost contact with a quorum of the cluster: term = %d", r.currentTerm)
+
+	// The leader is no longer known, so pending operations fail with an error that does not
+	// direct them back to this node.
+	r.leaderID = ""
+	r.stepdown()
+}
+
+// hasQuorum returns true if the provided set of nodes constitutes
 // quorum for the cluster and false otherwise. Non-voting members
-// of the cluster are not considered for quorum.
-func (r *Raft) hasQuorum(count int) bool {
+// of the cluster are not considered for quorum. If the configuration
+// is joint, the set must contain a majority of the voting members of
+// both the old and the new configuration.
+func (r *Raft) hasQuorum(nodes map[string]bool) bool {
+	if !hasMajority(r.configuration.IsVoter, nodes) {
+		return false
+	}
+	return !r.configuration.IsJoint() || hasMajority(r.configuration.OldIsVoter, nodes)
+}
+
+// hasMajority returns true if the provided set of nodes contains a majority
+// of the voting members in the provided voting statuses and false otherwise.
+func hasMajority(isVoter map[string]bool, nodes map[string]bool) bool {
 	voters := 0
-	for _, isVoter := range r.configuration.IsVoter {
-		if isVoter {
-			voters++
+	count := 0
+	for id, voter := range isVoter {
+		if !voter {
+			continue
+		}
+		voters++
+		if nodes[id] {
+			count++
 		}
 	}
 	return count > voters/2
@@ -2013,21 +4283,61 @@ func (r *Raft) nextConfiguration(next *Configuration) {
 		r.resetSnapshotFiles()
 	}
 
-	// Delete removed nodes from followers.
-	for id := range r.configuration.Members {
-		if _, ok := next.Members[id]; !ok {
-			delete(r.followers, id)
+	// Delete removed nodes from followers. The leader may have already switched to
+	// the next configuration when it was appended, so the committed configuration
+	// is also checked for removed nodes.
+	addresses := make(map[string]bool, len(next.Members))
+	for _, address := range next.Members {
+		addresses[address] = true
+	}
+	previous := []*Configuration{r.configuration, r.committedConfiguration}
+	for _, configuration := range previous {
+		if configuration == nil {
+			continue
+		}
+		for id, address := range configuration.Members {
+			if _, ok := next.Members[id]; !ok {
+				delete(r.followers, id)
+			}
+			// Release the connection to removed nodes unless the address is still in use.
+			if id != r.id && !addresses[address] {
+				r.transport.RemovePeer(address)
+			}
 		}
 	}
 
 	// Create entry for added nodes.
+	var members map[string]string
+	if r.configuration != nil {
+		members = r.configuration.Members
+	}
 	for id := range next.Members {
-		if _, ok := r.configuration.Members[id]; !ok {
+		if _, ok := members[id]; !ok {
 			r.followers[id] = new(follower)
 		}
 	}
 }
 
+// latestConfiguration returns the most recent configuration in the log, or the committed
+// configuration if the log does not contain a more recent one. Nil is returned if there is
+// neither. Expects the mutex to be locked.
+func (r *Raft) latestConfiguration() *Configuration {
+	for index := r.log.LastIndex(); index > r.lastIncludedIndex; index-- {
+		if r.committedConfiguration != nil && index <= r.committedConfiguration.Index {
+			break
+		}
+		entry, err := r.log.GetEntry(index)
+		if err != nil {
+			r.logger.Fatalf("failed to get entry from log: error = %v", err)
+		}
+		if entry.EntryType == ConfigurationEntry {
+			configuration := r.decodeConfiguration(entry.Data)
+			return &configuration
+		}
+	}
+	return r.committedConfiguration
+}
+
 // encodeConfiguration encodes the provided configuration.
 func (r *Raft) encodeConfiguration(configuration *Configuration) []byte {
 	data, err := r.transport.EncodeConfiguration(configuration)
@@ -2059,8 +4369,10 @@ func (r *Raft) appendConfiguration(configuration *Configuration) {
 
 // isVoter returns true if the node with the provided ID
 // is a voting member of the cluster and false otherwise.
+// In a joint configuration, this includes the voting members
+// of the old configuration.
 func (r *Raft) isVoter(id string) bool {
-	return r.configuration.IsVoter[id]
+	return r.configuration.IsVoter[id] || r.configuration.OldIsVoter[id]
 }
 
 // isMember returns true if the node with the provided ID
@@ -2076,6 +4388,28 @@ func (r *Raft) isSingleServerCluster() bool {
 	return len(r.configuration.Members) == 1 && r.configuration.IsVoter[r.id]
 }
 
+// checkMembershipChangeAllowed returns an error if a membership change may not be made at this
+// time. Only the leader may make membership changes, and only once a log entry for its term is
+// committed, there is no other membership change pending, and leadership is not being transferred.
+// The change must also not require a protocol version newer than the one used by the cluster.
+// Non-voters that are being caught up do not prevent membership changes; their promotion waits
+// until no other membership change is pending. Expects the mutex to be locked.
+func (r *Raft) checkMembershipChangeAllowed(minVersion uint32) error {
+	if r.state != Leader {
+		return r.notLeaderError()
+	}
+	if r.leadershipTransfer != nil {
+		return ErrLeadershipTransferInProgress
+	}
+	if !r.committedThisTerm() {
+		return ErrNoCommitThisTerm
+	}
+	if r.pendingConfigurationChange() {
+		return ErrPendingConfiguration
+	}
+	return r.requireProtocolVersion(minVersion, "membership change")
+}
+
 // pendingConfigurationChange returns true if the current configuration
 // has not been committed.
 func (r *Raft) pendingConfigurationChange() bool {
@@ -2,6 +2,9 @@ package raft
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -56,7 +59,7 @@ func TestAppendEntriesSuccess(t *testing.T) {
 	}
 	response := &AppendEntriesResponse{}
 
-	require.NoError(t, raft.AppendEntries(request, response))
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
 	require.True(t, response.Success)
 	require.Equal(t, request.Term, response.Term)
 	require.Equal(t, request.LeaderCommit, raft.commitIndex)
@@ -80,6 +83,7 @@ func TestAppendEntriesConflictSuccess(t *testing.T) {
 	raft.currentTerm = 2
 	raft.votedFor = "2"
 	raft.state = Follower
+	raft.followers = make(map[string]*follower)
 	raft.committedConfiguration = &Configuration{
 		Members: map[string]string{"1": "127.0.0.0:8080", "2": "127.0.0.1:8080"},
 		IsVoter: map[string]bool{"1": true, "2": true},
@@ -95,28 +99,31 @@ func TestAppendEntriesConflictSuccess(t *testing.T) {
 		Index:   2,
 	}
 
+	configuration1 := raft.encodeConfiguration(raft.committedConfiguration)
+	configuration2 := raft.encodeConfiguration(raft.configuration)
+
 	request := &AppendEntriesRequest{
 		LeaderID: "2",
 		Term:     2,
 		Entries: []*LogEntry{
-			NewLogEntry(1, 1, []byte("configuration1"), ConfigurationEntry),
-			NewLogEntry(2, 1, []byte("configuration2"), ConfigurationEntry),
+			NewLogEntry(1, 1, configuration1, ConfigurationEntry),
+			NewLogEntry(2, 1, configuration2, ConfigurationEntry),
 			NewLogEntry(3, 1, []byte("operation1"), OperationEntry),
 		},
 	}
 	response := &AppendEntriesResponse{}
 
-	require.NoError(t, raft.AppendEntries(request, response))
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
 	require.True(t, response.Success)
 	require.Equal(t, request.Term, response.Term)
 
 	request.Entries = []*LogEntry{
-		NewLogEntry(1, 1, []byte("configuration1"), ConfigurationEntry),
+		NewLogEntry(1, 1, configuration1, ConfigurationEntry),
 		NewLogEntry(2, 2, []byte("operation1"), OperationEntry),
 	}
 	response = &AppendEntriesResponse{}
 
-	require.NoError(t, raft.AppendEntries(request, response))
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
 	require.True(t, response.Success)
 	require.Equal(t, request.Term, response.Term)
 
@@ -133,6 +140,117 @@ func TestAppendEntriesConflictSuccess(t *testing.T) {
 	require.Equal(t, *raft.committedConfiguration, *raft.configuration)
 }
 
+// TestAppendEntriesConflictUncommittedConfiguration checks that a node whose configuration is
+// truncated falls back to the most recent configuration remaining in its log rather than its
+// committed configuration.
+func TestAppendEntriesConflictUncommittedConfiguration(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.currentTerm = 2
+	raft.votedFor = "2"
+	raft.state = Follower
+	raft.followers = make(map[string]*follower)
+	raft.committedConfiguration = &Configuration{
+		Members: map[string]string{"1": "127.0.0.0:8080", "2": "127.0.0.1:8080"},
+		IsVoter: map[string]bool{"1": true, "2": true},
+		Index:   1,
+	}
+	raft.configuration = raft.committedConfiguration
+	remaining := &Configuration{
+		Members: map[string]string{
+			"1": "127.0.0.0:8080",
+			"2": "127.0.0.1:8080",
+			"3": "127.0.0.2:8080",
+		},
+		IsVoter: map[string]bool{"1": true, "2": true, "3": false},
+		Index:   2,
+	}
+	truncated := &Configuration{
+		Members: map[string]string{
+			"1": "127.0.0.0:8080",
+			"2": "127.0.0.1:8080",
+			"3": "127.0.0.2:8080",
+		},
+		IsVoter: map[string]bool{"1": true, "2": true, "3": true},
+		Index:   3,
+	}
+
+	request := &AppendEntriesRequest{
+		LeaderID: "2",
+		Term:     2,
+		Entries: []*LogEntry{
+			NewLogEntry(1, 1, raft.encodeConfiguration(raft.committedConfiguration), ConfigurationEntry),
+			NewLogEntry(2, 1, raft.encodeConfiguration(remaining), ConfigurationEntry),
+			NewLogEntry(3, 1, raft.encodeConfiguration(truncated), ConfigurationEntry),
+		},
+	}
+	response := &AppendEntriesResponse{}
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
+	require.True(t, response.Success)
+	require.Equal(t, *truncated, *raft.configuration)
+
+	// The entry containing the newest configuration conflicts with the leader's log, but the
+	// one before it does not.
+	request = &AppendEntriesRequest{
+		LeaderID:     "2",
+		Term:         2,
+		PrevLogIndex: 2,
+		PrevLogTerm:  1,
+		Entries:      []*LogEntry{NewLogEntry(3, 2, []byte("operation1"), OperationEntry)},
+	}
+	response = &AppendEntriesResponse{}
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
+	require.True(t, response.Success)
+	require.Equal(t, *remaining, *raft.configuration)
+}
+
+// TestAppendEntriesConflictNoCommittedConfiguration checks that a node that has never committed
+// a configuration clears its configuration if the only configuration in its log is truncated.
+func TestAppendEntriesConflictNoCommittedConfiguration(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.currentTerm = 2
+	raft.votedFor = "2"
+	raft.state = Follower
+	raft.followers = make(map[string]*follower)
+	configuration := &Configuration{
+		Members: map[string]string{"1": "127.0.0.0:8080", "2": "127.0.0.1:8080"},
+		IsVoter: map[string]bool{"1": true, "2": true},
+		Index:   1,
+	}
+
+	request := &AppendEntriesRequest{
+		LeaderID: "2",
+		Term:     2,
+		Entries: []*LogEntry{
+			NewLogEntry(1, 1, raft.encodeConfiguration(configuration), ConfigurationEntry),
+		},
+	}
+	response := &AppendEntriesResponse{}
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
+	require.True(t, response.Success)
+	require.Nil(t, raft.committedConfiguration)
+	require.Equal(t, *configuration, *raft.configuration)
+
+	request = &AppendEntriesRequest{
+		LeaderID: "2",
+		Term:     3,
+		Entries:  []*LogEntry{NewLogEntry(1, 3, []byte("operation1"), OperationEntry)},
+	}
+	response = &AppendEntriesResponse{}
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
+	require.True(t, response.Success)
+	require.Nil(t, raft.configuration)
+}
+
 // TestAppendEntriesLeaderStepDownSuccess checks that a raft instance in the leader
 // state correctly steps down to the follower state when it receives an AppendEntries
 // request with a greater term than its own.
@@ -153,7 +271,7 @@ func TestAppendEntriesLeaderStepDownSuccess(t *testing.T) {
 	}
 	response := &AppendEntriesResponse{}
 
-	require.NoError(t, raft.AppendEntries(request, response))
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
 	require.True(t, response.Success)
 	require.Equal(t, request.Term, response.Term)
 
@@ -162,6 +280,43 @@ func TestAppendEntriesLeaderStepDownSuccess(t *testing.T) {
 	require.Empty(t, raft.votedFor)
 }
 
+// TestAppendEntriesLeaderStepDownStopsTransfer checks that a leader that steps down
+// while transferring leadership stops the timer for the transfer so that it does not
+// fire once the transfer has ended.
+func TestAppendEntriesLeaderStepDownStopsTransfer(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+
+	raft.currentTerm = 1
+	raft.state = Leader
+	raft.votedFor = "1"
+
+	transfer := &leadershipTransfer{
+		targetID:   "2",
+		term:       raft.currentTerm,
+		responseCh: make(chan Result[LeadershipTransferResponse], 1),
+	}
+	transfer.timer = time.AfterFunc(time.Hour, func() {})
+	raft.leadershipTransfer = transfer
+
+	request := &AppendEntriesRequest{
+		LeaderID: "3",
+		Term:     3,
+		Entries:  []*LogEntry{},
+	}
+	response := &AppendEntriesResponse{}
+
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
+	require.Equal(t, Follower, raft.state)
+	require.Nil(t, raft.leadershipTransfer)
+	require.False(t, transfer.timer.Stop())
+
+	result := <-transfer.responseCh
+	require.ErrorIs(t, result.Error(), ErrNotLeader)
+}
+
 // TestAppendEntriesOutOfDateTermFailure checks that an AppendEntries request
 // received that has a term less than the term of server that received it is rejected.
 func TestAppendEntriesOutOfDateTermFailure(t *testing.T) {
@@ -180,7 +335,7 @@ func TestAppendEntriesOutOfDateTermFailure(t *testing.T) {
 	}
 	response := &AppendEntriesResponse{}
 
-	require.NoError(t, raft.AppendEntries(request, response))
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
 	require.False(t, response.Success)
 	require.Equal(t, response.Term, raft.currentTerm)
 }
@@ -211,7 +366,7 @@ func TestAppendEntriesPrevLogIndexFailure(t *testing.T) {
 	}
 	response := &AppendEntriesResponse{}
 
-	require.NoError(t, raft.AppendEntries(request, response))
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
 	require.False(t, response.Success)
 	require.Equal(t, request.Term, response.Term)
 }
@@ -231,7 +386,7 @@ func TestAppendEntriesShutdownFailure(t *testing.T) {
 	}
 	response := &AppendEntriesResponse{}
 
-	require.Error(t, raft.AppendEntries(request, response))
+	require.Error(t, raft.AppendEntries(context.Background(), request, response))
 	require.False(t, response.Success)
 }
 
@@ -253,7 +408,7 @@ func TestRequestVoteSuccess(t *testing.T) {
 	}
 	response := &RequestVoteResponse{}
 
-	require.NoError(t, raft.RequestVote(request, response))
+	require.NoError(t, raft.RequestVote(context.Background(), request, response))
 	require.True(t, response.VoteGranted)
 	require.Equal(t, request.Term, response.Term)
 
@@ -280,7 +435,7 @@ func TestPrevoteNoVote(t *testing.T) {
 	}
 	response := &RequestVoteResponse{}
 
-	require.NoError(t, raft.RequestVote(request, response))
+	require.NoError(t, raft.RequestVote(context.Background(), request, response))
 	require.True(t, response.VoteGranted)
 	require.Equal(t, raft.currentTerm, response.Term)
 	require.Equal(t, "2", raft.votedFor)
@@ -306,7 +461,7 @@ func TestRequestVoteLeaderStepDownSuccess(t *testing.T) {
 	}
 	response := &RequestVoteResponse{}
 
-	require.NoError(t, raft.RequestVote(request, response))
+	require.NoError(t, raft.RequestVote(context.Background(), request, response))
 	require.True(t, response.VoteGranted)
 	require.Equal(t, request.Term, response.Term)
 
@@ -334,7 +489,7 @@ func TestRequestVoteAlreadyVotedSuccess(t *testing.T) {
 	}
 	response := &RequestVoteResponse{}
 
-	require.NoError(t, raft.RequestVote(request, response))
+	require.NoError(t, raft.RequestVote(context.Background(), request, response))
 	require.True(t, response.VoteGranted)
 }
 
@@ -358,7 +513,7 @@ func TestRequestVoteAlreadyVotedFailure(t *testing.T) {
 	}
 	response := &RequestVoteResponse{}
 
-	require.NoError(t, raft.RequestVote(request, response))
+	require.NoError(t, raft.RequestVote(context.Background(), request, response))
 	require.False(t, response.VoteGranted)
 	require.Equal(t, "2", raft.votedFor)
 }
@@ -384,11 +539,39 @@ func TestRequestVoteLeaderContactFailuire(t *testing.T) {
 	}
 	response := &RequestVoteResponse{}
 
-	require.NoError(t, raft.RequestVote(request, response))
+	require.NoError(t, raft.RequestVote(context.Background(), request, response))
 	require.False(t, response.VoteGranted)
 	require.Equal(t, "2", raft.votedFor)
 }
 
+// TestRequestVoteLeadershipTransferSuccess checks that a node that receives a
+// RequestVote request for an election started at the request of the leader grants
+// its vote even if it has recently been contacted by the leader.
+func TestRequestVoteLeadershipTransferSuccess(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+
+	raft.currentTerm = 1
+	raft.votedFor = "2"
+	raft.state = Follower
+	raft.lastContact = time.Now()
+
+	request := &RequestVoteRequest{
+		CandidateID:        "3",
+		Term:               2,
+		Prevote:            false,
+		LeadershipTransfer: true,
+	}
+	response := &RequestVoteResponse{}
+
+	require.NoError(t, raft.RequestVote(context.Background(), request, response))
+	require.True(t, response.VoteGranted)
+	require.Equal(t, "3", raft.votedFor)
+	require.Equal(t, request.Term, raft.currentTerm)
+}
+
 // TestRequestVoteOutOfDateTermFailure checks that a RequestVote request
 // received that has a term less than the term of server that received
 // it is rejected.
@@ -409,7 +592,7 @@ func TestRequestVoteOutOfDateTermFailure(t *testing.T) {
 	}
 	response := &RequestVoteResponse{}
 
-	require.NoError(t, raft.RequestVote(request, response))
+	require.NoError(t, raft.RequestVote(context.Background(), request, response))
 	require.False(t, response.VoteGranted)
 	require.Equal(t, "2", raft.votedFor)
 }
@@ -441,7 +624,7 @@ func TestRequestVoteOutOfDateLogFailure(t *testing.T) {
 	}
 	response := &RequestVoteResponse{}
 
-	require.NoError(t, raft.RequestVote(request, response))
+	require.NoError(t, raft.RequestVote(context.Background(), request, response))
 	require.False(t, response.VoteGranted)
 	require.Equal(t, "2", raft.votedFor)
 }
@@ -461,7 +644,7 @@ func TestRequestVoteShutdownFailure(t *testing.T) {
 	}
 	response := &RequestVoteResponse{}
 
-	require.Error(t, raft.RequestVote(request, response))
+	require.Error(t, raft.RequestVote(context.Background(), request, response))
 	require.False(t, response.VoteGranted)
 }
 
@@ -513,7 +696,7 @@ func TestInstallSnapshotSuccess(t *testing.T) {
 	}
 	response := &InstallSnapshotResponse{}
 
-	require.NoError(t, raft.InstallSnapshot(request1, response))
+	require.NoError(t, raft.InstallSnapshot(context.Background(), request1, response))
 	require.Equal(t, request1.Term, response.Term)
 	require.Zero(t, raft.commitIndex)
 	require.Zero(t, raft.lastApplied)
@@ -532,7 +715,7 @@ func TestInstallSnapshotSuccess(t *testing.T) {
 		Done:              true,
 	}
 
-	require.NoError(t, raft.InstallSnapshot(request2, response))
+	require.NoError(t, raft.InstallSnapshot(context.Background(), request2, response))
 	require.Equal(t, request2.Term, response.Term)
 	require.Equal(t, request2.LastIncludedIndex, raft.commitIndex)
 	require.Equal(t, request2.LastIncludedIndex, raft.lastApplied)
@@ -595,7 +778,7 @@ func TestInstallSnapshotLeaderStepDownSuccess(t *testing.T) {
 	}
 	response := &InstallSnapshotResponse{}
 
-	require.NoError(t, raft.InstallSnapshot(request, response))
+	require.NoError(t, raft.InstallSnapshot(context.Background(), request, response))
 	require.Equal(t, request.Term, response.Term)
 	require.Equal(t, Follower, raft.state)
 	require.Equal(t, request.Term, raft.currentTerm)
@@ -627,10 +810,727 @@ func TestInstallSnapshotOutOfDateTermFailure(t *testing.T) {
 	}
 	response := &InstallSnapshotResponse{}
 
-	require.NoError(t, raft.InstallSnapshot(request, response))
+	require.NoError(t, raft.InstallSnapshot(context.Background(), request, response))
 	require.Equal(t, raft.currentTerm, response.Term)
 	require.Zero(t, raft.commitIndex)
 	require.Zero(t, raft.lastApplied)
 	require.Zero(t, raft.lastIncludedIndex)
 	require.Zero(t, raft.lastIncludedTerm)
 }
+
+// TestInstallSnapshotIncrementalSuccess checks that an incremental snapshot is installed
+// when the node has the base of the snapshot and that the state machine is restored
+// using the base and the incremental snapshot.
+func TestInstallSnapshotIncrementalSuccess(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fsm := &incrementalStateMachineMock{newStateMachineMock(false, 0)}
+	raft, err := makeRaftWithStateMachine("1", "127.0.0.0:8080", tmpDir, fsm)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.currentTerm = 1
+	raft.followers = make(map[string]*follower)
+	raft.state = Follower
+
+	configuration := Configuration{
+		Members: map[string]string{"1": "127.0.0.0:8080", "2": "127.0.0.1:8080"},
+		IsVoter: map[string]bool{"1": true, "2": true},
+		Index:   1,
+	}
+	raft.configuration = &configuration
+	configurationData, err := raft.transport.EncodeConfiguration(&configuration)
+	require.NoError(t, err)
+
+	// Store the base of the incremental snapshot.
+	baseOperations := []Operation{{Bytes: []byte("operation1"), LogIndex: 2, LogTerm: 1}}
+	baseData, err := encodeOperations(baseOperations)
+	require.NoError(t, err)
+	base, err := raft.snapshotStorage.NewSnapshotFile(2, 1, configurationData)
+	require.NoError(t, err)
+	_, err = base.Write(baseData)
+	require.NoError(t, err)
+	require.NoError(t, base.Close())
+
+	deltaOperations := []Operation{
+		{Bytes: []byte("operation2"), LogIndex: 3, LogTerm: 1},
+		{Bytes: []byte("operation3"), LogIndex: 4, LogTerm: 1},
+	}
+	deltaData, err := encodeOperations(deltaOperations)
+	require.NoError(t, err)
+
+	request := &InstallSnapshotRequest{
+		LeaderID:          "2",
+		Term:              1,
+		LastIncludedIndex: 4,
+		LastIncludedTerm:  1,
+		Bytes:             deltaData,
+		Configuration:     configurationData,
+		Offset:            0,
+		Done:              true,
+		BaseIndex:         2,
+	}
+	response := &InstallSnapshotResponse{}
+
+	require.NoError(t, raft.InstallSnapshot(context.Background(), request, response))
+	require.False(t, response.MissingBase)
+	require.Equal(t, request.LastIncludedIndex, raft.commitIndex)
+	require.Equal(t, request.LastIncludedIndex, raft.lastApplied)
+	require.Equal(t, request.LastIncludedIndex, raft.lastIncludedIndex)
+	require.Equal(t, request.LastIncludedTerm, raft.lastIncludedTerm)
+	require.Equal(t, append(baseOperations, deltaOperations...), fsm.appliedOperations())
+}
+
+// TestInstallSnapshotMissingBaseFailure checks that an incremental snapshot is rejected
+// when the node does not have the base of the snapshot.
+func TestInstallSnapshotMissingBaseFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fsm := &incrementalStateMachineMock{newStateMachineMock(false, 0)}
+	raft, err := makeRaftWithStateMachine("1", "127.0.0.0:8080", tmpDir, fsm)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.currentTerm = 1
+	raft.state = Follower
+
+	request := &InstallSnapshotRequest{
+		LeaderID:          "2",
+		Term:              1,
+		LastIncludedIndex: 4,
+		LastIncludedTerm:  1,
+		Bytes:             []byte("delta"),
+		Configuration:     []byte{},
+		Offset:            0,
+		Done:              true,
+		BaseIndex:         2,
+	}
+	response := &InstallSnapshotResponse{}
+
+	require.NoError(t, raft.InstallSnapshot(context.Background(), request, response))
+	require.True(t, response.MissingBase)
+	require.Zero(t, response.BytesWritten)
+	require.Nil(t, raft.snapshot)
+	require.Zero(t, raft.commitIndex)
+	require.Zero(t, raft.lastApplied)
+	require.Zero(t, raft.lastIncludedIndex)
+	require.Zero(t, raft.lastIncludedTerm)
+}
+
+// TestFetchSnapshotSuccess checks that a node serves chunks of its most recent snapshot
+// to a peer and reports that the snapshot is unavailable if it is not recent enough.
+func TestFetchSnapshotSuccess(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.state = Follower
+
+	// There is no snapshot to fetch yet.
+	request := &FetchSnapshotRequest{MinIndex: 1}
+	response := &FetchSnapshotResponse{}
+	require.NoError(t, raft.FetchSnapshot(context.Background(), request, response))
+	require.True(t, response.Unavailable)
+
+	// Store a snapshot that spans multiple chunks.
+	data := bytes.Repeat([]byte("a"), snapshotChunkSize+10)
+	file, err := raft.snapshotStorage.NewSnapshotFile(4, 2, []byte("configuration"))
+	require.NoError(t, err)
+	_, err = file.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	// Fetch the first chunk of the snapshot.
+	response = &FetchSnapshotResponse{}
+	require.NoError(t, raft.FetchSnapshot(context.Background(), request, response))
+	require.False(t, response.Unavailable)
+	require.False(t, response.Done)
+	require.Equal(t, uint64(4), response.LastIncludedIndex)
+	require.Equal(t, uint64(2), response.LastIncludedTerm)
+	require.Equal(t, []byte("configuration"), response.Configuration)
+	require.Equal(t, data[:snapshotChunkSize], response.Bytes)
+
+	// Fetch the last chunk of the snapshot.
+	request = &FetchSnapshotRequest{MinIndex: 1, LastIncludedIndex: 4, Offset: snapshotChunkSize}
+	response = &FetchSnapshotResponse{}
+	require.NoError(t, raft.FetchSnapshot(context.Background(), request, response))
+	require.False(t, response.Unavailable)
+	require.True(t, response.Done)
+	require.Equal(t, data[snapshotChunkSize:], response.Bytes)
+
+	// The snapshot is not recent enough.
+	request = &FetchSnapshotRequest{MinIndex: 5}
+	response = &FetchSnapshotResponse{}
+	require.NoError(t, raft.FetchSnapshot(context.Background(), request, response))
+	require.True(t, response.Unavailable)
+
+	// The snapshot is not the one that was being fetched.
+	request = &FetchSnapshotRequest{MinIndex: 1, LastIncludedIndex: 3, Offset: snapshotChunkSize}
+	response = &FetchSnapshotResponse{}
+	require.NoError(t, raft.FetchSnapshot(context.Background(), request, response))
+	require.True(t, response.Unavailable)
+}
+
+// makePersistedState stores a snapshot with the provided last included index and a log
+// containing the provided number of entries in the provided directory.
+func makePersistedState(t *testing.T, dataPath string, lastIncludedIndex uint64, numEntries int) {
+	configuration := NewConfiguration(1, map[string]string{"1": "127.0.0.0:8080"})
+	configurationData, err := encodeConfiguration(configuration)
+	require.NoError(t, err)
+
+	operations := []Operation{{Bytes: []byte("snapshot"), LogIndex: lastIncludedIndex, LogTerm: 1}}
+	operationData, err := encodeOperations(operations)
+	require.NoError(t, err)
+	snapshotStorage, err := NewSnapshotStorage(dataPath)
+	require.NoError(t, err)
+	file, err := snapshotStorage.NewSnapshotFile(lastIncludedIndex, 1, configurationData)
+	require.NoError(t, err)
+	_, err = file.Write(operationData)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	log, err := NewLog(dataPath)
+	require.NoError(t, err)
+	require.NoError(t, log.Open())
+	require.NoError(t, log.Replay())
+	entries := []*LogEntry{NewLogEntry(1, 1, configurationData, ConfigurationEntry)}
+	for index := 2; index <= numEntries; index++ {
+		entries = append(entries, NewLogEntry(uint64(index), 1, []byte("operation"), OperationEntry))
+	}
+	require.NoError(t, log.AppendEntries(entries))
+	require.NoError(t, log.Close())
+}
+
+// TestRestorePersistentStateMachineSkipRestore checks that a state machine that persists
+// itself is not restored from a snapshot that it already contains and that operations it
+// has already applied will not be applied again.
+func TestRestorePersistentStateMachineSkipRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	makePersistedState(t, tmpDir, 2, 5)
+
+	// The state machine has applied operations beyond the snapshot.
+	fsm := &persistentStateMachineMock{newStateMachineMock(false, 0)}
+	operations := []Operation{
+		{Bytes: []byte("operation"), LogIndex: 3, LogTerm: 1},
+		{Bytes: []byte("operation"), LogIndex: 4, LogTerm: 1},
+	}
+	fsm.operations = append(fsm.operations, operations...)
+
+	raft, err := makeRaftWithStateMachine("1", "127.0.0.0:8080", tmpDir, fsm)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	require.Equal(t, uint64(2), raft.lastIncludedIndex)
+	require.Equal(t, uint64(4), raft.commitIndex)
+	require.Equal(t, uint64(4), raft.lastApplied)
+	require.Equal(t, operations, fsm.appliedOperations())
+}
+
+// TestRestorePersistentStateMachineRestore checks that a state machine that persists
+// itself is restored from a snapshot that contains state it does not have.
+func TestRestorePersistentStateMachineRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	makePersistedState(t, tmpDir, 4, 5)
+
+	// The state machine is behind the snapshot.
+	fsm := &persistentStateMachineMock{newStateMachineMock(false, 0)}
+	fsm.operations = append(fsm.operations, Operation{Bytes: []byte("operation"), LogIndex: 3, LogTerm: 1})
+
+	raft, err := makeRaftWithStateMachine("1", "127.0.0.0:8080", tmpDir, fsm)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	require.Equal(t, uint64(4), raft.lastIncludedIndex)
+	require.Equal(t, uint64(4), raft.commitIndex)
+	require.Equal(t, uint64(4), raft.lastApplied)
+	require.Equal(
+		t,
+		[]Operation{{Bytes: []byte("snapshot"), LogIndex: 4, LogTerm: 1}},
+		fsm.appliedOperations(),
+	)
+}
+
+// TestRestorePersistentStateMachineFailure checks that a node cannot be created if its
+// state machine has applied operations that are not in its log.
+func TestRestorePersistentStateMachineFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	makePersistedState(t, tmpDir, 2, 3)
+
+	fsm := &persistentStateMachineMock{newStateMachineMock(false, 0)}
+	fsm.operations = append(fsm.operations, Operation{Bytes: []byte("operation"), LogIndex: 5, LogTerm: 1})
+
+	_, err := makeRaftWithStateMachine("1", "127.0.0.0:8080", tmpDir, fsm)
+	require.Error(t, err)
+}
+
+// TestAppendEntriesCancelledFailure checks that an AppendEntries request that has been
+// abandoned by the leader is rejected.
+func TestAppendEntriesCancelledFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.state = Follower
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	request := &AppendEntriesRequest{LeaderID: "2", Term: 1}
+	response := &AppendEntriesResponse{}
+	require.ErrorIs(t, raft.AppendEntries(ctx, request, response), context.Canceled)
+	require.False(t, response.Success)
+	require.Zero(t, raft.currentTerm)
+}
+
+// TestNotLeaderError checks that operations and membership changes submitted to a node that is not
+// the leader fail with an error identifying the leader known to the node.
+func TestNotLeaderError(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.1:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+
+	raft.state = Follower
+	raft.currentTerm = 3
+	raft.leaderID = "2"
+	raft.configuration = &Configuration{
+		Members: map[string]string{"1": "127.0.0.1:8080", "2": "127.0.0.2:8080"},
+		IsVoter: map[string]bool{"1": true, "2": true},
+	}
+
+	checkNotLeader := func(err error) {
+		require.ErrorIs(t, err, ErrNotLeader)
+		var notLeader *NotLeaderError
+		require.ErrorAs(t, err, &notLeader)
+		require.Equal(t, "2", notLeader.LeaderID)
+		require.Equal(t, "127.0.0.2:8080", notLeader.LeaderAddress)
+		require.Equal(t, uint64(3), notLeader.Term)
+	}
+
+	checkNotLeader(raft.SubmitOperation([]byte("operation"), Replicated, futureTimeout).Await().Error())
+	checkNotLeader(raft.SubmitOperation(nil, LinearizableReadOnly, futureTimeout).Await().Error())
+	checkNotLeader(raft.AddServer("3", "127.0.0.3:8080", true, futureTimeout).Await().Error())
+	checkNotLeader(raft.RemoveServer("2", futureTimeout).Await().Error())
+
+	// The leader is not known.
+	raft.leaderID = ""
+	err = raft.SubmitOperation([]byte("operation"), Replicated, futureTimeout).Await().Error()
+	require.ErrorIs(t, err, ErrNotLeader)
+	var notLeader *NotLeaderError
+	require.ErrorAs(t, err, &notLeader)
+	require.Empty(t, notLeader.LeaderID)
+	require.Empty(t, notLeader.LeaderAddress)
+}
+
+// TestAppendEntriesProtocolVersion checks that a follower uses the protocol version chosen by
+// the leader, limited to the newest version it supports, and that requests from a leader that
+// does not support any of the versions supported by the follower are rejected.
+func TestAppendEntriesProtocolVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fsm := newStateMachineMock(false, 0)
+	raft, err := makeRaftWithStateMachine("1", "127.0.0.1:8080", tmpDir, fsm, WithMaxProtocolVersion(1))
+	require.NoError(t, err)
+
+	raft.state = Follower
+
+	// A leader that does not advertise a version only supports the original protocol.
+	request := &AppendEntriesRequest{LeaderID: "2", Term: 1}
+	response := &AppendEntriesResponse{}
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
+	require.True(t, response.Success)
+	require.Equal(t, MinProtocolVersion, response.MinProtocolVersion)
+	require.Equal(t, uint32(1), response.MaxProtocolVersion)
+	require.Equal(t, MinProtocolVersion, raft.Status().ProtocolVersion)
+
+	// The version chosen by the leader is limited to the newest version supported by this node.
+	request = &AppendEntriesRequest{
+		LeaderID:           "2",
+		Term:               1,
+		ProtocolVersion:    2,
+		MinProtocolVersion: 1,
+		MaxProtocolVersion: 2,
+	}
+	response = &AppendEntriesResponse{}
+	require.NoError(t, raft.AppendEntries(context.Background(), request, response))
+	require.True(t, response.Success)
+	require.Equal(t, uint32(1), raft.Status().ProtocolVersion)
+
+	// A leader that only supports newer versions is rejected.
+	request = &AppendEntriesRequest{
+		LeaderID:           "2",
+		Term:               2,
+		ProtocolVersion:    2,
+		MinProtocolVersion: 2,
+		MaxProtocolVersion: 2,
+	}
+	response = &AppendEntriesResponse{}
+	require.Error(t, raft.AppendEntries(context.Background(), request, response))
+	require.Equal(t, uint64(1), raft.currentTerm)
+}
+
+// TestUpdateProtocolVersion checks that the leader only raises the protocol version once every
+// voting member is known to support it and lowers it as soon as any voting member does not.
+func TestUpdateProtocolVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.1:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+
+	raft.state = Leader
+	raft.configuration = &Configuration{
+		Members: map[string]string{
+			"1": "127.0.0.1:8080",
+			"2": "127.0.0.2:8080",
+			"3": "127.0.0.3:8080",
+			"4": "127.0.0.4:8080",
+		},
+		IsVoter: map[string]bool{"1": true, "2": true, "3": true, "4": false},
+	}
+	raft.followers = map[string]*follower{
+		"2": new(follower),
+		"3": new(follower),
+		"4": new(follower),
+	}
+
+	// The version is not raised until the versions supported by every voter are known.
+	raft.updateProtocolVersion()
+	require.Equal(t, MinProtocolVersion, raft.protocolVersion)
+	raft.recordProtocolVersion("2", 1, 2)
+	require.Equal(t, MinProtocolVersion, raft.protocolVersion)
+	raft.recordProtocolVersion("3", 1, 2)
+	require.Equal(t, uint32(2), raft.protocolVersion)
+	require.True(t, raft.peerSupportsProtocolVersion("2", protocolVersionSnapshotTransfer))
+
+	// Non-voting members do not affect the version, but are checked individually.
+	raft.recordProtocolVersion("4", 0, 0)
+	require.Equal(t, uint32(2), raft.protocolVersion)
+	require.False(t, raft.peerSupportsProtocolVersion("4", protocolVersionSnapshotTransfer))
+
+	// The version is lowered as soon as a voter does not support it.
+	raft.recordProtocolVersion("3", 0, 0)
+	require.Equal(t, MinProtocolVersion, raft.protocolVersion)
+	require.False(t, raft.peerSupportsProtocolVersion("2", protocolVersionSnapshotTransfer))
+}
+
+// TestJointConsensusQuorum checks that quorum in a joint configuration requires a majority
+// of the voting members of both the old and the new configuration.
+func TestJointConsensusQuorum(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+
+	configuration := NewConfiguration(1, map[string]string{
+		"1": "127.0.0.0:8080",
+		"2": "127.0.0.1:8080",
+		"3": "127.0.0.2:8080",
+	})
+	joint := configuration.joint([]MembershipChange{
+		{ID: "4", Address: "127.0.0.3:8080", IsVoter: true},
+		{ID: "5", Address: "127.0.0.4:8080", IsVoter: true},
+		{ID: "2", Remove: true},
+		{ID: "3", Remove: true},
+	})
+	raft.configuration = &joint
+
+	// A majority of the new configuration is not enough.
+	require.False(t, raft.hasQuorum(map[string]bool{"1": true, "4": true, "5": true}))
+
+	// A majority of the old configuration is not enough.
+	require.False(t, raft.hasQuorum(map[string]bool{"1": true, "2": true, "3": true}))
+
+	// A majority of both configurations constitutes quorum.
+	require.True(t, raft.hasQuorum(map[string]bool{"1": true, "2": true, "4": true}))
+
+	// The removed nodes are no longer members once the joint configuration is left.
+	next := joint.next()
+	require.False(t, next.IsJoint())
+	require.Len(t, next.Members, 3)
+	require.NotContains(t, next.Members, "2")
+	require.NotContains(t, next.Members, "3")
+	require.True(t, next.IsVoter["1"] && next.IsVoter["4"] && next.IsVoter["5"])
+}
+
+// TestCatchUpRounds checks that a non-voter is given another catch-up round when a round
+// takes too long, that its promotion fails once it runs out of rounds, and that it is only
+// promoted once there is no pending membership change.
+func TestCatchUpRounds(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.state = Leader
+	raft.configuration = &Configuration{
+		Members: map[string]string{"1": "127.0.0.0:8080", "2": "127.0.0.1:8080"},
+		IsVoter: map[string]bool{"1": true, "2": false},
+		Index:   2,
+	}
+	raft.committedConfiguration = &Configuration{
+		Members: map[string]string{"1": "127.0.0.0:8080"},
+		IsVoter: map[string]bool{"1": true},
+		Index:   1,
+	}
+	responseCh := make(chan Result[Configuration], 1)
+	learner := &follower{matchIndex: 5}
+	raft.followers = map[string]*follower{"2": learner}
+
+	// A round that takes longer than the election timeout starts another round.
+	learner.catchUp = &catchUp{
+		roundIndex: 5,
+		roundStart: time.Now().Add(-2 * raft.options.electionTimeout),
+		rounds:     1,
+		responseCh: responseCh,
+	}
+	raft.maybePromote("2")
+	require.NotNil(t, learner.catchUp)
+	require.False(t, learner.catchUp.caughtUp)
+	require.Equal(t, 2, learner.catchUp.rounds)
+
+	// A round that completes within the election timeout means the node has caught up, but it
+	// is not promoted while there is a pending membership change.
+	raft.maybePromote("2")
+	require.NotNil(t, learner.catchUp)
+	require.True(t, learner.catchUp.caughtUp)
+	require.False(t, raft.configuration.IsVoter["2"])
+
+	// The promotion fails once the node has used all of its rounds.
+	learner.catchUp = &catchUp{
+		roundIndex: 5,
+		roundStart: time.Now().Add(-2 * raft.options.electionTimeout),
+		rounds:     raft.options.maxCatchUpRounds,
+		responseCh: responseCh,
+	}
+	raft.maybePromote("2")
+	require.Nil(t, learner.catchUp)
+	result := <-responseCh
+	require.ErrorIs(t, result.Error(), ErrCatchUpFailed)
+}
+
+// TestCheckMembershipChangeAllowed checks that membership changes are only allowed on a leader
+// that has committed an entry in its term, has no pending membership change, is not transferring
+// leadership, and uses a protocol version that supports the change.
+func TestCheckMembershipChangeAllowed(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.currentTerm = 1
+	raft.protocolVersion = protocolVersionJointConsensus
+	raft.configuration = &Configuration{
+		Members: map[string]string{"1": "127.0.0.0:8080"},
+		IsVoter: map[string]bool{"1": true},
+		Index:   1,
+	}
+	raft.committedConfiguration = raft.configuration
+	require.NoError(
+		t,
+		raft.log.AppendEntries([]*LogEntry{NewLogEntry(1, 1, nil, ConfigurationEntry)}),
+	)
+
+	require.ErrorIs(t, raft.checkMembershipChangeAllowed(0), ErrNotLeader)
+	raft.state = Leader
+
+	require.ErrorIs(t, raft.checkMembershipChangeAllowed(0), ErrNoCommitThisTerm)
+	raft.commitIndex = 1
+
+	require.NoError(t, raft.checkMembershipChangeAllowed(0))
+	require.NoError(t, raft.checkMembershipChangeAllowed(protocolVersionJointConsensus))
+	require.ErrorIs(
+		t,
+		raft.checkMembershipChangeAllowed(protocolVersionLearnerPromotion),
+		ErrUnsupportedProtocolVersion,
+	)
+
+	raft.leadershipTransfer = &leadershipTransfer{targetID: "2"}
+	require.ErrorIs(t, raft.checkMembershipChangeAllowed(0), ErrLeadershipTransferInProgress)
+	raft.leadershipTransfer = nil
+
+	raft.configuration = &Configuration{
+		Members: map[string]string{"1": "127.0.0.0:8080", "2": "127.0.0.1:8080"},
+		IsVoter: map[string]bool{"1": true, "2": true},
+		Index:   2,
+	}
+	require.ErrorIs(t, raft.checkMembershipChangeAllowed(0), ErrPendingConfiguration)
+}
+
+// TestForwardedError checks that the errors that callers may check for are reconstructed
+// by the follower from the code returned by the leader for a forwarded proposal.
+func TestForwardedError(t *testing.T) {
+	err := forwardedError(forwardedErrorCode(ErrPendingConfiguration), ErrPendingConfiguration.Error())
+	require.Equal(t, ErrPendingConfiguration, err)
+
+	wrapped := fmt.Errorf("%w: feature = test", ErrUnsupportedProtocolVersion)
+	err = forwardedError(forwardedErrorCode(wrapped), wrapped.Error())
+	require.ErrorIs(t, err, ErrUnsupportedProtocolVersion)
+	require.Equal(t, wrapped.Error(), err.Error())
+
+	other := errors.New(ErrTimeout.Error())
+	require.Zero(t, forwardedErrorCode(other))
+	require.NotErrorIs(t, forwardedError(0, other.Error()), ErrTimeout)
+	require.NotErrorIs(t, forwardedError(uint32(len(forwardedErrors)+1), "error"), ErrTimeout)
+}
+
+// TestForwardOperationCancelled checks that a forwarded operation stops waiting for its
+// result once the context of the request is done.
+func TestForwardOperationCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	// The operation is never applied since the raft instance is not started.
+	raft.currentTerm = 1
+	raft.state = Leader
+	raft.configuration = &Configuration{
+		Members: map[string]string{"1": "127.0.0.0:8080"},
+		IsVoter: map[string]bool{"1": true},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	request := &ForwardOperationRequest{
+		Operation:     []byte("operation"),
+		OperationType: Replicated,
+		Timeout:       time.Minute,
+	}
+	response := &ForwardOperationResponse{}
+
+	start := time.Now()
+	require.ErrorIs(t, raft.ForwardOperation(ctx, request, response), context.DeadlineExceeded)
+	require.Less(t, time.Since(start), request.Timeout)
+}
+
+// TestCheckQuorum checks that a leader remains the leader while it is in contact with a
+// quorum of the cluster and steps down, failing its pending operations, once it is not.
+func TestCheckQuorum(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.state = Leader
+	raft.leaderID = "1"
+	raft.configuration = &Configuration{
+		Members: map[string]string{
+			"1": "127.0.0.0:8080",
+			"2": "127.0.0.1:8080",
+			"3": "127.0.0.2:8080",
+		},
+		IsVoter: map[string]bool{"1": true, "2": true, "3": true},
+		Index:   1,
+	}
+	stale := time.Now().Add(-2 * raft.options.electionTimeout)
+	raft.followers = map[string]*follower{
+		"2": {lastContact: time.Now()},
+		"3": {lastContact: stale},
+	}
+	responseCh := make(chan Result[OperationResponse], 1)
+	raft.operationManager.pendingReplicated[2] = responseCh
+
+	// The leader has heard from a quorum of the cluster.
+	raft.checkQuorum()
+	require.Equal(t, Leader, raft.state)
+
+	// The leader has not heard from a quorum of the cluster.
+	raft.followers["2"].lastContact = stale
+	raft.checkQuorum()
+	require.Equal(t, Follower, raft.state)
+	require.Empty(t, raft.leaderID)
+
+	result := <-responseCh
+	require.ErrorIs(t, result.Error(), ErrNotLeader)
+}
+
+// TestAppendEntriesTimeout checks that the deadline for AppendEntries RPCs is derived from
+// the heartbeat interval and does not exceed the election timeout.
+func TestAppendEntriesTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaft("1", "127.0.0.0:8080", tmpDir, false, 0)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	raft.options.heartbeatInterval = 50 * time.Millisecond
+	raft.options.electionTimeout = 300 * time.Millisecond
+	require.Equal(t, 150*time.Millisecond, raft.appendEntriesTimeout())
+
+	raft.options.heartbeatInterval = 200 * time.Millisecond
+	require.Equal(t, raft.options.electionTimeout, raft.appendEntriesTimeout())
+}
+
+// TestReplicatorWindow checks that a replicator sends batches of entries until its window is
+// full, that a heartbeat sent while entries are outstanding is a probe, and that the window
+// is released as the results are handled.
+func TestReplicatorWindow(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	raft, err := makeRaftWithStateMachine(
+		"1",
+		"127.0.0.0:8080",
+		tmpDir,
+		newStateMachineMock(false, 0),
+		WithMaxInflightAppendEntries(2),
+		WithMaxAppendEntries(1),
+	)
+	require.NoError(t, err)
+	defer func() { raft.transport.Shutdown() }()
+
+	// The follower is unreachable, so every RPC fails.
+	raft.transport.(*transportMock).disconnect("127.0.0.1:8080")
+
+	raft.state = Leader
+	raft.currentTerm = 1
+	raft.leaderCtx = context.Background()
+	raft.configuration = &Configuration{
+		Members: map[string]string{"1": "127.0.0.0:8080", "2": "127.0.0.1:8080"},
+		IsVoter: map[string]bool{"1": true, "2": true},
+	}
+	for index := uint64(1); index <= 5; index++ {
+		require.NoError(t, raft.log.AppendEntry(NewLogEntry(index, 1, []byte("operation"), OperationEntry)))
+	}
+	raft.followers = map[string]*follower{"2": {nextIndex: 1}}
+	replicator := &replicator{
+		address: "127.0.0.1:8080",
+		results: make(chan appendEntriesResult, raft.options.maxInflight+1),
+		pending: true,
+	}
+
+	// Batches are sent until the window is full.
+	raft.replicateOnce("2", replicator)
+	require.Equal(t, 2, replicator.inflight)
+	require.False(t, replicator.heartbeating)
+	require.Equal(t, uint64(3), raft.followers["2"].nextIndex)
+
+	// A heartbeat sent while the window is full is a probe.
+	replicator.pending = true
+	raft.replicateOnce("2", replicator)
+	require.Equal(t, 2, replicator.inflight)
+	require.True(t, replicator.heartbeating)
+
+	probes := 0
+	for i := 0; i < 3; i++ {
+		result := <-replicator.results
+		if result.probe {
+			probes++
+		}
+		raft.handleAppendEntriesResult("2", replicator, result)
+	}
+	require.Equal(t, 1, probes)
+	require.Zero(t, replicator.inflight)
+	require.False(t, replicator.heartbeating)
+
+	// The entries that were lost must be sent again.
+	require.Equal(t, uint64(1), raft.followers["2"].nextIndex)
+}
@@ -1,6 +1,10 @@
 package raft
 
-import pb "github.com/jmsadair/raft/internal/protobuf"
+import (
+	"time"
+
+	pb "github.com/jmsadair/raft/internal/protobuf"
+)
 
 // AppendEntriesRequest is a request invoked by the leader to replicate log entries and also serves as a heartbeat.
 type AppendEntriesRequest struct {
@@ -21,6 +25,15 @@ type AppendEntriesRequest struct {
 
 	// Contains the log Entries to store (empty for heartbeat).
 	Entries []*LogEntry
+
+	// The protocol version in use by the cluster, as determined by the leader.
+	ProtocolVersion uint32
+
+	// The oldest protocol version supported by the sender.
+	MinProtocolVersion uint32
+
+	// The newest protocol version supported by the sender.
+	MaxProtocolVersion uint32
 }
 
 // AppendEntriesResponse is a response to a request to to replicate log entries.
@@ -33,6 +46,12 @@ type AppendEntriesResponse struct {
 
 	// The conflicting Index if there is one.
 	Index uint64
+
+	// The oldest protocol version supported by the server that received the request.
+	MinProtocolVersion uint32
+
+	// The newest protocol version supported by the server that received the request.
+	MaxProtocolVersion uint32
 }
 
 // RequestVoteRequest is a request invoked by candidates to gather votes.
@@ -51,6 +70,17 @@ type RequestVoteRequest struct {
 
 	// Indicates whether this request is for a prevote.
 	Prevote bool
+
+	// The oldest protocol version supported by the sender.
+	MinProtocolVersion uint32
+
+	// The newest protocol version supported by the sender.
+	MaxProtocolVersion uint32
+
+	// Indicates that the election was started at the request of the leader
+	// to transfer leadership to the candidate. The reciever grants its vote
+	// even if it has recently heard from the leader.
+	LeadershipTransfer bool
 }
 
 // RequestVoteResponse is a response to a request for a vote.
@@ -60,6 +90,12 @@ type RequestVoteResponse struct {
 
 	// Indicates whether the vote request was successful.
 	VoteGranted bool
+
+	// The oldest protocol version supported by the server that received the request.
+	MinProtocolVersion uint32
+
+	// The newest protocol version supported by the server that received the request.
+	MaxProtocolVersion uint32
 }
 
 // InstallSnapshotRequest is invoked by the leader to send a snapshot to a follower.
@@ -88,6 +124,25 @@ type InstallSnapshotRequest struct {
 
 	// Indicates whether this is the last chunk of the snapshot.
 	Done bool
+
+	// The last included index of the snapshot that this snapshot is
+	// an incremental snapshot of. Zero if this is a full snapshot.
+	BaseIndex uint64
+
+	// The ID of the peer that the reciever should fetch the snapshot
+	// from. If this is set, the request does not contain any part of
+	// the snapshot.
+	SourceID string
+
+	// The address of the peer that the reciever should fetch the
+	// snapshot from.
+	SourceAddress string
+
+	// The oldest protocol version supported by the sender.
+	MinProtocolVersion uint32
+
+	// The newest protocol version supported by the sender.
+	MaxProtocolVersion uint32
 }
 
 // InstallSnapshotResponse is a response to a snapshot installation.
@@ -99,6 +154,165 @@ type InstallSnapshotResponse struct {
 	// request is successful, this should be the number of bytes
 	// in the request.
 	BytesWritten int64
+
+	// Indicates that the reciever does not have the base of the
+	// incremental snapshot and must be sent a full snapshot.
+	MissingBase bool
+
+	// Indicates that the reciever was unable to fetch the snapshot
+	// from the peer it was directed to and must be sent the snapshot
+	// by the leader.
+	FetchFailed bool
+
+	// The oldest protocol version supported by the server that received the request.
+	MinProtocolVersion uint32
+
+	// The newest protocol version supported by the server that received the request.
+	MaxProtocolVersion uint32
+
+	// The last index included in the most recent snapshot of the server that received
+	// the request, or the last index it has applied if that is greater.
+	LastIncludedIndex uint64
+}
+
+// FetchSnapshotRequest is invoked by a node to fetch a chunk of the most recent
+// snapshot of a peer.
+type FetchSnapshotRequest struct {
+	// The minimum last included index of the snapshot.
+	MinIndex uint64
+
+	// The last included index of the snapshot being fetched. Zero
+	// if this is the first chunk of the snapshot.
+	LastIncludedIndex uint64
+
+	// The offset in the snapshot file.
+	Offset int64
+}
+
+// FetchSnapshotResponse is a response to a request to fetch a snapshot.
+type FetchSnapshotResponse struct {
+	// The last included index of the snapshot.
+	LastIncludedIndex uint64
+
+	// The term associated with the last included index.
+	LastIncludedTerm uint64
+
+	// The last included index of the snapshot that this snapshot is
+	// an incremental snapshot of. Zero if this is a full snapshot.
+	BaseIndex uint64
+
+	// The last configuration included in the snapshot.
+	Configuration []byte
+
+	// A chunk of the snapshot.
+	Bytes []byte
+
+	// Indicates whether this is the last chunk of the snapshot.
+	Done bool
+
+	// Indicates that the peer does not have the requested snapshot.
+	Unavailable bool
+}
+
+// TimeoutNowRequest is invoked by the leader to direct the reciever to start an
+// election immediately so that leadership is transferred to it.
+type TimeoutNowRequest struct {
+	// The leader's term.
+	Term uint64
+
+	// The ID of the leader.
+	LeaderID string
+}
+
+// TimeoutNowResponse is a response to a request to start an election.
+type TimeoutNowResponse struct {
+	// The term of the server that received the request.
+	Term uint64
+}
+
+// ForwardOperationRequest is invoked by a follower to forward an operation submitted
+// to it to the leader.
+type ForwardOperationRequest struct {
+	// The operation as bytes.
+	Operation []byte
+
+	// The type of the operation.
+	OperationType OperationType
+
+	// The amount of time the leader should wait for the operation to be applied.
+	Timeout time.Duration
+}
+
+// ForwardOperationResponse is a response to a forwarded operation.
+type ForwardOperationResponse struct {
+	// The log entry index associated with the operation. Only valid
+	// if this is a replicated operation.
+	LogIndex uint64
+
+	// The log entry term associated with the operation. Only valid
+	// if this is a replicated operation.
+	LogTerm uint64
+
+	// The encoded response returned by the state machine after
+	// applying the operation.
+	ApplicationResponse []byte
+
+	// The error that occurred while processing the operation.
+	// Empty if the operation was successful.
+	Error string
+
+	// Identifies the error that occurred while processing the operation
+	// if it is one that callers may check for. Zero otherwise.
+	ErrorCode uint32
+
+	// Identifies the leader known to the reciever if the reciever
+	// was not the leader. Nil if the reciever was the leader.
+	NotLeader *NotLeaderError
+}
+
+// ForwardMembershipChangeRequest is invoked by a follower to forward a membership
+// change submitted to it to the leader.
+type ForwardMembershipChangeRequest struct {
+	// The ID of the node being added or removed.
+	ID string
+
+	// The address of the node being added. Empty if the node is being removed.
+	Address string
+
+	// Indicates whether the node being added is a voter.
+	IsVoter bool
+
+	// Indicates whether the node is being removed rather than added.
+	Remove bool
+
+	// Indicates whether the node being added should be added as a non-voter
+	// and promoted to a voter once it has caught up with the leader.
+	Promote bool
+
+	// The amount of time the leader should wait for the membership change to complete.
+	Timeout time.Duration
+
+	// The membership changes to apply atomically. If non-empty, the ID, Address,
+	// IsVoter, and Remove fields are ignored.
+	Changes []MembershipChange
+}
+
+// ForwardMembershipChangeResponse is a response to a forwarded membership change.
+type ForwardMembershipChangeResponse struct {
+	// The encoded configuration that resulted from the membership change.
+	Configuration []byte
+
+	// The error that occurred while processing the membership change.
+	// Empty if the membership change was successful.
+	Error string
+
+	// Identifies the error that occurred while processing the membership
+	// change if it is one that callers may check for. Zero otherwise.
+	ErrorCode uint32
+
+	// Identifies the leader known to the reciever if the reciever
+	// was not the leader. Nil if the reciever was the leader.
+	NotLeader *NotLeaderError
 }
 
 // makeProtoEntries converts an array of LogEntry instances to an array of protobuf LogEntry instances.
@@ -119,62 +333,195 @@ func makeProtoEntries(entries []*LogEntry) []*pb.LogEntry {
 // makeProtoRequestVoteRequest converts a RequestVoteRequest instance to a protobuf RequestVoteRequest instance.
 func makeProtoRequestVoteRequest(request RequestVoteRequest) *pb.RequestVoteRequest {
 	return &pb.RequestVoteRequest{
-		CandidateId:  request.CandidateID,
-		Term:         request.Term,
-		LastLogIndex: request.LastLogIndex,
-		LastLogTerm:  request.LastLogTerm,
-		Prevote:      request.Prevote,
+		CandidateId:        request.CandidateID,
+		Term:               request.Term,
+		LastLogIndex:       request.LastLogIndex,
+		LastLogTerm:        request.LastLogTerm,
+		Prevote:            request.Prevote,
+		LeadershipTransfer: request.LeadershipTransfer,
+		MinProtocolVersion: request.MinProtocolVersion,
+		MaxProtocolVersion: request.MaxProtocolVersion,
 	}
 }
 
 // makeRequestVoteResponse converts a protobuf RequestVoteResponse instance to a RequestVoteResponse instance.
 func makeRequestVoteResponse(response *pb.RequestVoteResponse) RequestVoteResponse {
 	return RequestVoteResponse{
-		Term:        response.GetTerm(),
-		VoteGranted: response.GetVoteGranted(),
+		Term:               response.GetTerm(),
+		VoteGranted:        response.GetVoteGranted(),
+		MinProtocolVersion: response.GetMinProtocolVersion(),
+		MaxProtocolVersion: response.GetMaxProtocolVersion(),
 	}
 }
 
 // makeProtoAppendEntriesRequest converts an AppendEntriesRequest instance to a protobuf AppendEntriesRequest instance.
 func makeProtoAppendEntriesRequest(request AppendEntriesRequest) *pb.AppendEntriesRequest {
 	return &pb.AppendEntriesRequest{
-		LeaderId:     request.LeaderID,
-		Term:         request.Term,
-		LeaderCommit: request.LeaderCommit,
-		PrevLogIndex: request.PrevLogIndex,
-		PrevLogTerm:  request.PrevLogTerm,
-		Entries:      makeProtoEntries(request.Entries),
+		LeaderId:           request.LeaderID,
+		Term:               request.Term,
+		LeaderCommit:       request.LeaderCommit,
+		PrevLogIndex:       request.PrevLogIndex,
+		PrevLogTerm:        request.PrevLogTerm,
+		Entries:            makeProtoEntries(request.Entries),
+		ProtocolVersion:    request.ProtocolVersion,
+		MinProtocolVersion: request.MinProtocolVersion,
+		MaxProtocolVersion: request.MaxProtocolVersion,
 	}
 }
 
 // makeAppendEntriesResponse converts a protobuf AppendEntriesResponse instance to an AppendEntriesResponse instance.
 func makeAppendEntriesResponse(response *pb.AppendEntriesResponse) AppendEntriesResponse {
 	return AppendEntriesResponse{
-		Success: response.GetSuccess(),
-		Term:    response.GetTerm(),
-		Index:   response.GetIndex(),
+		Success:            response.GetSuccess(),
+		Term:               response.GetTerm(),
+		Index:              response.GetIndex(),
+		MinProtocolVersion: response.GetMinProtocolVersion(),
+		MaxProtocolVersion: response.GetMaxProtocolVersion(),
 	}
 }
 
 // makeProtoInstallSnapshotRequest converts an InstallSnapshotRequest instance to a protobuf InstallSnapshotRequest instance.
 func makeProtoInstallSnapshotRequest(request InstallSnapshotRequest) *pb.InstallSnapshotRequest {
 	return &pb.InstallSnapshotRequest{
-		Leader:            request.LeaderID,
-		Term:              request.Term,
-		LastIncludedIndex: request.LastIncludedIndex,
-		LastIncludedTerm:  request.LastIncludedTerm,
-		Configuration:     request.Configuration,
-		Data:              request.Bytes,
-		Offset:            request.Offset,
-		Done:              request.Done,
+		Leader:             request.LeaderID,
+		Term:               request.Term,
+		LastIncludedIndex:  request.LastIncludedIndex,
+		LastIncludedTerm:   request.LastIncludedTerm,
+		Configuration:      request.Configuration,
+		Data:               request.Bytes,
+		Offset:             request.Offset,
+		Done:               request.Done,
+		BaseIndex:          request.BaseIndex,
+		SourceId:           request.SourceID,
+		SourceAddress:      request.SourceAddress,
+		MinProtocolVersion: request.MinProtocolVersion,
+		MaxProtocolVersion: request.MaxProtocolVersion,
 	}
 }
 
 // makeInstallSnapshotResponse converts an protobuf InstallSnapshotResponse instance to a InstallSnapshotResponse instance.
 func makeInstallSnapshotResponse(response *pb.InstallSnapshotResponse) InstallSnapshotResponse {
 	return InstallSnapshotResponse{
-		Term:         response.GetTerm(),
-		BytesWritten: response.GetBytesWritten(),
+		Term:               response.GetTerm(),
+		BytesWritten:       response.GetBytesWritten(),
+		MissingBase:        response.GetMissingBase(),
+		FetchFailed:        response.GetFetchFailed(),
+		MinProtocolVersion: response.GetMinProtocolVersion(),
+		MaxProtocolVersion: response.GetMaxProtocolVersion(),
+		LastIncludedIndex:  response.GetLastIncludedIndex(),
+	}
+}
+
+// makeProtoFetchSnapshotRequest converts a FetchSnapshotRequest instance to a protobuf FetchSnapshotRequest instance.
+func makeProtoFetchSnapshotRequest(request FetchSnapshotRequest) *pb.FetchSnapshotRequest {
+	return &pb.FetchSnapshotRequest{
+		MinIndex:          request.MinIndex,
+		LastIncludedIndex: request.LastIncludedIndex,
+		Offset:            request.Offset,
+	}
+}
+
+// makeFetchSnapshotResponse converts a protobuf FetchSnapshotResponse instance to a FetchSnapshotResponse instance.
+func makeFetchSnapshotResponse(response *pb.FetchSnapshotResponse) FetchSnapshotResponse {
+	return FetchSnapshotResponse{
+		LastIncludedIndex: response.GetLastIncludedIndex(),
+		LastIncludedTerm:  response.GetLastIncludedTerm(),
+		BaseIndex:         response.GetBaseIndex(),
+		Configuration:     response.GetConfiguration(),
+		Bytes:             response.GetData(),
+		Done:              response.GetDone(),
+		Unavailable:       response.GetUnavailable(),
+	}
+}
+
+// makeProtoTimeoutNowRequest converts a TimeoutNowRequest instance to a protobuf TimeoutNowRequest instance.
+func makeProtoTimeoutNowRequest(request TimeoutNowRequest) *pb.TimeoutNowRequest {
+	return &pb.TimeoutNowRequest{
+		Term:     request.Term,
+		LeaderId: request.LeaderID,
+	}
+}
+
+// makeTimeoutNowResponse converts a protobuf TimeoutNowResponse instance to a TimeoutNowResponse instance.
+func makeTimeoutNowResponse(response *pb.TimeoutNowResponse) TimeoutNowResponse {
+	return TimeoutNowResponse{Term: response.GetTerm()}
+}
+
+// makeProtoForwardOperationRequest converts a ForwardOperationRequest instance to a protobuf ForwardOperationRequest instance.
+func makeProtoForwardOperationRequest(request ForwardOperationRequest) *pb.ForwardOperationRequest {
+	return &pb.ForwardOperationRequest{
+		Operation:     request.Operation,
+		OperationType: uint32(request.OperationType),
+		Timeout:       int64(request.Timeout),
+	}
+}
+
+// makeForwardOperationResponse converts a protobuf ForwardOperationResponse instance to a ForwardOperationResponse instance.
+func makeForwardOperationResponse(response *pb.ForwardOperationResponse) ForwardOperationResponse {
+	return ForwardOperationResponse{
+		LogIndex:            response.GetLogIndex(),
+		LogTerm:             response.GetLogTerm(),
+		ApplicationResponse: response.GetApplicationResponse(),
+		Error:               response.GetError(),
+		ErrorCode:           response.GetErrorCode(),
+		NotLeader:           makeNotLeaderError(response.GetNotLeader()),
+	}
+}
+
+// makeProtoForwardMembershipChangeRequest converts a ForwardMembershipChangeRequest instance to a protobuf ForwardMembershipChangeRequest instance.
+func makeProtoForwardMembershipChangeRequest(
+	request ForwardMembershipChangeRequest,
+) *pb.ForwardMembershipChangeRequest {
+	return &pb.ForwardMembershipChangeRequest{
+		Id:      request.ID,
+		Address: request.Address,
+		IsVoter: request.IsVoter,
+		Remove:  request.Remove,
+		Promote: request.Promote,
+		Timeout: int64(request.Timeout),
+		Changes: makeProtoMembershipChanges(request.Changes),
+	}
+}
+
+// makeProtoMembershipChanges converts an array of MembershipChange instances to an array of protobuf MembershipChange instances.
+func makeProtoMembershipChanges(changes []MembershipChange) []*pb.MembershipChange {
+	if len(changes) == 0 {
+		return nil
+	}
+	protoChanges := make([]*pb.MembershipChange, len(changes))
+	for i, change := range changes {
+		protoChanges[i] = &pb.MembershipChange{
+			Id:      change.ID,
+			Address: change.Address,
+			IsVoter: change.IsVoter,
+			Remove:  change.Remove,
+		}
+	}
+	return protoChanges
+}
+
+// makeForwardMembershipChangeResponse converts a protobuf ForwardMembershipChangeResponse instance to a ForwardMembershipChangeResponse instance.
+func makeForwardMembershipChangeResponse(
+	response *pb.ForwardMembershipChangeResponse,
+) ForwardMembershipChangeResponse {
+	return ForwardMembershipChangeResponse{
+		Configuration: response.GetConfiguration(),
+		Error:         response.GetError(),
+		ErrorCode:     response.GetErrorCode(),
+		NotLeader:     makeNotLeaderError(response.GetNotLeader()),
+	}
+}
+
+// makeNotLeaderError converts a protobuf NotLeader instance to a NotLeaderError instance.
+// Nil is returned if the provided instance is nil.
+func makeNotLeaderError(notLeader *pb.NotLeader) *NotLeaderError {
+	if notLeader == nil {
+		return nil
+	}
+	return &NotLeaderError{
+		LeaderID:      notLeader.GetLeaderId(),
+		LeaderAddress: notLeader.GetLeaderAddress(),
+		Term:          notLeader.GetTerm(),
 	}
 }
 
@@ -196,54 +543,69 @@ func makeEntries(protoEntries []*pb.LogEntry) []*LogEntry {
 // makeRequestVoteRequest converts a protobuf RequestVoteRequest instance to a RequestVoteRequest instance.
 func makeRequestVoteRequest(request *pb.RequestVoteRequest) RequestVoteRequest {
 	return RequestVoteRequest{
-		CandidateID:  request.GetCandidateId(),
-		Term:         request.GetTerm(),
-		LastLogIndex: request.GetLastLogIndex(),
-		LastLogTerm:  request.GetLastLogTerm(),
-		Prevote:      request.GetPrevote(),
+		CandidateID:        request.GetCandidateId(),
+		Term:               request.GetTerm(),
+		LastLogIndex:       request.GetLastLogIndex(),
+		LastLogTerm:        request.GetLastLogTerm(),
+		Prevote:            request.GetPrevote(),
+		LeadershipTransfer: request.GetLeadershipTransfer(),
+		MinProtocolVersion: request.GetMinProtocolVersion(),
+		MaxProtocolVersion: request.GetMaxProtocolVersion(),
 	}
 }
 
 // makeProtoRequestVoteResponse converts a RequestVoteResponse instance to a protobuf RequestVoteResponse instance.
 func makeProtoRequestVoteResponse(response RequestVoteResponse) *pb.RequestVoteResponse {
 	return &pb.RequestVoteResponse{
-		Term:        response.Term,
-		VoteGranted: response.VoteGranted,
+		Term:               response.Term,
+		VoteGranted:        response.VoteGranted,
+		MinProtocolVersion: response.MinProtocolVersion,
+		MaxProtocolVersion: response.MaxProtocolVersion,
 	}
 }
 
 // makeAppendEntriesRequest converts a protobuf AppendEntriesRequest instance to an AppendEntriesRequest instance.
 func makeAppendEntriesRequest(request *pb.AppendEntriesRequest) AppendEntriesRequest {
 	return AppendEntriesRequest{
-		LeaderID:     request.GetLeaderId(),
-		Term:         request.GetTerm(),
-		LeaderCommit: request.GetLeaderCommit(),
-		PrevLogIndex: request.GetPrevLogIndex(),
-		PrevLogTerm:  request.GetPrevLogTerm(),
-		Entries:      makeEntries(request.GetEntries()),
+		LeaderID:           request.GetLeaderId(),
+		Term:               request.GetTerm(),
+		LeaderCommit:       request.GetLeaderCommit(),
+		PrevLogIndex:       request.GetPrevLogIndex(),
+		PrevLogTerm:        request.GetPrevLogTerm(),
+		Entries:            makeEntries(request.GetEntries()),
+		ProtocolVersion:    request.GetProtocolVersion(),
+		MinProtocolVersion: request.GetMinProtocolVersion(),
+		MaxProtocolVersion: request.GetMaxProtocolVersion(),
 	}
 }
 
 // makeProtoAppendEntriesResponse converts an AppendEntriesResponse instance to a protobuf AppendEntriesResponse instance.
 func makeProtoAppendEntriesResponse(response AppendEntriesResponse) *pb.AppendEntriesResponse {
 	return &pb.AppendEntriesResponse{
-		Success: response.Success,
-		Term:    response.Term,
-		Index:   response.Index,
+		Success:            response.Success,
+		Term:               response.Term,
+		Index:              response.Index,
+		MinProtocolVersion: response.MinProtocolVersion,
+		MaxProtocolVersion: response.MaxProtocolVersion,
 	}
 }
 
 // makeInstallSnapshotRequest converts a protobuf InstallSnapshotRequest instance to a InstallSnapshotRequest instance.
 func makeInstallSnapshotRequest(request *pb.InstallSnapshotRequest) InstallSnapshotRequest {
 	return InstallSnapshotRequest{
-		LeaderID:          request.GetLeader(),
-		Term:              request.GetTerm(),
-		LastIncludedIndex: request.GetLastIncludedIndex(),
-		LastIncludedTerm:  request.GetLastIncludedTerm(),
-		Configuration:     request.GetConfiguration(),
-		Bytes:             request.GetData(),
-		Offset:            request.GetOffset(),
-		Done:              request.GetDone(),
+		LeaderID:           request.GetLeader(),
+		Term:               request.GetTerm(),
+		LastIncludedIndex:  request.GetLastIncludedIndex(),
+		LastIncludedTerm:   request.GetLastIncludedTerm(),
+		Configuration:      request.GetConfiguration(),
+		Bytes:              request.GetData(),
+		Offset:             request.GetOffset(),
+		Done:               request.GetDone(),
+		BaseIndex:          request.GetBaseIndex(),
+		SourceID:           request.GetSourceId(),
+		SourceAddress:      request.GetSourceAddress(),
+		MinProtocolVersion: request.GetMinProtocolVersion(),
+		MaxProtocolVersion: request.GetMaxProtocolVersion(),
 	}
 }
 
@@ -252,7 +614,125 @@ func makeProtoInstallSnapshotResponse(
 	response InstallSnapshotResponse,
 ) *pb.InstallSnapshotResponse {
 	return &pb.InstallSnapshotResponse{
-		Term:         response.Term,
-		BytesWritten: response.BytesWritten,
+		Term:               response.Term,
+		BytesWritten:       response.BytesWritten,
+		MissingBase:        response.MissingBase,
+		FetchFailed:        response.FetchFailed,
+		MinProtocolVersion: response.MinProtocolVersion,
+		MaxProtocolVersion: response.MaxProtocolVersion,
+		LastIncludedIndex:  response.LastIncludedIndex,
+	}
+}
+
+// makeFetchSnapshotRequest converts a protobuf FetchSnapshotRequest instance to a FetchSnapshotRequest instance.
+func makeFetchSnapshotRequest(request *pb.FetchSnapshotRequest) FetchSnapshotRequest {
+	return FetchSnapshotRequest{
+		MinIndex:          request.GetMinIndex(),
+		LastIncludedIndex: request.GetLastIncludedIndex(),
+		Offset:            request.GetOffset(),
+	}
+}
+
+// makeProtoFetchSnapshotResponse converts a FetchSnapshotResponse instance to a protobuf FetchSnapshotResponse instance.
+func makeProtoFetchSnapshotResponse(response FetchSnapshotResponse) *pb.FetchSnapshotResponse {
+	return &pb.FetchSnapshotResponse{
+		LastIncludedIndex: response.LastIncludedIndex,
+		LastIncludedTerm:  response.LastIncludedTerm,
+		BaseIndex:         response.BaseIndex,
+		Configuration:     response.Configuration,
+		Data:              response.Bytes,
+		Done:              response.Done,
+		Unavailable:       response.Unavailable,
+	}
+}
+
+// makeTimeoutNowRequest converts a protobuf TimeoutNowRequest instance to a TimeoutNowRequest instance.
+func makeTimeoutNowRequest(request *pb.TimeoutNowRequest) TimeoutNowRequest {
+	return TimeoutNowRequest{
+		Term:     request.GetTerm(),
+		LeaderID: request.GetLeaderId(),
+	}
+}
+
+// makeProtoTimeoutNowResponse converts a TimeoutNowResponse instance to a protobuf TimeoutNowResponse instance.
+func makeProtoTimeoutNowResponse(response TimeoutNowResponse) *pb.TimeoutNowResponse {
+	return &pb.TimeoutNowResponse{Term: response.Term}
+}
+
+// makeForwardOperationRequest converts a protobuf ForwardOperationRequest instance to a ForwardOperationRequest instance.
+func makeForwardOperationRequest(request *pb.ForwardOperationRequest) ForwardOperationRequest {
+	return ForwardOperationRequest{
+		Operation:     request.GetOperation(),
+		OperationType: OperationType(request.GetOperationType()),
+		Timeout:       time.Duration(request.GetTimeout()),
+	}
+}
+
+// makeProtoForwardOperationResponse converts a ForwardOperationResponse instance to a protobuf ForwardOperationResponse instance.
+func makeProtoForwardOperationResponse(response ForwardOperationResponse) *pb.ForwardOperationResponse {
+	return &pb.ForwardOperationResponse{
+		LogIndex:            response.LogIndex,
+		LogTerm:             response.LogTerm,
+		ApplicationResponse: response.ApplicationResponse,
+		Error:               response.Error,
+		ErrorCode:           response.ErrorCode,
+		NotLeader:           makeProtoNotLeader(response.NotLeader),
+	}
+}
+
+// makeForwardMembershipChangeRequest converts a protobuf ForwardMembershipChangeRequest instance to a ForwardMembershipChangeRequest instance.
+func makeForwardMembershipChangeRequest(
+	request *pb.ForwardMembershipChangeRequest,
+) ForwardMembershipChangeRequest {
+	return ForwardMembershipChangeRequest{
+		ID:      request.GetId(),
+		Address: request.GetAddress(),
+		IsVoter: request.GetIsVoter(),
+		Remove:  request.GetRemove(),
+		Promote: request.GetPromote(),
+		Timeout: time.Duration(request.GetTimeout()),
+		Changes: makeMembershipChanges(request.GetChanges()),
+	}
+}
+
+// makeMembershipChanges converts an array of protobuf MembershipChange instances to an array of MembershipChange instances.
+func makeMembershipChanges(protoChanges []*pb.MembershipChange) []MembershipChange {
+	if len(protoChanges) == 0 {
+		return nil
+	}
+	changes := make([]MembershipChange, len(protoChanges))
+	for i, protoChange := range protoChanges {
+		changes[i] = MembershipChange{
+			ID:      protoChange.GetId(),
+			Address: protoChange.GetAddress(),
+			IsVoter: protoChange.GetIsVoter(),
+			Remove:  protoChange.GetRemove(),
+		}
+	}
+	return changes
+}
+
+// makeProtoForwardMembershipChangeResponse converts a ForwardMembershipChangeResponse instance to a protobuf ForwardMembershipChangeResponse instance.
+func makeProtoForwardMembershipChangeResponse(
+	response ForwardMembershipChangeResponse,
+) *pb.ForwardMembershipChangeResponse {
+	return &pb.ForwardMembershipChangeResponse{
+		Configuration: response.Configuration,
+		Error:         response.Error,
+		ErrorCode:     response.ErrorCode,
+		NotLeader:     makeProtoNotLeader(response.NotLeader),
+	}
+}
+
+// makeProtoNotLeader converts a NotLeaderError instance to a protobuf NotLeader instance.
+// Nil is returned if the provided instance is nil.
+func makeProtoNotLeader(err *NotLeaderError) *pb.NotLeader {
+	if err == nil {
+		return nil
+	}
+	return &pb.NotLeader{
+		LeaderId:      err.LeaderID,
+		LeaderAddress: err.LeaderAddress,
+		Term:          err.Term,
 	}
 }
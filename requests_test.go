@@ -125,14 +125,16 @@ func TestMakeProtoInstallSnapshotRequest(t *testing.T) {
 // InstallSnapshotResponse.
 func TestMakeInstallSnapshotResponse(t *testing.T) {
 	protoResponse := &pb.InstallSnapshotResponse{
-		Term:         1,
-		BytesWritten: 10,
+		Term:              1,
+		BytesWritten:      10,
+		LastIncludedIndex: 5,
 	}
 
 	response := makeInstallSnapshotResponse(protoResponse)
 
 	require.Equal(t, protoResponse.GetTerm(), response.Term)
 	require.Equal(t, protoResponse.GetBytesWritten(), response.BytesWritten)
+	require.Equal(t, protoResponse.GetLastIncludedIndex(), response.LastIncludedIndex)
 }
 
 // TestMakeEntries checks that an array of protobuf log entries is correctly converted to an array
@@ -253,12 +255,14 @@ func TestMakeInstallSnapshotRequest(t *testing.T) {
 // protobuf InstallSnapshotResponse.
 func TestMakeProtoInstallSnapshotResponse(t *testing.T) {
 	response := InstallSnapshotResponse{
-		Term:         1,
-		BytesWritten: 10,
+		Term:              1,
+		BytesWritten:      10,
+		LastIncludedIndex: 5,
 	}
 
 	protoResponse := makeProtoInstallSnapshotResponse(response)
 
 	require.Equal(t, response.Term, protoResponse.GetTerm())
 	require.Equal(t, response.BytesWritten, protoResponse.GetBytesWritten())
+	require.Equal(t, response.LastIncludedIndex, protoResponse.GetLastIncludedIndex())
 }
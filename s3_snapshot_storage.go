@@ -0,0 +1,618 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/jmsadair/raft/internal/s3"
+)
+
+// The minimum size of a part in a multipart upload supported by most S3-compatible stores.
+const defaultS3PartSize = 5 * 1024 * 1024
+
+// The default amount of time that a request to the bucket may take.
+const defaultS3RequestTimeout = time.Minute
+
+type s3Options struct {
+	// The region of the bucket.
+	region string
+
+	// The access key ID used to sign requests.
+	accessKeyID string
+
+	// The secret access key used to sign requests.
+	secretAccessKey string
+
+	// The prefix of all keys written by the storage.
+	prefix string
+
+	// The size of each part of a multipart upload in bytes.
+	partSize int
+
+	// The HTTP client used to make requests.
+	httpClient *http.Client
+
+	// The amount of time that a request may take.
+	requestTimeout time.Duration
+}
+
+// S3Option is a function that updates the options associated with an S3 snapshot storage.
+type S3Option func(options *s3Options) error
+
+// WithS3Credentials sets the credentials used to sign requests to the bucket.
+func WithS3Credentials(accessKeyID string, secretAccessKey string) S3Option {
+	return func(options *s3Options) error {
+		if accessKeyID == "" || secretAccessKey == "" {
+			return errors.New("access key ID and secret access key must not be empty")
+		}
+		options.accessKeyID = accessKeyID
+		options.secretAccessKey = secretAccessKey
+		return nil
+	}
+}
+
+// WithS3Region sets the region of the bucket. The default region is us-east-1.
+func WithS3Region(region string) S3Option {
+	return func(options *s3Options) error {
+		options.region = region
+		return nil
+	}
+}
+
+// WithS3Prefix sets the prefix of all keys written by the snapshot storage. This allows
+// multiple nodes to share a single bucket, in which case each node must use its own prefix.
+// Uploads left incomplete by a crash are only aborted when the storage is created if a
+// prefix is set, since they may otherwise belong to another node.
+func WithS3Prefix(prefix string) S3Option {
+	return func(options *s3Options) error {
+		options.prefix = prefix
+		return nil
+	}
+}
+
+// WithS3PartSize sets the size in bytes of each part of a multipart upload. Most S3-compatible
+// stores require that all parts except the last are at least 5 MiB, which is the default.
+func WithS3PartSize(partSize int) S3Option {
+	return func(options *s3Options) error {
+		if partSize <= 0 {
+			return errors.New("part size must be positive")
+		}
+		options.partSize = partSize
+		return nil
+	}
+}
+
+// WithS3HTTPClient sets the HTTP client used to make requests to the bucket.
+func WithS3HTTPClient(client *http.Client) S3Option {
+	return func(options *s3Options) error {
+		if client == nil {
+			return errors.New("HTTP client must not be nil")
+		}
+		options.httpClient = client
+		return nil
+	}
+}
+
+// WithS3RequestTimeout sets the amount of time that a request to the bucket may take before it
+// is cancelled. This includes reading the body of the response, so it bounds the time taken to
+// read the data of a snapshot from a single request. The default timeout is one minute.
+func WithS3RequestTimeout(timeout time.Duration) S3Option {
+	return func(options *s3Options) error {
+		if timeout <= 0 {
+			return errors.New("request timeout must be positive")
+		}
+		options.requestTimeout = timeout
+		return nil
+	}
+}
+
+// s3SnapshotWriter implements the SnapshotFile interface for a snapshot that is being
+// written to an S3-compatible bucket. The snapshot is uploaded in parts as it is written.
+type s3SnapshotWriter struct {
+	// The client for the bucket.
+	client *s3.Client
+
+	// The key of the directory that will contain the snapshot and its metadata.
+	dir string
+
+	// The ID of the multipart upload for the snapshot data.
+	uploadID string
+
+	// The size of each part of the upload.
+	partSize int
+
+	// The amount of time that a request may take.
+	requestTimeout time.Duration
+
+	// The parts that have been uploaded.
+	parts []s3.Part
+
+	// Data that has been written but not yet uploaded.
+	buf bytes.Buffer
+
+	// The total number of bytes written.
+	written int64
+
+	// Indicates whether the snapshot has been closed or discarded.
+	closed bool
+
+	// The error that caused a part to fail to upload, if one has.
+	err error
+
+	// The metadata associated with the snapshot.
+	metadata SnapshotMetadata
+}
+
+func (s *s3SnapshotWriter) Read(p []byte) (int, error) {
+	return 0, errors.New("snapshot is being written and cannot be read")
+}
+
+func (s *s3SnapshotWriter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("snapshot is closed")
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, _ := s.buf.Write(p)
+	for s.buf.Len() >= s.partSize {
+		// Data that was buffered by an earlier write may have been lost, so the
+		// snapshot cannot be completed once a part fails to upload.
+		if err := s.uploadPart(s.buf.Next(s.partSize)); err != nil {
+			s.err = err
+			return 0, err
+		}
+	}
+	s.written += int64(n)
+	return n, nil
+}
+
+func (s *s3SnapshotWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, errors.New("snapshot is being written and only supports reporting its offset")
+	}
+	return s.written, nil
+}
+
+func (s *s3SnapshotWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.err != nil {
+		s.abort()
+		return s.err
+	}
+
+	// Upload any remaining data as the final part. There is always at least one part.
+	if s.buf.Len() > 0 || len(s.parts) == 0 {
+		if err := s.uploadPart(s.buf.Bytes()); err != nil {
+			s.abort()
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	if err := s.client.CompleteMultipartUpload(
+		ctx,
+		path.Join(s.dir, snapshotBase),
+		s.uploadID,
+		s.parts,
+	); err != nil {
+		s.abort()
+		return fmt.Errorf("could not complete upload: %w", err)
+	}
+
+	// The metadata is written last so that its presence indicates that
+	// the snapshot is complete.
+	var metadata bytes.Buffer
+	if err := encodeMetadata(&metadata, &s.metadata); err != nil {
+		return fmt.Errorf("could not encode snapshot metadata: %w", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	if err := s.client.PutObject(
+		ctx,
+		path.Join(s.dir, metadataBase),
+		metadata.Bytes(),
+	); err != nil {
+		return fmt.Errorf("could not write snapshot metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (s *s3SnapshotWriter) Discard() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	if err := s.client.AbortMultipartUpload(
+		ctx,
+		path.Join(s.dir, snapshotBase),
+		s.uploadID,
+	); err != nil {
+		return fmt.Errorf("could not abort upload: %w", err)
+	}
+	return nil
+}
+
+func (s *s3SnapshotWriter) Metadata() SnapshotMetadata {
+	return s.metadata
+}
+
+func (s *s3SnapshotWriter) uploadPart(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	part, err := s.client.UploadPart(
+		ctx,
+		path.Join(s.dir, snapshotBase),
+		s.uploadID,
+		len(s.parts)+1,
+		data,
+	)
+	if err != nil {
+		return fmt.Errorf("could not upload part: %w", err)
+	}
+	s.parts = append(s.parts, part)
+	return nil
+}
+
+func (s *s3SnapshotWriter) abort() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	_ = s.client.AbortMultipartUpload(
+		ctx,
+		path.Join(s.dir, snapshotBase),
+		s.uploadID,
+	)
+}
+
+// s3SnapshotReader implements the SnapshotFile interface for a complete snapshot stored
+// in an S3-compatible bucket. Data is fetched lazily starting from the current offset.
+type s3SnapshotReader struct {
+	// The client for the bucket.
+	client *s3.Client
+
+	// The key of the snapshot data.
+	key string
+
+	// The size of the snapshot data in bytes.
+	size int64
+
+	// The current offset.
+	offset int64
+
+	// The amount of time that a request may take.
+	requestTimeout time.Duration
+
+	// The body of the current request, if there is one.
+	body io.ReadCloser
+
+	// Cancels the current request, if there is one.
+	cancel context.CancelFunc
+
+	// The metadata associated with the snapshot.
+	metadata SnapshotMetadata
+}
+
+func (s *s3SnapshotReader) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+	if s.body == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+		body, err := s.client.GetObject(ctx, s.key, s.offset)
+		if err != nil {
+			cancel()
+			return 0, fmt.Errorf("could not get snapshot data: %w", err)
+		}
+		s.body = body
+		s.cancel = cancel
+	}
+	n, err := s.body.Read(p)
+	s.offset += int64(n)
+	if err == io.EOF && s.offset < s.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (s *s3SnapshotReader) Write(p []byte) (int, error) {
+	return 0, errors.New("snapshot is complete and cannot be written")
+}
+
+func (s *s3SnapshotReader) Seek(offset int64, whence int) (int64, error) {
+	var next int64
+	switch whence {
+	case io.SeekStart:
+		next = offset
+	case io.SeekCurrent:
+		next = s.offset + offset
+	case io.SeekEnd:
+		next = s.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if next < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if next != s.offset {
+		s.closeBody()
+		s.offset = next
+	}
+	return s.offset, nil
+}
+
+func (s *s3SnapshotReader) Close() error {
+	s.closeBody()
+	return nil
+}
+
+func (s *s3SnapshotReader) Discard() error {
+	return nil
+}
+
+func (s *s3SnapshotReader) Metadata() SnapshotMetadata {
+	return s.metadata
+}
+
+func (s *s3SnapshotReader) closeBody() {
+	if s.body != nil {
+		_ = s.body.Close()
+		s.cancel()
+		s.body = nil
+		s.cancel = nil
+	}
+}
+
+// s3SnapshotStorage is an implementation of the SnapshotStorage interface that stores
+// snapshots in an S3-compatible bucket. This implementation is not concurrent safe.
+type s3SnapshotStorage struct {
+	// The client for the bucket.
+	client *s3.Client
+
+	// The key prefix under which snapshots are stored.
+	snapshotDir string
+
+	// The size of each part of a multipart upload.
+	partSize int
+
+	// The amount of time that a request may take.
+	requestTimeout time.Duration
+}
+
+// NewS3SnapshotStorage creates a new SnapshotStorage instance that stores snapshots
+// in the provided bucket of an S3-compatible object store located at the provided endpoint.
+//
+// Snapshots will be stored under the key prefix/snapshots. Like the local snapshot storage,
+// each snapshot has its own directory that is named using a timestamp taken at the time of
+// its creation, and each of these directories will contain two objects - one for the content
+// of the snapshot and one for its metadata. Snapshot data is written using a multipart upload
+// and the metadata is written once the upload completes, so a snapshot is only visible once it
+// is complete. Since snapshots survive the loss of a node, a new node may be started with the
+// snapshot storage of a lost node to restore its state.
+// The returned storage also implements IncrementalSnapshotStorage.
+func NewS3SnapshotStorage(endpoint string, bucket string, opts ...S3Option) (SnapshotStorage, error) {
+	var options s3Options
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			return nil, err
+		}
+	}
+	if options.partSize == 0 {
+		options.partSize = defaultS3PartSize
+	}
+	if options.requestTimeout == 0 {
+		options.requestTimeout = defaultS3RequestTimeout
+	}
+
+	client, err := s3.NewClient(s3.Config{
+		Endpoint:        endpoint,
+		Bucket:          bucket,
+		Region:          options.region,
+		AccessKeyID:     options.accessKeyID,
+		SecretAccessKey: options.secretAccessKey,
+		HTTPClient:      options.httpClient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create S3 client: %w", err)
+	}
+
+	storage := &s3SnapshotStorage{
+		client:         client,
+		snapshotDir:    path.Join(options.prefix, snapshotDirBase),
+		partSize:       options.partSize,
+		requestTimeout: options.requestTimeout,
+	}
+
+	// Abort any uploads that may have been left incomplete before a crash. Without a prefix,
+	// the uploads may belong to other nodes sharing the bucket, so they are left alone.
+	if options.prefix == "" {
+		return storage, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), options.requestTimeout)
+	uploads, err := client.ListMultipartUploads(ctx, storage.snapshotDir+"/")
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("could not list incomplete uploads: %w", err)
+	}
+	for _, upload := range uploads {
+		ctx, cancel := context.WithTimeout(context.Background(), options.requestTimeout)
+		err := client.AbortMultipartUpload(ctx, upload.Key, upload.UploadID)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("could not abort incomplete upload: %w", err)
+		}
+	}
+
+	return storage, nil
+}
+
+func (s *s3SnapshotStorage) NewSnapshotFile(
+	lastIncludedIndex uint64, lastIncludedTerm uint64, configuration []byte,
+) (SnapshotFile, error) {
+	return s.newSnapshotFile(lastIncludedIndex, lastIncludedTerm, 0, configuration)
+}
+
+func (s *s3SnapshotStorage) NewIncrementalSnapshotFile(
+	lastIncludedIndex uint64, lastIncludedTerm uint64, baseIndex uint64, configuration []byte,
+) (SnapshotFile, error) {
+	return s.newSnapshotFile(lastIncludedIndex, lastIncludedTerm, baseIndex, configuration)
+}
+
+func (s *s3SnapshotStorage) newSnapshotFile(
+	lastIncludedIndex uint64, lastIncludedTerm uint64, baseIndex uint64, configuration []byte,
+) (SnapshotFile, error) {
+	dir := path.Join(s.snapshotDir, buildDirectoryBase())
+	ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	uploadID, err := s.client.CreateMultipartUpload(ctx, path.Join(dir, snapshotBase))
+	if err != nil {
+		return nil, fmt.Errorf("could not create upload for snapshot data: %w", err)
+	}
+	return &s3SnapshotWriter{
+		client:         s.client,
+		dir:            dir,
+		uploadID:       uploadID,
+		partSize:       s.partSize,
+		requestTimeout: s.requestTimeout,
+		metadata: SnapshotMetadata{
+			LastIncludedIndex: lastIncludedIndex,
+			LastIncludedTerm:  lastIncludedTerm,
+			Configuration:     configuration,
+			BaseIndex:         baseIndex,
+		},
+	}, nil
+}
+
+func (s *s3SnapshotStorage) SnapshotFile() (SnapshotFile, error) {
+	// Retrieve the most recent snapshot if there is one.
+	dirs, err := s.directories()
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+	dir := dirs[len(dirs)-1]
+
+	metadata, err := s.metadata(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.open(dir, metadata)
+}
+
+func (s *s3SnapshotStorage) SnapshotChain(lastIncludedIndex uint64) ([]SnapshotFile, error) {
+	dirs, err := s.directories()
+	if err != nil {
+		return nil, err
+	}
+
+	// Map each last included index to the most recent snapshot with that index.
+	indexDirs := make(map[uint64]string, len(dirs))
+	metadata := make(map[string]SnapshotMetadata, len(dirs))
+	for _, dir := range dirs {
+		dirMetadata, err := s.metadata(dir)
+		if err != nil {
+			return nil, err
+		}
+		indexDirs[dirMetadata.LastIncludedIndex] = dir
+		metadata[dir] = dirMetadata
+	}
+
+	chain := snapshotChain(lastIncludedIndex, indexDirs, metadata)
+	if chain == nil {
+		return nil, nil
+	}
+
+	files := make([]SnapshotFile, 0, len(chain))
+	for _, dir := range chain {
+		file, err := s.open(dir, metadata[dir])
+		if err != nil {
+			_ = closeSnapshotFiles(files)
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// metadata reads the metadata of the snapshot in the provided directory.
+func (s *s3SnapshotStorage) metadata(dir string) (SnapshotMetadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	reader, err := s.client.GetObject(ctx, path.Join(dir, metadataBase), 0)
+	if err != nil {
+		return SnapshotMetadata{}, fmt.Errorf("could not get snapshot metadata: %w", err)
+	}
+	defer reader.Close()
+	metadata, err := decodeMetadata(reader)
+	if err != nil {
+		return SnapshotMetadata{}, fmt.Errorf("could not decode snapshot metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// open makes the data of the snapshot in the provided directory prepared for reading.
+func (s *s3SnapshotStorage) open(dir string, metadata SnapshotMetadata) (SnapshotFile, error) {
+	key := path.Join(dir, snapshotBase)
+	ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	size, err := s.client.HeadObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("could not get snapshot data size: %w", err)
+	}
+	return &s3SnapshotReader{
+		client:         s.client,
+		key:            key,
+		size:           size,
+		requestTimeout: s.requestTimeout,
+		metadata:       metadata,
+	}, nil
+}
+
+// directories returns the keys of the directories of all complete snapshots
+// sorted by their timestamp.
+func (s *s3SnapshotStorage) directories() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	keys, err := s.client.ListObjects(ctx, s.snapshotDir+"/")
+	if err != nil {
+		return nil, fmt.Errorf("could not list snapshots: %w", err)
+	}
+
+	// A snapshot is only complete once its metadata has been written.
+	dirs := make([]string, 0, len(keys))
+	timestamps := make(map[string]int64, len(keys))
+	for _, key := range keys {
+		if path.Base(key) != metadataBase {
+			continue
+		}
+		dir := path.Dir(key)
+		var timestamp int64
+		if _, err := fmt.Sscanf(path.Base(dir), "snapshot-%d", &timestamp); err != nil {
+			continue
+		}
+		dirs = append(dirs, dir)
+		timestamps[dir] = timestamp
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		return timestamps[dirs[i]] < timestamps[dirs[j]]
+	})
+
+	return dirs, nil
+}
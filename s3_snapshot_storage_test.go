@@ -0,0 +1,266 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmsadair/raft/internal/s3"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testS3Bucket          = "raft"
+	testS3Region          = "us-east-1"
+	testS3AccessKeyID     = "access-key"
+	testS3SecretAccessKey = "secret-key"
+)
+
+func newTestS3SnapshotStorage(t *testing.T, server *s3.FakeServer, prefix string) SnapshotStorage {
+	store, err := NewS3SnapshotStorage(
+		server.URL(),
+		testS3Bucket,
+		WithS3Credentials(testS3AccessKeyID, testS3SecretAccessKey),
+		WithS3Region(testS3Region),
+		WithS3Prefix(prefix),
+		WithS3PartSize(4),
+	)
+	require.NoError(t, err)
+	return store
+}
+
+func TestS3SnapshotStorageWriterReader(t *testing.T) {
+	server := s3.NewFakeServer(testS3Bucket, testS3Region, testS3AccessKeyID, testS3SecretAccessKey)
+	defer server.Close()
+	store := newTestS3SnapshotStorage(t, server, "node1")
+
+	// There should not be a snapshot yet.
+	file, err := store.SnapshotFile()
+	require.NoError(t, err)
+	require.Nil(t, file)
+
+	// Write the first snapshot. The data is larger than the part size so multiple parts are uploaded.
+	lastIncludedIndex1 := uint64(1)
+	lastIncludedTerm1 := uint64(1)
+	configuration1 := []byte("configuration1")
+	data1 := []byte("snapshot1")
+	file1, err := store.NewSnapshotFile(lastIncludedIndex1, lastIncludedTerm1, configuration1)
+	require.NoError(t, err)
+	n, err := file1.Write(data1)
+	require.NoError(t, err)
+	require.Equal(t, len(data1), n)
+	offset, err := file1.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data1)), offset)
+	require.NoError(t, file1.Close())
+
+	// Check the first snapshot.
+	file1, err = store.SnapshotFile()
+	require.NoError(t, err)
+	metadata1 := file1.Metadata()
+	require.Equal(t, lastIncludedIndex1, metadata1.LastIncludedIndex)
+	require.Equal(t, lastIncludedTerm1, metadata1.LastIncludedTerm)
+	require.Equal(t, configuration1, metadata1.Configuration)
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, file1)
+	require.NoError(t, err)
+	require.Equal(t, string(data1), buf.String())
+	require.NoError(t, file1.Close())
+
+	// Write the second snapshot.
+	lastIncludedIndex2 := uint64(2)
+	lastIncludedTerm2 := uint64(2)
+	configuration2 := []byte("configuration2")
+	data2 := []byte("snapshot2")
+	file2, err := store.NewSnapshotFile(lastIncludedIndex2, lastIncludedTerm2, configuration2)
+	require.NoError(t, err)
+	n, err = file2.Write(data2)
+	require.NoError(t, err)
+	require.Equal(t, len(data2), n)
+	require.NoError(t, file2.Close())
+
+	// Check the second snapshot.
+	file2, err = store.SnapshotFile()
+	require.NoError(t, err)
+	metadata2 := file2.Metadata()
+	require.Equal(t, lastIncludedIndex2, metadata2.LastIncludedIndex)
+	require.Equal(t, lastIncludedTerm2, metadata2.LastIncludedTerm)
+	require.Equal(t, configuration2, metadata2.Configuration)
+	buf.Reset()
+	_, err = io.Copy(&buf, file2)
+	require.NoError(t, err)
+	require.Equal(t, string(data2), buf.String())
+
+	// Seek back into the snapshot and read the remainder.
+	offset, err = file2.Seek(5, io.SeekStart)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), offset)
+	buf.Reset()
+	_, err = io.Copy(&buf, file2)
+	require.NoError(t, err)
+	require.Equal(t, string(data2[5:]), buf.String())
+	require.NoError(t, file2.Close())
+
+	// Another storage with a different prefix should not see the snapshots.
+	otherStore := newTestS3SnapshotStorage(t, server, "node2")
+	file, err = otherStore.SnapshotFile()
+	require.NoError(t, err)
+	require.Nil(t, file)
+}
+
+func TestS3SnapshotStorageChain(t *testing.T) {
+	server := s3.NewFakeServer(testS3Bucket, testS3Region, testS3AccessKeyID, testS3SecretAccessKey)
+	defer server.Close()
+	store := newTestS3SnapshotStorage(t, server, "")
+	testSnapshotStorageChain(t, store.(IncrementalSnapshotStorage))
+}
+
+// TestS3SnapshotStorageDiscard checks that discarded and incomplete snapshots are not
+// visible and that their uploads are aborted without affecting the uploads of other nodes.
+func TestS3SnapshotStorageDiscard(t *testing.T) {
+	server := s3.NewFakeServer(testS3Bucket, testS3Region, testS3AccessKeyID, testS3SecretAccessKey)
+	defer server.Close()
+	store := newTestS3SnapshotStorage(t, server, "node1")
+
+	// Discard a snapshot.
+	file, err := store.NewSnapshotFile(1, 1, []byte("configuration"))
+	require.NoError(t, err)
+	_, err = file.Write([]byte("snapshot"))
+	require.NoError(t, err)
+	require.NoError(t, file.Discard())
+	require.Zero(t, server.Uploads())
+	file, err = store.SnapshotFile()
+	require.NoError(t, err)
+	require.Nil(t, file)
+
+	// Leave a snapshot incomplete as though there was a crash.
+	file, err = store.NewSnapshotFile(2, 2, []byte("configuration"))
+	require.NoError(t, err)
+	_, err = file.Write([]byte("snapshot"))
+	require.NoError(t, err)
+	require.Equal(t, 1, server.Uploads())
+	file, err = store.SnapshotFile()
+	require.NoError(t, err)
+	require.Nil(t, file)
+
+	// Another node sharing the bucket has an upload in progress.
+	otherStore := newTestS3SnapshotStorage(t, server, "node2")
+	otherFile, err := otherStore.NewSnapshotFile(1, 1, []byte("configuration"))
+	require.NoError(t, err)
+	_, err = otherFile.Write([]byte("snapshot"))
+	require.NoError(t, err)
+	require.Equal(t, 2, server.Uploads())
+
+	// Creating the storage again should only abort the incomplete upload of this node.
+	newTestS3SnapshotStorage(t, server, "node1")
+	require.Equal(t, 1, server.Uploads())
+
+	// Storage without a prefix must not abort uploads that may belong to other nodes.
+	newTestS3SnapshotStorage(t, server, "")
+	require.Equal(t, 1, server.Uploads())
+	require.NoError(t, otherFile.Discard())
+	require.Zero(t, server.Uploads())
+	require.Empty(t, server.Objects())
+}
+
+// TestS3SnapshotStorageRequestTimeout checks that requests to a bucket that does not respond
+// are cancelled once the request timeout elapses.
+func TestS3SnapshotStorageRequestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	store, err := NewS3SnapshotStorage(
+		server.URL,
+		testS3Bucket,
+		WithS3Credentials(testS3AccessKeyID, testS3SecretAccessKey),
+		WithS3RequestTimeout(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	_, err = store.NewSnapshotFile(1, 1, []byte("configuration"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = store.SnapshotFile()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = NewS3SnapshotStorage(server.URL, testS3Bucket, WithS3RequestTimeout(0))
+	require.Error(t, err)
+}
+
+// TestS3SnapshotStorageUploadFailure checks that a write that fails to upload a part does
+// not report any bytes as written and that the snapshot cannot be completed afterwards.
+func TestS3SnapshotStorageUploadFailure(t *testing.T) {
+	server := s3.NewFakeServer(testS3Bucket, testS3Region, testS3AccessKeyID, testS3SecretAccessKey)
+	defer server.Close()
+	store := newTestS3SnapshotStorage(t, server, "node1")
+
+	file, err := store.NewSnapshotFile(1, 1, []byte("configuration"))
+	require.NoError(t, err)
+	n, err := file.Write([]byte("sna"))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	server.Close()
+	n, err = file.Write([]byte("pshot"))
+	require.Error(t, err)
+	require.Zero(t, n)
+	offset, err := file.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), offset)
+
+	n, err = file.Write([]byte("data"))
+	require.Error(t, err)
+	require.Zero(t, n)
+	require.Error(t, file.Close())
+}
+
+// TestS3SnapshotStorageRestore checks that a new node can restore its state from a
+// snapshot written to the bucket by a node that was lost.
+func TestS3SnapshotStorageRestore(t *testing.T) {
+	server := s3.NewFakeServer(testS3Bucket, testS3Region, testS3AccessKeyID, testS3SecretAccessKey)
+	defer server.Close()
+
+	configuration := NewConfiguration(1, map[string]string{"1": "127.0.0.0:8080"})
+	configurationData, err := encodeConfiguration(configuration)
+	require.NoError(t, err)
+	operations := []Operation{{Bytes: []byte("operation1"), LogIndex: 2, LogTerm: 1}}
+	operationData, err := encodeOperations(operations)
+	require.NoError(t, err)
+
+	// Write a snapshot as the lost node.
+	store := newTestS3SnapshotStorage(t, server, "node1")
+	file, err := store.NewSnapshotFile(2, 1, configurationData)
+	require.NoError(t, err)
+	_, err = file.Write(operationData)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	// Create a new node with an empty data directory using the same snapshot storage.
+	transport, err := newTransportMock("127.0.0.0:8080")
+	require.NoError(t, err)
+	raft, err := NewRaft(
+		"1",
+		"127.0.0.0:8080",
+		newStateMachineMock(false, 0),
+		t.TempDir(),
+		WithTransport(transport),
+		WithSnapshotStorage(newTestS3SnapshotStorage(t, server, "node1")),
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, uint64(2), raft.lastIncludedIndex)
+	require.Equal(t, uint64(1), raft.lastIncludedTerm)
+	require.Equal(t, uint64(2), raft.lastApplied)
+	require.Equal(t, *configuration, *raft.configuration)
+	require.Equal(t, operations, raft.fsm.(*stateMachineMock).appliedOperations())
+}
@@ -1,6 +1,7 @@
 package raft
 
 import (
+	"errors"
 	"os"
 	"strconv"
 	"sync"
@@ -1033,3 +1034,650 @@ func TestReadOnlyDisconnect(t *testing.T) {
 	// Check that read-only operation is successful.
 	cluster.submit(false, LeaseBasedReadOnly, []byte{})
 }
+
+// TestIncrementalSnapshots checks that a cluster whose state machines support incremental
+// snapshots is able to bring servers that fall behind up to date and that servers are able
+// to recover their state from a chain of incremental snapshots after crashing.
+func TestIncrementalSnapshots(t *testing.T) {
+	cluster := newIncrementalCluster(t, 5, 20, 0)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	// Submit some operations so that snapshots are taken.
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(300)
+	cluster.submit(false, Replicated, operations[:50]...)
+
+	var followers []string
+	for _, id := range cluster.nodeIDs() {
+		if id != leader {
+			followers = append(followers, id)
+		}
+	}
+
+	// Disconnect a follower so that it must be sent a snapshot to catch up.
+	cluster.disconnectServer(followers[0])
+	cluster.submit(false, Replicated, operations[50:150]...)
+	cluster.reconnectServer(followers[0])
+	cluster.submit(false, Replicated, operations[150:200]...)
+
+	// Crash a follower so that it must restore its state from its snapshots.
+	cluster.crashServer(followers[1])
+	cluster.submit(false, Replicated, operations[200:250]...)
+	cluster.restartServer(followers[1])
+	cluster.submit(false, Replicated, operations[250:]...)
+
+	cluster.checkStateMachines(5, operations)
+}
+
+// TestPeerSnapshotTransfer checks that servers that fall behind are able to catch up by
+// fetching snapshots from other followers when directed to by the leader.
+func TestPeerSnapshotTransfer(t *testing.T) {
+	cluster := makeCluster(t, 5, true, true, 20, 0, WithPeerSnapshotTransfer(true), WithMaxSnapshotDeltas(0))
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(300)
+	cluster.submit(false, Replicated, operations[:50]...)
+
+	var followers []string
+	for _, id := range cluster.nodeIDs() {
+		if id != leader {
+			followers = append(followers, id)
+		}
+	}
+
+	// Disconnect two followers at once so that they must both be sent snapshots to catch up.
+	cluster.disconnectServer(followers[0])
+	cluster.disconnectServer(followers[1])
+	cluster.submit(false, Replicated, operations[50:200]...)
+	cluster.reconnectServer(followers[0])
+	cluster.reconnectServer(followers[1])
+
+	// Wait for the followers to catch up using the snapshot, then make sure that they are
+	// sent the entries that are submitted after the snapshot was fetched.
+	waitForApplied := func(index uint64) {
+		for _, follower := range followers[:2] {
+			applied := false
+			for start := time.Now(); !applied && time.Since(start) < 10*time.Second; {
+				applied = cluster.nodes[follower].Status().LastApplied >= index
+				time.Sleep(10 * time.Millisecond)
+			}
+			if !applied {
+				t.Fatalf(
+					"follower did not apply entries: ID = %s, lastApplied = %d, expected = %d",
+					follower,
+					cluster.nodes[follower].Status().LastApplied,
+					index,
+				)
+			}
+		}
+	}
+	waitForApplied(cluster.nodes[leader].Status().CommitIndex)
+	cluster.submit(false, Replicated, operations[200:]...)
+	waitForApplied(cluster.nodes[leader].Status().CommitIndex)
+
+	cluster.checkStateMachines(5, operations)
+}
+
+// TestSnapshotBandwidth checks that a server that falls behind is able to catch up when the
+// bandwidth for sending snapshots is limited and that the leader reports the progress of the
+// snapshot being sent.
+func TestSnapshotBandwidth(t *testing.T) {
+	cluster := makeCluster(t, 3, true, true, 20, 0, WithSnapshotBandwidth(1024), WithMaxSnapshotDeltas(0))
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(200)
+
+	var follower string
+	for _, id := range cluster.nodeIDs() {
+		if id != leader {
+			follower = id
+			break
+		}
+	}
+
+	// Disconnect a follower so that it must be sent a snapshot to catch up.
+	cluster.disconnectServer(follower)
+	cluster.submit(false, Replicated, operations[:150]...)
+	cluster.reconnectServer(follower)
+
+	// The snapshot takes several seconds to send, so the leader should report its progress.
+	reported := false
+	for start := time.Now(); !reported && time.Since(start) < 5*time.Second; {
+		transfer, ok := cluster.nodes[leader].Status().SnapshotTransfers[follower]
+		reported = ok && transfer.BytesSent > 0 && transfer.BytesSent < transfer.TotalBytes &&
+			transfer.Rate > 0 && transfer.ETA > 0
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !reported {
+		t.Fatalf("leader did not report progress of snapshot sent to follower: ID = %s", follower)
+	}
+
+	// The snapshot should eventually be received by the follower.
+	sent := false
+	for start := time.Now(); !sent && time.Since(start) < 10*time.Second; {
+		_, ok := cluster.nodes[leader].Status().SnapshotTransfers[follower]
+		sent = !ok && cluster.nodes[follower].Status().LastApplied >= 150
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !sent {
+		t.Fatalf("follower did not receive snapshot: ID = %s", follower)
+	}
+
+	cluster.submit(false, Replicated, operations[150:]...)
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestPipelinedReplication checks that a cluster replicates operations correctly when the
+// leader may have several AppendEntries RPCs outstanding to each follower and RPCs are lost
+// or followers fall behind.
+func TestPipelinedReplication(t *testing.T) {
+	cluster := makeCluster(
+		t,
+		5,
+		snapshotting,
+		false,
+		snapshotSize,
+		20,
+		WithMaxInflightAppendEntries(8),
+	)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(300)
+	cluster.submit(false, Replicated, operations[:100]...)
+
+	var follower string
+	for _, id := range cluster.nodeIDs() {
+		if id != leader {
+			follower = id
+			break
+		}
+	}
+
+	// Disconnect a follower so that the entries sent to it are lost and must be sent again.
+	cluster.disconnectServer(follower)
+	cluster.submit(false, Replicated, operations[100:200]...)
+	cluster.reconnectServer(follower)
+	cluster.submit(false, Replicated, operations[200:]...)
+
+	cluster.checkStateMachines(5, operations)
+}
+
+// TestReplicatorLifecycle checks that the leader runs a replicator for each of the other
+// members of the cluster and that the replicators are stopped when leadership is lost.
+func TestReplicatorLifecycle(t *testing.T) {
+	cluster := makeCluster(t, 3, snapshotting, false, snapshotSize, 0)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	numReplicators := func(id string) int {
+		node := cluster.nodes[id]
+		node.mu.Lock()
+		defer node.mu.Unlock()
+		return len(node.replicators)
+	}
+
+	leader := cluster.checkLeaders(false)
+	if n := numReplicators(leader); n != 2 {
+		t.Fatalf("leader has %d replicators, expected 2", n)
+	}
+
+	// Disconnect the leader so that a new leader is elected.
+	cluster.disconnectServer(leader)
+	newLeader := cluster.checkLeaders(false)
+	if n := numReplicators(newLeader); n != 2 {
+		t.Fatalf("new leader has %d replicators, expected 2", n)
+	}
+
+	// The old leader should stop its replicators once it learns of the new term.
+	cluster.reconnectServer(leader)
+	deadline := time.Now().Add(5 * time.Second)
+	for numReplicators(leader) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("old leader did not stop its replicators")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	operations := makeOperations(100)
+	cluster.submit(false, Replicated, operations...)
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestBoundedAppendEntries checks that a follower that is far behind is able to catch up
+// when the number and size of the entries in each AppendEntries RPC are limited.
+func TestBoundedAppendEntries(t *testing.T) {
+	cluster := makeCluster(
+		t,
+		3,
+		snapshotting,
+		false,
+		snapshotSize,
+		0,
+		WithMaxAppendEntries(8),
+		WithMaxAppendEntriesBytes(64),
+	)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(300)
+	cluster.submit(false, Replicated, operations[:50]...)
+
+	var follower string
+	for _, id := range cluster.nodeIDs() {
+		if id != leader {
+			follower = id
+			break
+		}
+	}
+
+	// Disconnect a follower so that it falls far behind the leader.
+	cluster.disconnectServer(follower)
+	cluster.submit(false, Replicated, operations[50:250]...)
+	cluster.reconnectServer(follower)
+	cluster.submit(false, Replicated, operations[250:]...)
+
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestProposalForwarding checks that operations and membership changes submitted to
+// followers are forwarded to the leader when proposal forwarding is enabled.
+func TestProposalForwarding(t *testing.T) {
+	cluster := makeCluster(t, 3, snapshotting, false, snapshotSize, 0, WithProposalForwarding(true))
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	var follower *Raft
+	for _, id := range cluster.nodeIDs() {
+		if id != leader {
+			follower = cluster.nodes[id]
+			break
+		}
+	}
+
+	// Wait for the follower to learn who the leader is.
+	start := time.Now()
+	for time.Since(start).Seconds() < maxElectionTime {
+		follower.mu.Lock()
+		leaderID := follower.leaderID
+		follower.mu.Unlock()
+		if leaderID == leader {
+			break
+		}
+		time.Sleep(defaultHeartbeat)
+	}
+
+	operations := makeOperations(10)
+	for i, operation := range operations {
+		response := follower.SubmitOperation(operation, Replicated, time.Second).Await()
+		if err := response.Error(); err != nil {
+			t.Fatalf("failed to forward operation: error = %v", err)
+		}
+		result := response.Success()
+		if string(result.Operation.Bytes) != string(operation) {
+			t.Fatal("operation response does not match submitted operation")
+		}
+		if result.Operation.LogIndex == 0 || result.Operation.LogTerm == 0 {
+			t.Fatal("forwarded operation response is missing its log index and term")
+		}
+		if result.ApplicationResponse != i+1 {
+			t.Fatalf(
+				"unexpected response to forwarded operation: expected = %d, actual = %v",
+				i+1,
+				result.ApplicationResponse,
+			)
+		}
+	}
+
+	response := follower.SubmitOperation(nil, LinearizableReadOnly, time.Second).Await()
+	if err := response.Error(); err != nil {
+		t.Fatalf("failed to forward read-only operation: error = %v", err)
+	}
+	if response.Success().ApplicationResponse != len(operations) {
+		t.Fatalf(
+			"unexpected response to forwarded read-only operation: expected = %d, actual = %v",
+			len(operations),
+			response.Success().ApplicationResponse,
+		)
+	}
+
+	cluster.checkStateMachines(3, operations)
+
+	// Add and then remove a non-voter through the follower. Membership changes are retried
+	// until the resulting configuration is returned.
+	id, address := cluster.unusedIDandAddress()
+	start = time.Now()
+	for {
+		response := follower.AddServer(id, address, false, futureTimeout).Await()
+		if err := response.Error(); err == nil {
+			if response.Success().Members[id] != address {
+				t.Fatalf("forwarded membership change did not add node: ID = %s", id)
+			}
+			break
+		}
+		if time.Since(start).Seconds() > maxMembershipChangeTime {
+			t.Fatalf("timed out forwarding membership change: ID = %s", id)
+		}
+	}
+
+	start = time.Now()
+	for {
+		response := follower.RemoveServer(id, futureTimeout).Await()
+		if err := response.Error(); err == nil {
+			if _, ok := response.Success().Members[id]; ok {
+				t.Fatalf("forwarded membership change did not remove node: ID = %s", id)
+			}
+			break
+		}
+		if time.Since(start).Seconds() > maxMembershipChangeTime {
+			t.Fatalf("timed out forwarding membership change: ID = %s", id)
+		}
+	}
+}
+
+// TestRollingUpgrade checks that a cluster continues to make progress while its servers are
+// upgraded one at a time and that the leader only uses the newest protocol version once every
+// server supports it.
+func TestRollingUpgrade(t *testing.T) {
+	cluster := makeCluster(t, 3, true, true, 20, 0, WithMaxProtocolVersion(MinProtocolVersion))
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(200)
+	cluster.submit(false, Replicated, operations[:50]...)
+	if version := cluster.nodes[leader].Status().ProtocolVersion; version != MinProtocolVersion {
+		t.Fatalf("leader is using an unsupported protocol version: version = %d", version)
+	}
+
+	// Upgrade the servers one at a time.
+	cluster.options = nil
+	for i, id := range cluster.nodeIDs() {
+		cluster.crashServer(id)
+		cluster.restartServer(id)
+		cluster.submit(false, Replicated, operations[50+i*50:100+i*50]...)
+	}
+
+	// The leader should use the newest protocol version once every server has been upgraded.
+	upgraded := false
+	for start := time.Now(); !upgraded && time.Since(start) < 5*time.Second; {
+		leader := cluster.checkLeaders(false)
+		upgraded = cluster.nodes[leader].Status().ProtocolVersion == MaxProtocolVersion
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !upgraded {
+		t.Fatalf("leader did not use the newest protocol version after the upgrade")
+	}
+
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestTransferLeadership checks that leadership is transferred to the provided node and that the
+// cluster continues to make progress afterwards.
+func TestTransferLeadership(t *testing.T) {
+	cluster := newCluster(t, 3, snapshotting, snapshotSize, 0)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(200)
+	cluster.submit(false, Replicated, operations[:100]...)
+
+	var target string
+	for _, id := range cluster.nodeIDs() {
+		if id != leader {
+			target = id
+			break
+		}
+	}
+
+	result := cluster.nodes[leader].TransferLeadership(target, defaultElectionTimeout*10).Await()
+	if err := result.Error(); err != nil {
+		t.Fatalf("failed to transfer leadership: error = %v", err)
+	}
+	if result.Success().TargetID != target {
+		t.Fatalf("leadership transferred to %s, expected %s", result.Success().TargetID, target)
+	}
+	if newLeader := cluster.checkLeaders(false); newLeader != target {
+		t.Fatalf("new leader is %s, expected %s", newLeader, target)
+	}
+
+	cluster.submit(false, Replicated, operations[100:]...)
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestTransferLeadershipAnyVoter checks that leadership is transferred to another voting member
+// when no target is provided and that a node that is not the leader may not transfer leadership.
+func TestTransferLeadershipAnyVoter(t *testing.T) {
+	cluster := newCluster(t, 3, snapshotting, snapshotSize, 0)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(100)
+	cluster.submit(false, Replicated, operations...)
+
+	for _, id := range cluster.nodeIDs() {
+		if id == leader {
+			continue
+		}
+		result := cluster.nodes[id].TransferLeadership("", defaultElectionTimeout*10).Await()
+		if !errors.Is(result.Error(), ErrNotLeader) {
+			t.Fatalf("follower transferred leadership: error = %v", result.Error())
+		}
+	}
+
+	result := cluster.nodes[leader].TransferLeadership("", defaultElectionTimeout*10).Await()
+	if err := result.Error(); err != nil {
+		t.Fatalf("failed to transfer leadership: error = %v", err)
+	}
+	if target := result.Success().TargetID; target == leader || target == "" {
+		t.Fatalf("leadership transferred to an invalid node: target = %s", target)
+	}
+	if newLeader := cluster.checkLeaders(false); newLeader == leader {
+		t.Fatalf("leader did not step down after transferring leadership")
+	}
+
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestChangeMembership checks that several nodes, including the leader, can be replaced in a
+// single membership change and that the cluster continues to make progress afterwards.
+func TestChangeMembership(t *testing.T) {
+	cluster := newCluster(t, 3, snapshotting, snapshotSize, 0)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(200)
+	cluster.submit(false, Replicated, operations[:100]...)
+
+	var follower string
+	for _, id := range cluster.nodeIDs() {
+		if id != leader {
+			follower = id
+			break
+		}
+	}
+
+	// Replace the leader and a follower with two new nodes.
+	cluster.changeMembership(2, leader, follower)
+
+	cluster.checkLeaders(false)
+	cluster.submit(false, Replicated, operations[100:]...)
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestChangeMembershipProtocolVersion checks that membership changes are rejected while the
+// cluster is using a protocol version that does not support joint consensus.
+func TestChangeMembershipProtocolVersion(t *testing.T) {
+	cluster := makeCluster(
+		t,
+		3,
+		snapshotting,
+		false,
+		snapshotSize,
+		0,
+		WithMaxProtocolVersion(protocolVersionJointConsensus-1),
+	)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(100)
+	cluster.submit(false, Replicated, operations...)
+
+	changes := []MembershipChange{{ID: "4", Address: "127.0.0.4:8080", IsVoter: true}}
+	future := cluster.nodes[leader].ChangeMembership(changes, futureTimeout)
+	if err := future.Await().Error(); !errors.Is(err, ErrUnsupportedProtocolVersion) {
+		t.Fatalf("membership change was not rejected: error = %v", err)
+	}
+
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestAddLearner checks that nodes added as learners are promoted to voters once they
+// have caught up and that the cluster can be led by them once the original nodes are removed.
+func TestAddLearner(t *testing.T) {
+	cluster := newCluster(t, 3, snapshotting, snapshotSize, 0)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	nodes := cluster.nodeIDs()
+
+	cluster.checkLeaders(false)
+	operations := makeOperations(400)
+	cluster.submit(false, Replicated, operations[:100]...)
+
+	// Add three new nodes that must catch up before they are promoted.
+	for i := 0; i < 3; i++ {
+		id, address := cluster.unusedIDandAddress()
+		cluster.addLearner(id, address)
+		cluster.submit(false, Replicated, operations[100*(i+1):100*(i+2)]...)
+	}
+
+	// Remove the original nodes.
+	for _, node := range nodes {
+		cluster.removeServer(node)
+	}
+
+	cluster.checkLeaders(false)
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestAddLearnerSuperseded checks that adding a node again while it is being caught up fails
+// the request that started catching it up.
+func TestAddLearnerSuperseded(t *testing.T) {
+	cluster := newCluster(t, 3, snapshotting, snapshotSize, 0)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(100)
+	cluster.submit(false, Replicated, operations...)
+
+	// The node is not running, so it never catches up.
+	id, address := cluster.unusedIDandAddress()
+	first := cluster.nodes[leader].AddLearner(id, address, 5*time.Second)
+
+	// Add the node again once it has been added as a non-voter.
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		err := cluster.nodes[leader].AddLearner(id, address, futureTimeout).Await().Error()
+		if !errors.Is(err, ErrPendingConfiguration) {
+			break
+		}
+		if time.Since(start).Seconds() > maxMembershipChangeTime {
+			t.Fatalf("timed out trying to add a learner: ID = %s", id)
+		}
+	}
+
+	if err := first.Await().Error(); !errors.Is(err, ErrCatchUpSuperseded) {
+		t.Fatalf("request to add learner was not superseded: error = %v", err)
+	}
+
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestAddLearnerProtocolVersion checks that nodes are not added as learners while the cluster is
+// using a protocol version that does not support promoting them.
+func TestAddLearnerProtocolVersion(t *testing.T) {
+	cluster := makeCluster(
+		t,
+		3,
+		snapshotting,
+		false,
+		snapshotSize,
+		0,
+		WithMaxProtocolVersion(protocolVersionLearnerPromotion-1),
+	)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	operations := makeOperations(100)
+	cluster.submit(false, Replicated, operations...)
+
+	future := cluster.nodes[leader].AddLearner("4", "127.0.0.4:8080", futureTimeout)
+	if err := future.Await().Error(); !errors.Is(err, ErrUnsupportedProtocolVersion) {
+		t.Fatalf("request to add learner was not rejected: error = %v", err)
+	}
+
+	cluster.checkStateMachines(3, operations)
+}
+
+// TestDisconnectedLeaderStepsDown checks that a leader that is disconnected from the rest of the
+// cluster steps down and that operations submitted to it fail with ErrNotLeader.
+func TestDisconnectedLeaderStepsDown(t *testing.T) {
+	cluster := newCluster(t, 3, snapshotting, snapshotSize, 0)
+
+	cluster.startCluster()
+	defer cluster.stopCluster()
+
+	leader := cluster.checkLeaders(false)
+	cluster.disconnectServer(leader)
+
+	// The disconnected leader should step down once it has not heard from the
+	// other nodes for the election timeout.
+	node := cluster.nodes[leader]
+	steppedDown := false
+	for start := time.Now(); !steppedDown && time.Since(start) < 5*time.Second; {
+		steppedDown = node.Status().State != Leader
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !steppedDown {
+		t.Fatalf("disconnected leader did not step down: ID = %s", leader)
+	}
+
+	future := node.SubmitOperation([]byte("operation"), Replicated, futureTimeout)
+	if err := future.Await().Error(); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("operation submitted to disconnected node did not fail with ErrNotLeader: error = %v", err)
+	}
+
+	// The rest of the cluster should still make progress.
+	operations := makeOperations(20)
+	cluster.submit(false, Replicated, operations...)
+	cluster.checkStateMachines(2, operations)
+}
@@ -2,6 +2,7 @@ package raft
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -29,6 +30,10 @@ type SnapshotMetadata struct {
 
 	// The most up-to-date configuration in the snapshot.
 	Configuration []byte `json:"configuration"`
+
+	// The last included index of the snapshot that this snapshot is an incremental
+	// snapshot of. Zero if this is a full snapshot.
+	BaseIndex uint64 `json:"base_index,omitempty"`
 }
 
 func encodeMetadata(w io.Writer, metadata *SnapshotMetadata) error {
@@ -82,6 +87,73 @@ type SnapshotStorage interface {
 	SnapshotFile() (SnapshotFile, error)
 }
 
+// IncrementalSnapshotStorage is an optional extension of SnapshotStorage for storages
+// that are capable of storing incremental snapshots. An incremental snapshot only contains
+// the changes made since another snapshot, which is referred to as its base.
+type IncrementalSnapshotStorage interface {
+	SnapshotStorage
+
+	// NewIncrementalSnapshotFile creates a new snapshot file for an incremental snapshot
+	// of the snapshot with the provided base index. It is the caller's responsibility to
+	// close the file or discard it when they are done with it.
+	NewIncrementalSnapshotFile(
+		lastIncludedIndex uint64,
+		lastIncludedTerm uint64,
+		baseIndex uint64,
+		configuration []byte,
+	) (SnapshotFile, error)
+
+	// SnapshotChain returns the snapshot files needed to restore the snapshot with the
+	// provided last included index: a full snapshot followed by each incremental snapshot
+	// built on top of it, in order. If there is no such snapshot or any snapshot in the
+	// chain is missing, nil is returned. It is the caller's responsibility to close the
+	// files when they are done with them.
+	SnapshotChain(lastIncludedIndex uint64) ([]SnapshotFile, error)
+}
+
+// snapshotChain follows the base indices of the provided snapshot metadata starting at the
+// snapshot with the provided last included index. It returns the keys of the snapshots in the
+// chain, starting with the full snapshot, or nil if the chain is incomplete. The provided maps
+// map last included index to key and key to metadata.
+func snapshotChain(
+	lastIncludedIndex uint64,
+	keys map[uint64]string,
+	metadata map[string]SnapshotMetadata,
+) []string {
+	var chain []string
+	for index := lastIncludedIndex; ; {
+		key, ok := keys[index]
+		if !ok {
+			return nil
+		}
+		chain = append(chain, key)
+		baseIndex := metadata[key].BaseIndex
+		if baseIndex == 0 {
+			break
+		}
+		// A snapshot must always be based on an older one.
+		if baseIndex >= index {
+			return nil
+		}
+		index = baseIndex
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// closeSnapshotFiles closes all of the provided snapshot files.
+func closeSnapshotFiles(files []SnapshotFile) error {
+	var errs []error
+	for _, file := range files {
+		if err := file.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // snapshotFile implements the SnapshotFile interface.
 type snapshotFile struct {
 	io.ReadWriteSeeker
@@ -172,7 +244,8 @@ type persistentSnapshotStorage struct {
 // that is created will have its own directory that is named using
 // a timestamp taken at the time of its creation. Each of these
 // directories will contain two separate files - one for the content of
-// the snapshot and one for its metadata.
+// the snapshot and one for its metadata. The returned storage also
+// implements IncrementalSnapshotStorage.
 func NewSnapshotStorage(path string) (SnapshotStorage, error) {
 	snapshotPath := filepath.Join(path, snapshotDirBase)
 	if err := os.MkdirAll(snapshotPath, os.ModePerm); err != nil {
@@ -189,6 +262,18 @@ func NewSnapshotStorage(path string) (SnapshotStorage, error) {
 
 func (p *persistentSnapshotStorage) NewSnapshotFile(
 	lastIncludedIndex uint64, lastIncludedTerm uint64, configuration []byte,
+) (SnapshotFile, error) {
+	return p.newSnapshotFile(lastIncludedIndex, lastIncludedTerm, 0, configuration)
+}
+
+func (p *persistentSnapshotStorage) NewIncrementalSnapshotFile(
+	lastIncludedIndex uint64, lastIncludedTerm uint64, baseIndex uint64, configuration []byte,
+) (SnapshotFile, error) {
+	return p.newSnapshotFile(lastIncludedIndex, lastIncludedTerm, baseIndex, configuration)
+}
+
+func (p *persistentSnapshotStorage) newSnapshotFile(
+	lastIncludedIndex uint64, lastIncludedTerm uint64, baseIndex uint64, configuration []byte,
 ) (SnapshotFile, error) {
 	// The temporary directory that will contain the snapshot and its metadata.
 	// This directory will be renamed once the snapshot has been safely written to disk.
@@ -212,6 +297,7 @@ func (p *persistentSnapshotStorage) NewSnapshotFile(
 		LastIncludedIndex: lastIncludedIndex,
 		LastIncludedTerm:  lastIncludedTerm,
 		Configuration:     configuration,
+		BaseIndex:         baseIndex,
 	}
 	if err := encodeMetadata(metadataFile, &metadata); err != nil {
 		return nil, fmt.Errorf("could not encode snapshot metadata: %w", err)
@@ -243,22 +329,71 @@ func (p *persistentSnapshotStorage) SnapshotFile() (SnapshotFile, error) {
 	}
 	dirName := dirNames[len(dirNames)-1]
 
-	// Make the file containing the snapshot data prepared for reading.
-	dataFile, err := os.Open(filepath.Join(dirName, snapshotBase))
+	metadata, err := p.metadata(dirName)
 	if err != nil {
-		return nil, fmt.Errorf("could not open snapshot data file: %w", err)
+		return nil, err
+	}
+
+	return p.open(dirName, metadata)
+}
+
+func (p *persistentSnapshotStorage) SnapshotChain(lastIncludedIndex uint64) ([]SnapshotFile, error) {
+	dirNames, err := p.directories()
+	if err != nil {
+		return nil, err
 	}
 
-	// Read the metadata from the metadata file.
+	// Map each last included index to the most recent snapshot with that index.
+	dirs := make(map[uint64]string, len(dirNames))
+	metadata := make(map[string]SnapshotMetadata, len(dirNames))
+	for _, dirName := range dirNames {
+		dirMetadata, err := p.metadata(dirName)
+		if err != nil {
+			return nil, err
+		}
+		dirs[dirMetadata.LastIncludedIndex] = dirName
+		metadata[dirName] = dirMetadata
+	}
+
+	chain := snapshotChain(lastIncludedIndex, dirs, metadata)
+	if chain == nil {
+		return nil, nil
+	}
+
+	files := make([]SnapshotFile, 0, len(chain))
+	for _, dirName := range chain {
+		file, err := p.open(dirName, metadata[dirName])
+		if err != nil {
+			_ = closeSnapshotFiles(files)
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// metadata reads the metadata of the snapshot in the provided directory.
+func (p *persistentSnapshotStorage) metadata(dirName string) (SnapshotMetadata, error) {
 	metadataFile, err := os.Open(filepath.Join(dirName, metadataBase))
 	if err != nil {
-		return nil, fmt.Errorf("could not open snapshot metadata file: %w", err)
+		return SnapshotMetadata{}, fmt.Errorf("could not open snapshot metadata file: %w", err)
 	}
+	defer metadataFile.Close()
 	metadata, err := decodeMetadata(metadataFile)
 	if err != nil {
-		return nil, fmt.Errorf("could not decode snapshot metadata: %w", err)
+		return SnapshotMetadata{}, fmt.Errorf("could not decode snapshot metadata: %w", err)
 	}
+	return metadata, nil
+}
 
+// open makes the file containing the data of the snapshot in the provided directory
+// prepared for reading.
+func (p *persistentSnapshotStorage) open(dirName string, metadata SnapshotMetadata) (SnapshotFile, error) {
+	dataFile, err := os.Open(filepath.Join(dirName, snapshotBase))
+	if err != nil {
+		return nil, fmt.Errorf("could not open snapshot data file: %w", err)
+	}
 	return &snapshotFile{
 		ReadWriteSeeker: dataFile,
 		file:            dataFile,
@@ -62,3 +62,82 @@ func TestSnapshotStorageWriterReader(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, string(data2), buf.String())
 }
+
+// writeSnapshot writes a snapshot with the provided data to the storage. If the base index is not
+// zero, the snapshot is an incremental snapshot.
+func writeSnapshot(
+	t *testing.T,
+	store IncrementalSnapshotStorage,
+	lastIncludedIndex uint64,
+	baseIndex uint64,
+	data string,
+) {
+	var file SnapshotFile
+	var err error
+	if baseIndex == 0 {
+		file, err = store.NewSnapshotFile(lastIncludedIndex, 1, []byte("configuration"))
+	} else {
+		file, err = store.NewIncrementalSnapshotFile(lastIncludedIndex, 1, baseIndex, []byte("configuration"))
+	}
+	require.NoError(t, err)
+	_, err = file.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
+
+// checkSnapshotChain checks that the chain of the snapshot with the provided last
+// included index contains the provided data in order.
+func checkSnapshotChain(
+	t *testing.T,
+	store IncrementalSnapshotStorage,
+	lastIncludedIndex uint64,
+	expected ...string,
+) {
+	chain, err := store.SnapshotChain(lastIncludedIndex)
+	require.NoError(t, err)
+	require.Len(t, chain, len(expected))
+	for i, file := range chain {
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, file)
+		require.NoError(t, err)
+		require.Equal(t, expected[i], buf.String())
+		require.NoError(t, file.Close())
+	}
+}
+
+// testSnapshotStorageChain checks that chains of incremental snapshots are
+// correctly stored and retrieved by the provided storage.
+func testSnapshotStorageChain(t *testing.T, store IncrementalSnapshotStorage) {
+	// Write a full snapshot followed by two incremental snapshots.
+	writeSnapshot(t, store, 2, 0, "full")
+	writeSnapshot(t, store, 4, 2, "delta1")
+	writeSnapshot(t, store, 6, 4, "delta2")
+
+	// The most recent snapshot should be the last incremental snapshot.
+	file, err := store.SnapshotFile()
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), file.Metadata().LastIncludedIndex)
+	require.Equal(t, uint64(4), file.Metadata().BaseIndex)
+	require.NoError(t, file.Close())
+
+	checkSnapshotChain(t, store, 2, "full")
+	checkSnapshotChain(t, store, 4, "full", "delta1")
+	checkSnapshotChain(t, store, 6, "full", "delta1", "delta2")
+
+	// There is no snapshot with this index.
+	checkSnapshotChain(t, store, 5)
+
+	// An incremental snapshot whose base is missing does not have a chain.
+	writeSnapshot(t, store, 9, 8, "delta3")
+	checkSnapshotChain(t, store, 9)
+
+	// A full snapshot with the same index replaces the incremental snapshot.
+	writeSnapshot(t, store, 6, 0, "full2")
+	checkSnapshotChain(t, store, 6, "full2")
+}
+
+func TestSnapshotStorageChain(t *testing.T) {
+	store, err := NewSnapshotStorage(t.TempDir())
+	require.NoError(t, err)
+	testSnapshotStorageChain(t, store.(IncrementalSnapshotStorage))
+}
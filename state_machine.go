@@ -20,3 +20,50 @@ type StateMachine interface {
 	// otherwise. The provided log size is the number of entries currently in the log.
 	NeedSnapshot(logSize int) bool
 }
+
+// IncrementalStateMachine is an optional extension of StateMachine for state machines that are
+// able to take incremental snapshots. An incremental snapshot only contains the changes made to
+// the state machine since a previous snapshot, which is referred to as its base. Taking and
+// transferring an incremental snapshot is usually much cheaper than doing so for a full snapshot
+// when the state machine is large.
+type IncrementalStateMachine interface {
+	StateMachine
+
+	// SnapshotDelta writes the changes made to the state machine since the snapshot with the
+	// provided last included index using the provided writer.
+	SnapshotDelta(baseIndex uint64, snapshotWriter io.Writer) error
+
+	// RestoreDelta applies the changes contained in an incremental snapshot to the state machine
+	// given a reader for the snapshot. The state machine will have already been restored to the
+	// base of the snapshot.
+	RestoreDelta(snapshotReader io.Reader) error
+}
+
+// PersistentStateMachine is an optional extension of StateMachine for state machines that persist
+// their own state, such as those backed by an embedded database. Such a state machine does not
+// need to be restored from a snapshot when the node restarts, and operations that it has already
+// applied must not be applied to it again.
+type PersistentStateMachine interface {
+	StateMachine
+
+	// AppliedIndex returns the log index of the last operation that has been durably applied
+	// to the state machine. Zero should be returned if no operations have been applied. When
+	// the state machine is restored from a snapshot, the applied index is expected to become
+	// the last index included in the snapshot.
+	AppliedIndex() (uint64, error)
+}
+
+// ForwardingStateMachine is an optional extension of StateMachine for state machines whose responses
+// may be sent between nodes. It is required when proposal forwarding is enabled, since the response
+// to an operation forwarded from a follower is produced by the leader and must be returned to the
+// follower that the operation was submitted to.
+type ForwardingStateMachine interface {
+	StateMachine
+
+	// EncodeResponse encodes a response returned by Apply such that it can be decoded by
+	// DecodeResponse.
+	EncodeResponse(response interface{}) ([]byte, error)
+
+	// DecodeResponse decodes a response that was encoded by EncodeResponse.
+	DecodeResponse(data []byte) (interface{}, error)
+}
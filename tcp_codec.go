@@ -0,0 +1,534 @@
+package raft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The kinds of frames exchanged by TCP transports.
+const (
+	tcpAppendEntries byte = iota + 1
+	tcpRequestVote
+	tcpInstallSnapshot
+	tcpFetchSnapshot
+	tcpForwardOperation
+	tcpForwardMembershipChange
+
+	// A frame that abandons the request with the same ID.
+	tcpCancel
+
+	// A frame that contains the response to the request with the same ID.
+	tcpResponse
+
+	// A frame that contains the error returned for the request with the same ID.
+	tcpError
+
+	// Kinds added later are appended so that the values of existing kinds do not change.
+	tcpTimeoutNow
+)
+
+const (
+	// The size of a frame header: a 4 byte length, a 1 byte kind, and an 8 byte ID.
+	tcpFrameHeaderSize = 13
+
+	// The maximum size of a frame payload.
+	maxTCPFramePayloadSize = 64 * 1024 * 1024
+)
+
+// errMalformedFrame is returned when a frame payload cannot be decoded.
+var errMalformedFrame = errors.New("malformed frame")
+
+// tcpFrame is the unit of data exchanged by TCP transports. Each frame is prefixed with
+// the length of its payload so that frames for concurrent requests may share a connection.
+type tcpFrame struct {
+	// The kind of the frame.
+	kind byte
+
+	// The ID of the request that the frame belongs to.
+	id uint64
+
+	// The encoded request, response, or error.
+	payload []byte
+}
+
+// writeFrame writes the provided frame to the provided writer in a single write.
+func writeFrame(w io.Writer, frame tcpFrame) error {
+	if len(frame.payload) > maxTCPFramePayloadSize {
+		return fmt.Errorf("frame payload is too large: size = %d", len(frame.payload))
+	}
+	buf := make([]byte, tcpFrameHeaderSize, tcpFrameHeaderSize+len(frame.payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(frame.payload)))
+	buf[4] = frame.kind
+	binary.BigEndian.PutUint64(buf[5:13], frame.id)
+	buf = append(buf, frame.payload...)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFrame reads a frame from the provided reader.
+func readFrame(r io.Reader) (tcpFrame, error) {
+	var header [tcpFrameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return tcpFrame{}, err
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxTCPFramePayloadSize {
+		return tcpFrame{}, fmt.Errorf("frame payload is too large: size = %d", size)
+	}
+	frame := tcpFrame{kind: header[4], id: binary.BigEndian.Uint64(header[5:13])}
+	if size > 0 {
+		frame.payload = make([]byte, size)
+		if _, err := io.ReadFull(r, frame.payload); err != nil {
+			return tcpFrame{}, err
+		}
+	}
+	return frame, nil
+}
+
+// binaryEncoder appends values to a buffer using variable length integers.
+type binaryEncoder struct {
+	buf []byte
+}
+
+func (e *binaryEncoder) writeUint64(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *binaryEncoder) writeInt64(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *binaryEncoder) writeBool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *binaryEncoder) writeBytes(v []byte) {
+	e.writeUint64(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *binaryEncoder) writeString(v string) {
+	e.writeUint64(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// binaryDecoder reads values written by a binaryEncoder. The first error encountered is
+// recorded and all subsequent reads return zero values. Decoded byte slices share memory
+// with the buffer being decoded.
+type binaryDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *binaryDecoder) readUint64() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errMalformedFrame
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *binaryDecoder) readInt64() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errMalformedFrame
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *binaryDecoder) readBool() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.buf) == 0 || d.buf[0] > 1 {
+		d.err = errMalformedFrame
+		return false
+	}
+	v := d.buf[0] == 1
+	d.buf = d.buf[1:]
+	return v
+}
+
+func (d *binaryDecoder) readBytes() []byte {
+	size := d.readUint64()
+	if d.err != nil {
+		return nil
+	}
+	if size > uint64(len(d.buf)) {
+		d.err = errMalformedFrame
+		return nil
+	}
+	if size == 0 {
+		return nil
+	}
+	v := d.buf[:size:size]
+	d.buf = d.buf[size:]
+	return v
+}
+
+func (d *binaryDecoder) readString() string {
+	return string(d.readBytes())
+}
+
+// finish returns the first error encountered while decoding, or an error if
+// there is data left over.
+func (d *binaryDecoder) finish() error {
+	if d.err == nil && len(d.buf) != 0 {
+		d.err = errMalformedFrame
+	}
+	return d.err
+}
+
+// encodeBinaryLogEntry encodes the provided log entry. The offset of the entry is
+// local to the log that it is stored in, so it is not encoded.
+func encodeBinaryLogEntry(e *binaryEncoder, entry *LogEntry) {
+	e.writeUint64(entry.Index)
+	e.writeUint64(entry.Term)
+	e.writeBytes(entry.Data)
+	e.writeUint64(uint64(entry.EntryType))
+}
+
+func decodeBinaryLogEntry(d *binaryDecoder) *LogEntry {
+	return &LogEntry{
+		Index:     d.readUint64(),
+		Term:      d.readUint64(),
+		Data:      d.readBytes(),
+		EntryType: LogEntryType(d.readUint64()),
+	}
+}
+
+func encodeNotLeader(e *binaryEncoder, notLeader *NotLeaderError) {
+	e.writeBool(notLeader != nil)
+	if notLeader == nil {
+		return
+	}
+	e.writeString(notLeader.LeaderID)
+	e.writeString(notLeader.LeaderAddress)
+	e.writeUint64(notLeader.Term)
+}
+
+func decodeNotLeader(d *binaryDecoder) *NotLeaderError {
+	if !d.readBool() {
+		return nil
+	}
+	return &NotLeaderError{
+		LeaderID:      d.readString(),
+		LeaderAddress: d.readString(),
+		Term:          d.readUint64(),
+	}
+}
+
+func encodeMembershipChange(e *binaryEncoder, change *MembershipChange) {
+	e.writeString(change.ID)
+	e.writeString(change.Address)
+	e.writeBool(change.IsVoter)
+	e.writeBool(change.Remove)
+}
+
+func decodeMembershipChange(d *binaryDecoder) MembershipChange {
+	return MembershipChange{
+		ID:      d.readString(),
+		Address: d.readString(),
+		IsVoter: d.readBool(),
+		Remove:  d.readBool(),
+	}
+}
+
+func encodeAppendEntriesRequest(e *binaryEncoder, request *AppendEntriesRequest) {
+	e.writeString(request.LeaderID)
+	e.writeUint64(request.Term)
+	e.writeUint64(request.LeaderCommit)
+	e.writeUint64(request.PrevLogIndex)
+	e.writeUint64(request.PrevLogTerm)
+	e.writeUint64(uint64(len(request.Entries)))
+	for _, entry := range request.Entries {
+		encodeBinaryLogEntry(e, entry)
+	}
+	e.writeUint64(uint64(request.ProtocolVersion))
+	e.writeUint64(uint64(request.MinProtocolVersion))
+	e.writeUint64(uint64(request.MaxProtocolVersion))
+}
+
+func decodeAppendEntriesRequest(d *binaryDecoder, request *AppendEntriesRequest) {
+	request.LeaderID = d.readString()
+	request.Term = d.readUint64()
+	request.LeaderCommit = d.readUint64()
+	request.PrevLogIndex = d.readUint64()
+	request.PrevLogTerm = d.readUint64()
+	numEntries := d.readUint64()
+	// Each entry occupies at least one byte per field, which bounds the allocation.
+	if numEntries > uint64(len(d.buf)) {
+		d.err = errMalformedFrame
+		return
+	}
+	if numEntries > 0 {
+		request.Entries = make([]*LogEntry, numEntries)
+		for i := range request.Entries {
+			request.Entries[i] = decodeBinaryLogEntry(d)
+		}
+	}
+	request.ProtocolVersion = uint32(d.readUint64())
+	request.MinProtocolVersion = uint32(d.readUint64())
+	request.MaxProtocolVersion = uint32(d.readUint64())
+}
+
+func encodeAppendEntriesResponse(e *binaryEncoder, response *AppendEntriesResponse) {
+	e.writeUint64(response.Term)
+	e.writeBool(response.Success)
+	e.writeUint64(response.Index)
+	e.writeUint64(uint64(response.MinProtocolVersion))
+	e.writeUint64(uint64(response.MaxProtocolVersion))
+}
+
+func decodeAppendEntriesResponse(d *binaryDecoder, response *AppendEntriesResponse) {
+	response.Term = d.readUint64()
+	response.Success = d.readBool()
+	response.Index = d.readUint64()
+	response.MinProtocolVersion = uint32(d.readUint64())
+	response.MaxProtocolVersion = uint32(d.readUint64())
+}
+
+func encodeRequestVoteRequest(e *binaryEncoder, request *RequestVoteRequest) {
+	e.writeString(request.CandidateID)
+	e.writeUint64(request.Term)
+	e.writeUint64(request.LastLogIndex)
+	e.writeUint64(request.LastLogTerm)
+	e.writeBool(request.Prevote)
+	e.writeUint64(uint64(request.MinProtocolVersion))
+	e.writeUint64(uint64(request.MaxProtocolVersion))
+	e.writeBool(request.LeadershipTransfer)
+}
+
+func decodeRequestVoteRequest(d *binaryDecoder, request *RequestVoteRequest) {
+	request.CandidateID = d.readString()
+	request.Term = d.readUint64()
+	request.LastLogIndex = d.readUint64()
+	request.LastLogTerm = d.readUint64()
+	request.Prevote = d.readBool()
+	request.MinProtocolVersion = uint32(d.readUint64())
+	request.MaxProtocolVersion = uint32(d.readUint64())
+	request.LeadershipTransfer = d.readBool()
+}
+
+func encodeRequestVoteResponse(e *binaryEncoder, response *RequestVoteResponse) {
+	e.writeUint64(response.Term)
+	e.writeBool(response.VoteGranted)
+	e.writeUint64(uint64(response.MinProtocolVersion))
+	e.writeUint64(uint64(response.MaxProtocolVersion))
+}
+
+func decodeRequestVoteResponse(d *binaryDecoder, response *RequestVoteResponse) {
+	response.Term = d.readUint64()
+	response.VoteGranted = d.readBool()
+	response.MinProtocolVersion = uint32(d.readUint64())
+	response.MaxProtocolVersion = uint32(d.readUint64())
+}
+
+func encodeInstallSnapshotRequest(e *binaryEncoder, request *InstallSnapshotRequest) {
+	e.writeString(request.LeaderID)
+	e.writeUint64(request.Term)
+	e.writeUint64(request.LastIncludedIndex)
+	e.writeUint64(request.LastIncludedTerm)
+	e.writeBytes(request.Configuration)
+	e.writeBytes(request.Bytes)
+	e.writeInt64(request.Offset)
+	e.writeBool(request.Done)
+	e.writeUint64(request.BaseIndex)
+	e.writeString(request.SourceID)
+	e.writeString(request.SourceAddress)
+	e.writeUint64(uint64(request.MinProtocolVersion))
+	e.writeUint64(uint64(request.MaxProtocolVersion))
+}
+
+func decodeInstallSnapshotRequest(d *binaryDecoder, request *InstallSnapshotRequest) {
+	request.LeaderID = d.readString()
+	request.Term = d.readUint64()
+	request.LastIncludedIndex = d.readUint64()
+	request.LastIncludedTerm = d.readUint64()
+	request.Configuration = d.readBytes()
+	request.Bytes = d.readBytes()
+	request.Offset = d.readInt64()
+	request.Done = d.readBool()
+	request.BaseIndex = d.readUint64()
+	request.SourceID = d.readString()
+	request.SourceAddress = d.readString()
+	request.MinProtocolVersion = uint32(d.readUint64())
+	request.MaxProtocolVersion = uint32(d.readUint64())
+}
+
+func encodeInstallSnapshotResponse(e *binaryEncoder, response *InstallSnapshotResponse) {
+	e.writeUint64(response.Term)
+	e.writeInt64(response.BytesWritten)
+	e.writeBool(response.MissingBase)
+	e.writeBool(response.FetchFailed)
+	e.writeUint64(uint64(response.MinProtocolVersion))
+	e.writeUint64(uint64(response.MaxProtocolVersion))
+	e.writeUint64(response.LastIncludedIndex)
+}
+
+func decodeInstallSnapshotResponse(d *binaryDecoder, response *InstallSnapshotResponse) {
+	response.Term = d.readUint64()
+	response.BytesWritten = d.readInt64()
+	response.MissingBase = d.readBool()
+	response.FetchFailed = d.readBool()
+	response.MinProtocolVersion = uint32(d.readUint64())
+	response.MaxProtocolVersion = uint32(d.readUint64())
+	response.LastIncludedIndex = d.readUint64()
+}
+
+func encodeFetchSnapshotRequest(e *binaryEncoder, request *FetchSnapshotRequest) {
+	e.writeUint64(request.MinIndex)
+	e.writeUint64(request.LastIncludedIndex)
+	e.writeInt64(request.Offset)
+}
+
+func decodeFetchSnapshotRequest(d *binaryDecoder, request *FetchSnapshotRequest) {
+	request.MinIndex = d.readUint64()
+	request.LastIncludedIndex = d.readUint64()
+	request.Offset = d.readInt64()
+}
+
+func encodeFetchSnapshotResponse(e *binaryEncoder, response *FetchSnapshotResponse) {
+	e.writeUint64(response.LastIncludedIndex)
+	e.writeUint64(response.LastIncludedTerm)
+	e.writeUint64(response.BaseIndex)
+	e.writeBytes(response.Configuration)
+	e.writeBytes(response.Bytes)
+	e.writeBool(response.Done)
+	e.writeBool(response.Unavailable)
+}
+
+func decodeFetchSnapshotResponse(d *binaryDecoder, response *FetchSnapshotResponse) {
+	response.LastIncludedIndex = d.readUint64()
+	response.LastIncludedTerm = d.readUint64()
+	response.BaseIndex = d.readUint64()
+	response.Configuration = d.readBytes()
+	response.Bytes = d.readBytes()
+	response.Done = d.readBool()
+	response.Unavailable = d.readBool()
+}
+
+func encodeTimeoutNowRequest(e *binaryEncoder, request *TimeoutNowRequest) {
+	e.writeUint64(request.Term)
+	e.writeString(request.LeaderID)
+}
+
+func decodeTimeoutNowRequest(d *binaryDecoder, request *TimeoutNowRequest) {
+	request.Term = d.readUint64()
+	request.LeaderID = d.readString()
+}
+
+func encodeTimeoutNowResponse(e *binaryEncoder, response *TimeoutNowResponse) {
+	e.writeUint64(response.Term)
+}
+
+func decodeTimeoutNowResponse(d *binaryDecoder, response *TimeoutNowResponse) {
+	response.Term = d.readUint64()
+}
+
+func encodeForwardOperationRequest(e *binaryEncoder, request *ForwardOperationRequest) {
+	e.writeBytes(request.Operation)
+	e.writeUint64(uint64(request.OperationType))
+	e.writeInt64(int64(request.Timeout))
+}
+
+func decodeForwardOperationRequest(d *binaryDecoder, request *ForwardOperationRequest) {
+	request.Operation = d.readBytes()
+	request.OperationType = OperationType(d.readUint64())
+	request.Timeout = time.Duration(d.readInt64())
+}
+
+func encodeForwardOperationResponse(e *binaryEncoder, response *ForwardOperationResponse) {
+	e.writeUint64(response.LogIndex)
+	e.writeUint64(response.LogTerm)
+	e.writeBytes(response.ApplicationResponse)
+	e.writeString(response.Error)
+	e.writeUint64(uint64(response.ErrorCode))
+	encodeNotLeader(e, response.NotLeader)
+}
+
+func decodeForwardOperationResponse(d *binaryDecoder, response *ForwardOperationResponse) {
+	response.LogIndex = d.readUint64()
+	response.LogTerm = d.readUint64()
+	response.ApplicationResponse = d.readBytes()
+	response.Error = d.readString()
+	response.ErrorCode = uint32(d.readUint64())
+	response.NotLeader = decodeNotLeader(d)
+}
+
+func encodeForwardMembershipChangeRequest(
+	e *binaryEncoder,
+	request *ForwardMembershipChangeRequest,
+) {
+	e.writeString(request.ID)
+	e.writeString(request.Address)
+	e.writeBool(request.IsVoter)
+	e.writeBool(request.Remove)
+	e.writeBool(request.Promote)
+	e.writeInt64(int64(request.Timeout))
+	e.writeUint64(uint64(len(request.Changes)))
+	for i := range request.Changes {
+		encodeMembershipChange(e, &request.Changes[i])
+	}
+}
+
+func decodeForwardMembershipChangeRequest(
+	d *binaryDecoder,
+	request *ForwardMembershipChangeRequest,
+) {
+	request.ID = d.readString()
+	request.Address = d.readString()
+	request.IsVoter = d.readBool()
+	request.Remove = d.readBool()
+	request.Promote = d.readBool()
+	request.Timeout = time.Duration(d.readInt64())
+	numChanges := d.readUint64()
+	// Each change occupies at least one byte per field, which bounds the allocation.
+	if numChanges > uint64(len(d.buf)) {
+		d.err = errMalformedFrame
+		return
+	}
+	if numChanges > 0 {
+		request.Changes = make([]MembershipChange, numChanges)
+		for i := range request.Changes {
+			request.Changes[i] = decodeMembershipChange(d)
+		}
+	}
+}
+
+func encodeForwardMembershipChangeResponse(
+	e *binaryEncoder,
+	response *ForwardMembershipChangeResponse,
+) {
+	e.writeBytes(response.Configuration)
+	e.writeString(response.Error)
+	e.writeUint64(uint64(response.ErrorCode))
+	encodeNotLeader(e, response.NotLeader)
+}
+
+func decodeForwardMembershipChangeResponse(
+	d *binaryDecoder,
+	response *ForwardMembershipChangeResponse,
+) {
+	response.Configuration = d.readBytes()
+	response.Error = d.readString()
+	response.ErrorCode = uint32(d.readUint64())
+	response.NotLeader = decodeNotLeader(d)
+}
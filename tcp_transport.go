@@ -0,0 +1,755 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// tcpPeer is a persistent connection to a peer over which the requests of concurrent RPCs
+// are multiplexed. Each request is assigned an ID that is echoed by the frame containing
+// its response. This implementation is concurrent safe.
+type tcpPeer struct {
+	// The connection to the peer.
+	conn net.Conn
+
+	// The ID assigned to the next request.
+	nextID uint64
+
+	// The requests awaiting a response. Maps request ID to the channel the response
+	// frame is delivered on. The channel is closed if the connection fails.
+	pending map[uint64]chan tcpFrame
+
+	// The error that caused the connection to fail, if it has failed.
+	err error
+
+	// Serializes writes to the connection so that frames are not interleaved.
+	writeMu sync.Mutex
+
+	mu sync.Mutex
+}
+
+// call sends a request frame of the provided kind to the peer and waits for its response.
+// If the provided context is done before the response is received, the request is abandoned
+// and the peer is notified so that it may cancel the handling of the request.
+func (p *tcpPeer) call(ctx context.Context, kind byte, payload []byte) ([]byte, error) {
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return nil, p.err
+	}
+	p.nextID++
+	id := p.nextID
+	responseCh := make(chan tcpFrame, 1)
+	p.pending[id] = responseCh
+	p.mu.Unlock()
+
+	if err := p.write(ctx, tcpFrame{kind: kind, id: id, payload: payload}); err != nil {
+		p.abandon(id)
+		return nil, err
+	}
+
+	select {
+	case frame, ok := <-responseCh:
+		if !ok {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return nil, p.err
+		}
+		if frame.kind == tcpError {
+			return nil, errors.New(string(frame.payload))
+		}
+		return frame.payload, nil
+	case <-ctx.Done():
+		p.abandon(id)
+		p.write(context.Background(), tcpFrame{kind: tcpCancel, id: id})
+		return nil, ctx.Err()
+	}
+}
+
+// abandon stops waiting for the response to the request with the provided ID.
+func (p *tcpPeer) abandon(id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, id)
+}
+
+// write writes the provided frame to the connection. The connection is closed if
+// the frame could not be written in its entirety.
+func (p *tcpPeer) write(ctx context.Context, frame tcpFrame) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	p.conn.SetWriteDeadline(deadline)
+	if err := writeFrame(p.conn, frame); err != nil {
+		p.conn.Close()
+		return fmt.Errorf("could not write frame: %w", err)
+	}
+	return nil
+}
+
+// readResponses delivers the response frames read from the connection to the requests
+// awaiting them until the connection fails.
+func (p *tcpPeer) readResponses() error {
+	for {
+		frame, err := readFrame(p.conn)
+		if err != nil {
+			return err
+		}
+		if frame.kind != tcpResponse && frame.kind != tcpError {
+			return fmt.Errorf("unexpected frame: kind = %d", frame.kind)
+		}
+		p.mu.Lock()
+		if responseCh, ok := p.pending[frame.id]; ok {
+			delete(p.pending, frame.id)
+			responseCh <- frame
+		}
+		p.mu.Unlock()
+	}
+}
+
+// fail closes the connection and fails all requests awaiting a response with the
+// provided error.
+func (p *tcpPeer) fail(err error) {
+	p.conn.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = fmt.Errorf("connection to peer failed: %w", err)
+	}
+	for id, responseCh := range p.pending {
+		close(responseCh)
+		delete(p.pending, id)
+	}
+}
+
+// tcpTransport is an implementation of the Transport interface that exchanges length-prefixed
+// binary frames over raw TCP connections.
+type tcpTransport struct {
+	// Indicates whether the transport is started.
+	running bool
+
+	// The local network address.
+	address string
+
+	// The listener for incoming connections.
+	listener net.Listener
+
+	// The context provided to handlers. It is cancelled when the transport is shutdown.
+	ctx context.Context
+
+	// Cancels the context provided to handlers.
+	cancel context.CancelFunc
+
+	// The function that is called when an AppendEntries RPC is received.
+	appendEntriesHandler func(context.Context, *AppendEntriesRequest, *AppendEntriesResponse) error
+
+	// The function that is called when a RequestVote RPC is received.
+	requestVoteHandler func(context.Context, *RequestVoteRequest, *RequestVoteResponse) error
+
+	// The function that is called when an InstallSnapshot RPC is recieved.
+	installSnapshotHandler func(context.Context, *InstallSnapshotRequest, *InstallSnapshotResponse) error
+
+	// The function that is called when a FetchSnapshot RPC is received.
+	fetchSnapshotHandler func(context.Context, *FetchSnapshotRequest, *FetchSnapshotResponse) error
+
+	// The function that is called when a TimeoutNow RPC is received.
+	timeoutNowHandler func(context.Context, *TimeoutNowRequest, *TimeoutNowResponse) error
+
+	// The function that is called when a ForwardOperation RPC is received.
+	forwardOperationHandler func(context.Context, *ForwardOperationRequest, *ForwardOperationResponse) error
+
+	// The function that is called when a ForwardMembershipChange RPC is received.
+	forwardMembershipChangeHandler func(
+		context.Context,
+		*ForwardMembershipChangeRequest,
+		*ForwardMembershipChangeResponse,
+	) error
+
+	// The connections to peers that RPCs are sent over. Maps address to peer.
+	peers map[string]*tcpPeer
+
+	// The connections that RPCs are received over.
+	conns map[net.Conn]bool
+
+	// Tracks the goroutines that serve and read from connections.
+	wg sync.WaitGroup
+
+	mu sync.RWMutex
+}
+
+// NewTCPTransport creates a new Transport instance that sends RPCs as length-prefixed binary
+// frames over raw TCP connections. It avoids the HTTP/2 framing and protobuf conversion of the
+// transport created by NewTransport. A single persistent connection is kept to each peer and
+// concurrent RPCs are multiplexed over it. The address may also be a Unix domain socket of the
+// form unix:///absolute/path.
+//
+// RPCs are sent in plaintext and are accepted from any peer that can connect to the address:
+// there is no TLS and no peer authentication. Only use this transport on a trusted network,
+// such as a private network or a Unix domain socket, or use NewTransport instead.
+func NewTCPTransport(address string) (Transport, error) {
+	if _, _, err := parseListenAddress(address); err != nil {
+		return nil, fmt.Errorf("could not parse address: %w", err)
+	}
+	return &tcpTransport{
+		address: address,
+		peers:   make(map[string]*tcpPeer),
+		conns:   make(map[net.Conn]bool),
+	}, nil
+}
+
+func (t *tcpTransport) Run() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		return nil
+	}
+
+	listener, err := listen(t.address)
+	if err != nil {
+		return fmt.Errorf("could not create listener: %w", err)
+	}
+
+	t.listener = listener
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	t.running = true
+	t.wg.Add(1)
+	go t.serve(listener)
+
+	return nil
+}
+
+func (t *tcpTransport) Shutdown() error {
+	t.mu.Lock()
+	if !t.running {
+		t.mu.Unlock()
+		return nil
+	}
+	t.running = false
+	t.listener.Close()
+	t.cancel()
+	for conn := range t.conns {
+		conn.Close()
+	}
+	for address, peer := range t.peers {
+		peer.conn.Close()
+		delete(t.peers, address)
+	}
+	t.mu.Unlock()
+
+	stopped := make(chan interface{})
+	go func() {
+		t.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-time.After(shutdownGracePeriod):
+	case <-stopped:
+	}
+
+	return nil
+}
+
+// serve accepts incoming connections until the provided listener is closed.
+func (t *tcpTransport) serve(listener net.Listener) {
+	defer t.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		t.mu.Lock()
+		if !t.running {
+			t.mu.Unlock()
+			conn.Close()
+			return
+		}
+		t.conns[conn] = true
+		t.wg.Add(1)
+		t.mu.Unlock()
+		go t.serveConn(conn)
+	}
+}
+
+// serveConn handles the requests received over the provided connection until it fails.
+// Each request is handled concurrently and its response is written once it is ready.
+func (t *tcpTransport) serveConn(conn net.Conn) {
+	defer t.wg.Done()
+
+	var writeMu, cancelMu sync.Mutex
+	var handlers sync.WaitGroup
+	cancels := make(map[uint64]context.CancelFunc)
+
+	defer func() {
+		conn.Close()
+		t.mu.Lock()
+		delete(t.conns, conn)
+		t.mu.Unlock()
+		cancelMu.Lock()
+		for _, cancel := range cancels {
+			cancel()
+		}
+		cancelMu.Unlock()
+		handlers.Wait()
+	}()
+
+	for {
+		frame, err := readFrame(conn)
+		if err != nil {
+			return
+		}
+
+		if frame.kind == tcpCancel {
+			cancelMu.Lock()
+			if cancel, ok := cancels[frame.id]; ok {
+				cancel()
+			}
+			cancelMu.Unlock()
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(t.ctx)
+		cancelMu.Lock()
+		cancels[frame.id] = cancel
+		cancelMu.Unlock()
+
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+
+			response := tcpFrame{kind: tcpResponse, id: frame.id}
+			payload, err := t.handle(ctx, frame)
+			if err != nil {
+				response.kind = tcpError
+				response.payload = []byte(err.Error())
+			} else {
+				response.payload = payload
+			}
+
+			cancelMu.Lock()
+			delete(cancels, frame.id)
+			cancelMu.Unlock()
+			cancel()
+
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := writeFrame(conn, response); err != nil {
+				conn.Close()
+			}
+		}()
+	}
+}
+
+// handle decodes the request in the provided frame, passes it to the registered handler,
+// and returns the encoded response.
+func (t *tcpTransport) handle(ctx context.Context, frame tcpFrame) ([]byte, error) {
+	d := &binaryDecoder{buf: frame.payload}
+	e := &binaryEncoder{}
+
+	switch frame.kind {
+	case tcpAppendEntries:
+		var request AppendEntriesRequest
+		var response AppendEntriesResponse
+		decodeAppendEntriesRequest(d, &request)
+		if err := d.finish(); err != nil {
+			return nil, fmt.Errorf("could not decode AppendEntries request: %w", err)
+		}
+		if t.appendEntriesHandler == nil {
+			return nil, errors.New("no AppendEntries handler is registered")
+		}
+		if err := t.appendEntriesHandler(ctx, &request, &response); err != nil {
+			return nil, err
+		}
+		encodeAppendEntriesResponse(e, &response)
+	case tcpRequestVote:
+		var request RequestVoteRequest
+		var response RequestVoteResponse
+		decodeRequestVoteRequest(d, &request)
+		if err := d.finish(); err != nil {
+			return nil, fmt.Errorf("could not decode RequestVote request: %w", err)
+		}
+		if t.requestVoteHandler == nil {
+			return nil, errors.New("no RequestVote handler is registered")
+		}
+		if err := t.requestVoteHandler(ctx, &request, &response); err != nil {
+			return nil, err
+		}
+		encodeRequestVoteResponse(e, &response)
+	case tcpInstallSnapshot:
+		var request InstallSnapshotRequest
+		var response InstallSnapshotResponse
+		decodeInstallSnapshotRequest(d, &request)
+		if err := d.finish(); err != nil {
+			return nil, fmt.Errorf("could not decode InstallSnapshot request: %w", err)
+		}
+		if t.installSnapshotHandler == nil {
+			return nil, errors.New("no InstallSnapshot handler is registered")
+		}
+		if err := t.installSnapshotHandler(ctx, &request, &response); err != nil {
+			return nil, err
+		}
+		encodeInstallSnapshotResponse(e, &response)
+	case tcpFetchSnapshot:
+		var request FetchSnapshotRequest
+		var response FetchSnapshotResponse
+		decodeFetchSnapshotRequest(d, &request)
+		if err := d.finish(); err != nil {
+			return nil, fmt.Errorf("could not decode FetchSnapshot request: %w", err)
+		}
+		if t.fetchSnapshotHandler == nil {
+			return nil, errors.New("no FetchSnapshot handler is registered")
+		}
+		if err := t.fetchSnapshotHandler(ctx, &request, &response); err != nil {
+			return nil, err
+		}
+		encodeFetchSnapshotResponse(e, &response)
+	case tcpTimeoutNow:
+		var request TimeoutNowRequest
+		var response TimeoutNowResponse
+		decodeTimeoutNowRequest(d, &request)
+		if err := d.finish(); err != nil {
+			return nil, fmt.Errorf("could not decode TimeoutNow request: %w", err)
+		}
+		if t.timeoutNowHandler == nil {
+			return nil, errors.New("no TimeoutNow handler is registered")
+		}
+		if err := t.timeoutNowHandler(ctx, &request, &response); err != nil {
+			return nil, err
+		}
+		encodeTimeoutNowResponse(e, &response)
+	case tcpForwardOperation:
+		var request ForwardOperationRequest
+		var response ForwardOperationResponse
+		decodeForwardOperationRequest(d, &request)
+		if err := d.finish(); err != nil {
+			return nil, fmt.Errorf("could not decode ForwardOperation request: %w", err)
+		}
+		if t.forwardOperationHandler == nil {
+			return nil, errors.New("no ForwardOperation handler is registered")
+		}
+		if err := t.forwardOperationHandler(ctx, &request, &response); err != nil {
+			return nil, err
+		}
+		encodeForwardOperationResponse(e, &response)
+	case tcpForwardMembershipChange:
+		var request ForwardMembershipChangeRequest
+		var response ForwardMembershipChangeResponse
+		decodeForwardMembershipChangeRequest(d, &request)
+		if err := d.finish(); err != nil {
+			return nil, fmt.Errorf("could not decode ForwardMembershipChange request: %w", err)
+		}
+		if t.forwardMembershipChangeHandler == nil {
+			return nil, errors.New("no ForwardMembershipChange handler is registered")
+		}
+		if err := t.forwardMembershipChangeHandler(ctx, &request, &response); err != nil {
+			return nil, err
+		}
+		encodeForwardMembershipChangeResponse(e, &response)
+	default:
+		return nil, fmt.Errorf("unknown request: kind = %d", frame.kind)
+	}
+
+	return e.buf, nil
+}
+
+// peer returns the connection to the peer at the provided address. If one does not
+// exist, it will be created.
+func (t *tcpTransport) peer(ctx context.Context, address string) (*tcpPeer, error) {
+	t.mu.RLock()
+	running := t.running
+	peer, ok := t.peers[address]
+	t.mu.RUnlock()
+	if !running {
+		return nil, errors.New("transport is closed")
+	}
+	if ok {
+		return peer, nil
+	}
+
+	network, dialAddress, err := parseListenAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse address: %w", err)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, dialAddress)
+	if err != nil {
+		return nil, fmt.Errorf("could not establish connection: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.running {
+		conn.Close()
+		return nil, errors.New("transport is closed")
+	}
+	// Another RPC may have connected to the peer while this one was dialing.
+	if peer, ok := t.peers[address]; ok {
+		conn.Close()
+		return peer, nil
+	}
+
+	peer = &tcpPeer{conn: conn, pending: make(map[uint64]chan tcpFrame)}
+	t.peers[address] = peer
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		peer.fail(peer.readResponses())
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.peers[address] == peer {
+			delete(t.peers, address)
+		}
+	}()
+
+	return peer, nil
+}
+
+// call sends a request of the provided kind to the peer at the provided address and
+// decodes the response.
+func (t *tcpTransport) call(
+	ctx context.Context,
+	address string,
+	kind byte,
+	rpc string,
+	encode func(e *binaryEncoder),
+	decode func(d *binaryDecoder),
+) error {
+	peer, err := t.peer(ctx, address)
+	if err != nil {
+		return fmt.Errorf("could not make %s RPC: %w", rpc, err)
+	}
+
+	e := &binaryEncoder{}
+	encode(e)
+	payload, err := peer.call(ctx, kind, e.buf)
+	if err != nil {
+		return fmt.Errorf("could not make %s RPC: %w", rpc, err)
+	}
+
+	d := &binaryDecoder{buf: payload}
+	decode(d)
+	if err := d.finish(); err != nil {
+		return fmt.Errorf("could not decode %s response: %w", rpc, err)
+	}
+	return nil
+}
+
+func (t *tcpTransport) SendAppendEntries(
+	ctx context.Context,
+	address string,
+	request AppendEntriesRequest,
+) (AppendEntriesResponse, error) {
+	var response AppendEntriesResponse
+	err := t.call(
+		ctx,
+		address,
+		tcpAppendEntries,
+		"AppendEntries",
+		func(e *binaryEncoder) { encodeAppendEntriesRequest(e, &request) },
+		func(d *binaryDecoder) { decodeAppendEntriesResponse(d, &response) },
+	)
+	if err != nil {
+		return AppendEntriesResponse{}, err
+	}
+	return response, nil
+}
+
+func (t *tcpTransport) SendRequestVote(
+	ctx context.Context,
+	address string,
+	request RequestVoteRequest,
+) (RequestVoteResponse, error) {
+	var response RequestVoteResponse
+	err := t.call(
+		ctx,
+		address,
+		tcpRequestVote,
+		"RequestVote",
+		func(e *binaryEncoder) { encodeRequestVoteRequest(e, &request) },
+		func(d *binaryDecoder) { decodeRequestVoteResponse(d, &response) },
+	)
+	if err != nil {
+		return RequestVoteResponse{}, err
+	}
+	return response, nil
+}
+
+func (t *tcpTransport) SendInstallSnapshot(
+	ctx context.Context,
+	address string,
+	request InstallSnapshotRequest,
+) (InstallSnapshotResponse, error) {
+	var response InstallSnapshotResponse
+	err := t.call(
+		ctx,
+		address,
+		tcpInstallSnapshot,
+		"InstallSnapshot",
+		func(e *binaryEncoder) { encodeInstallSnapshotRequest(e, &request) },
+		func(d *binaryDecoder) { decodeInstallSnapshotResponse(d, &response) },
+	)
+	if err != nil {
+		return InstallSnapshotResponse{}, err
+	}
+	return response, nil
+}
+
+func (t *tcpTransport) SendFetchSnapshot(
+	ctx context.Context,
+	address string,
+	request FetchSnapshotRequest,
+) (FetchSnapshotResponse, error) {
+	var response FetchSnapshotResponse
+	err := t.call(
+		ctx,
+		address,
+		tcpFetchSnapshot,
+		"FetchSnapshot",
+		func(e *binaryEncoder) { encodeFetchSnapshotRequest(e, &request) },
+		func(d *binaryDecoder) { decodeFetchSnapshotResponse(d, &response) },
+	)
+	if err != nil {
+		return FetchSnapshotResponse{}, err
+	}
+	return response, nil
+}
+
+func (t *tcpTransport) SendTimeoutNow(
+	ctx context.Context,
+	address string,
+	request TimeoutNowRequest,
+) (TimeoutNowResponse, error) {
+	var response TimeoutNowResponse
+	err := t.call(
+		ctx,
+		address,
+		tcpTimeoutNow,
+		"TimeoutNow",
+		func(e *binaryEncoder) { encodeTimeoutNowRequest(e, &request) },
+		func(d *binaryDecoder) { decodeTimeoutNowResponse(d, &response) },
+	)
+	if err != nil {
+		return TimeoutNowResponse{}, err
+	}
+	return response, nil
+}
+
+func (t *tcpTransport) SendForwardOperation(
+	ctx context.Context,
+	address string,
+	request ForwardOperationRequest,
+) (ForwardOperationResponse, error) {
+	var response ForwardOperationResponse
+	err := t.call(
+		ctx,
+		address,
+		tcpForwardOperation,
+		"ForwardOperation",
+		func(e *binaryEncoder) { encodeForwardOperationRequest(e, &request) },
+		func(d *binaryDecoder) { decodeForwardOperationResponse(d, &response) },
+	)
+	if err != nil {
+		return ForwardOperationResponse{}, err
+	}
+	return response, nil
+}
+
+func (t *tcpTransport) SendForwardMembershipChange(
+	ctx context.Context,
+	address string,
+	request ForwardMembershipChangeRequest,
+) (ForwardMembershipChangeResponse, error) {
+	var response ForwardMembershipChangeResponse
+	err := t.call(
+		ctx,
+		address,
+		tcpForwardMembershipChange,
+		"ForwardMembershipChange",
+		func(e *binaryEncoder) { encodeForwardMembershipChangeRequest(e, &request) },
+		func(d *binaryDecoder) { decodeForwardMembershipChangeResponse(d, &response) },
+	)
+	if err != nil {
+		return ForwardMembershipChangeResponse{}, err
+	}
+	return response, nil
+}
+
+func (t *tcpTransport) RegisterAppendEntriesHandler(
+	handler func(context.Context, *AppendEntriesRequest, *AppendEntriesResponse) error,
+) {
+	t.appendEntriesHandler = handler
+}
+
+func (t *tcpTransport) RegisterRequestVoteHandler(
+	handler func(context.Context, *RequestVoteRequest, *RequestVoteResponse) error,
+) {
+	t.requestVoteHandler = handler
+}
+
+func (t *tcpTransport) RegsiterInstallSnapshotHandler(
+	handler func(context.Context, *InstallSnapshotRequest, *InstallSnapshotResponse) error,
+) {
+	t.installSnapshotHandler = handler
+}
+
+func (t *tcpTransport) RegisterFetchSnapshotHandler(
+	handler func(context.Context, *FetchSnapshotRequest, *FetchSnapshotResponse) error,
+) {
+	t.fetchSnapshotHandler = handler
+}
+
+func (t *tcpTransport) RegisterTimeoutNowHandler(
+	handler func(context.Context, *TimeoutNowRequest, *TimeoutNowResponse) error,
+) {
+	t.timeoutNowHandler = handler
+}
+
+func (t *tcpTransport) RegisterForwardOperationHandler(
+	handler func(context.Context, *ForwardOperationRequest, *ForwardOperationResponse) error,
+) {
+	t.forwardOperationHandler = handler
+}
+
+func (t *tcpTransport) RegisterForwardMembershipChangeHandler(
+	handler func(
+		context.Context,
+		*ForwardMembershipChangeRequest,
+		*ForwardMembershipChangeResponse,
+	) error,
+) {
+	t.forwardMembershipChangeHandler = handler
+}
+
+func (t *tcpTransport) EncodeConfiguration(configuration *Configuration) ([]byte, error) {
+	data, err := encodeConfiguration(configuration)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode configuration: %w", err)
+	}
+	return data, nil
+}
+
+func (t *tcpTransport) DecodeConfiguration(data []byte) (Configuration, error) {
+	configuration, err := decodeConfiguration(data)
+	if err != nil {
+		return Configuration{}, fmt.Errorf("could not decode configuration: %w", err)
+	}
+	return configuration, nil
+}
+
+func (t *tcpTransport) RemovePeer(address string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if peer, ok := t.peers[address]; ok {
+		peer.conn.Close()
+		delete(t.peers, address)
+	}
+}
+
+func (t *tcpTransport) Address() string {
+	return t.address
+}
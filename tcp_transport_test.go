@@ -0,0 +1,330 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// startTCPTransport creates and starts a TCP transport at the provided address that responds
+// successfully to AppendEntries and RequestVote RPCs.
+func startTCPTransport(t *testing.T, address string) Transport {
+	transport, err := NewTCPTransport(address)
+	require.NoError(t, err)
+	transport.RegisterAppendEntriesHandler(
+		func(ctx context.Context, request *AppendEntriesRequest, response *AppendEntriesResponse) error {
+			response.Term = request.Term
+			response.Success = true
+			response.Index = request.PrevLogIndex + uint64(len(request.Entries))
+			return nil
+		},
+	)
+	transport.RegisterRequestVoteHandler(
+		func(ctx context.Context, request *RequestVoteRequest, response *RequestVoteResponse) error {
+			response.Term = request.Term
+			response.VoteGranted = !request.Prevote
+			return nil
+		},
+	)
+	require.NoError(t, transport.Run())
+	t.Cleanup(func() { transport.Shutdown() })
+	return transport
+}
+
+// TestTCPTransportSend checks that TCP transports are able to send RPCs to each other and that
+// the requests and responses are decoded correctly.
+func TestTCPTransportSend(t *testing.T) {
+	server := startTCPTransport(t, "127.0.0.1:18112")
+	client := startTCPTransport(t, "127.0.0.1:18113")
+
+	entries := []*LogEntry{
+		NewLogEntry(5, 2, []byte("entry1"), OperationEntry),
+		NewLogEntry(6, 2, nil, NoOpEntry),
+	}
+	response, err := client.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 2, PrevLogIndex: 4, Entries: entries},
+	)
+	require.NoError(t, err)
+	require.Equal(t, AppendEntriesResponse{Term: 2, Success: true, Index: 6}, response)
+
+	voteResponse, err := client.SendRequestVote(
+		context.Background(),
+		server.Address(),
+		RequestVoteRequest{Term: 3, CandidateID: "candidate"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, RequestVoteResponse{Term: 3, VoteGranted: true}, voteResponse)
+
+	// Errors returned by handlers are returned to the sender.
+	_, err = client.SendFetchSnapshot(context.Background(), server.Address(), FetchSnapshotRequest{})
+	require.Error(t, err)
+
+	// RPCs to peers that are not running fail.
+	_, err = client.SendAppendEntries(context.Background(), "127.0.0.1:18114", AppendEntriesRequest{})
+	require.Error(t, err)
+}
+
+// TestTCPTransportConcurrentRequests checks that concurrent RPCs multiplexed over the same
+// connection each receive their own response.
+func TestTCPTransportConcurrentRequests(t *testing.T) {
+	server := startTCPTransport(t, "127.0.0.1:18115")
+	client := startTCPTransport(t, "127.0.0.1:18116")
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 100; i++ {
+		wg.Add(1)
+		go func(term uint64) {
+			defer wg.Done()
+			response, err := client.SendAppendEntries(
+				context.Background(),
+				server.Address(),
+				AppendEntriesRequest{Term: term},
+			)
+			require.NoError(t, err)
+			require.Equal(t, term, response.Term)
+		}(uint64(i))
+	}
+	wg.Wait()
+
+	require.Len(t, client.(*tcpTransport).peers, 1)
+}
+
+// TestTCPTransportCancellation checks that the handler is notified when the sender abandons
+// an RPC.
+func TestTCPTransportCancellation(t *testing.T) {
+	cancelled := make(chan struct{})
+	server, err := NewTCPTransport("127.0.0.1:18117")
+	require.NoError(t, err)
+	server.RegisterAppendEntriesHandler(
+		func(ctx context.Context, request *AppendEntriesRequest, response *AppendEntriesResponse) error {
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		},
+	)
+	require.NoError(t, server.Run())
+	defer server.Shutdown()
+
+	client := startTCPTransport(t, "127.0.0.1:18118")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = client.SendAppendEntries(ctx, server.Address(), AppendEntriesRequest{Term: 1})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not observe cancellation")
+	}
+}
+
+// TestTCPTransportReconnect checks that a transport reconnects to a peer that restarts.
+func TestTCPTransportReconnect(t *testing.T) {
+	server := startTCPTransport(t, "127.0.0.1:18119")
+	client := startTCPTransport(t, "127.0.0.1:18120")
+
+	_, err := client.SendAppendEntries(context.Background(), server.Address(), AppendEntriesRequest{})
+	require.NoError(t, err)
+
+	require.NoError(t, server.Shutdown())
+	require.NoError(t, server.Run())
+
+	require.Eventually(t, func() bool {
+		_, err := client.SendAppendEntries(
+			context.Background(),
+			server.Address(),
+			AppendEntriesRequest{},
+		)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestBinaryCodec checks that each request and response is unchanged after being encoded
+// and decoded.
+func TestBinaryCodec(t *testing.T) {
+	roundTrip := func(encode func(e *binaryEncoder), decode func(d *binaryDecoder)) {
+		e := &binaryEncoder{}
+		encode(e)
+		d := &binaryDecoder{buf: e.buf}
+		decode(d)
+		require.NoError(t, d.finish())
+	}
+
+	installSnapshot := InstallSnapshotRequest{
+		LeaderID:           "leader",
+		Term:               4,
+		LastIncludedIndex:  10,
+		LastIncludedTerm:   3,
+		Configuration:      []byte("configuration"),
+		Bytes:              []byte("snapshot"),
+		Offset:             -1,
+		Done:               true,
+		BaseIndex:          5,
+		SourceID:           "source",
+		SourceAddress:      "127.0.0.1:8080",
+		MinProtocolVersion: 1,
+		MaxProtocolVersion: 2,
+	}
+	var decodedInstallSnapshot InstallSnapshotRequest
+	roundTrip(
+		func(e *binaryEncoder) { encodeInstallSnapshotRequest(e, &installSnapshot) },
+		func(d *binaryDecoder) { decodeInstallSnapshotRequest(d, &decodedInstallSnapshot) },
+	)
+	require.Equal(t, installSnapshot, decodedInstallSnapshot)
+
+	installSnapshotResponse := InstallSnapshotResponse{
+		Term:               4,
+		BytesWritten:       8,
+		FetchFailed:        true,
+		MinProtocolVersion: 1,
+		MaxProtocolVersion: 2,
+		LastIncludedIndex:  10,
+	}
+	var decodedInstallSnapshotResponse InstallSnapshotResponse
+	roundTrip(
+		func(e *binaryEncoder) { encodeInstallSnapshotResponse(e, &installSnapshotResponse) },
+		func(d *binaryDecoder) { decodeInstallSnapshotResponse(d, &decodedInstallSnapshotResponse) },
+	)
+	require.Equal(t, installSnapshotResponse, decodedInstallSnapshotResponse)
+
+	fetchSnapshot := FetchSnapshotResponse{
+		LastIncludedIndex: 10,
+		LastIncludedTerm:  3,
+		BaseIndex:         5,
+		Configuration:     []byte("configuration"),
+		Bytes:             []byte("snapshot"),
+		Unavailable:       true,
+	}
+	var decodedFetchSnapshot FetchSnapshotResponse
+	roundTrip(
+		func(e *binaryEncoder) { encodeFetchSnapshotResponse(e, &fetchSnapshot) },
+		func(d *binaryDecoder) { decodeFetchSnapshotResponse(d, &decodedFetchSnapshot) },
+	)
+	require.Equal(t, fetchSnapshot, decodedFetchSnapshot)
+
+	timeoutNow := TimeoutNowRequest{Term: 4, LeaderID: "leader"}
+	var decodedTimeoutNow TimeoutNowRequest
+	roundTrip(
+		func(e *binaryEncoder) { encodeTimeoutNowRequest(e, &timeoutNow) },
+		func(d *binaryDecoder) { decodeTimeoutNowRequest(d, &decodedTimeoutNow) },
+	)
+	require.Equal(t, timeoutNow, decodedTimeoutNow)
+
+	forwardOperation := ForwardOperationRequest{
+		Operation:     []byte("operation"),
+		OperationType: LinearizableReadOnly,
+		Timeout:       time.Second,
+	}
+	var decodedForwardOperation ForwardOperationRequest
+	roundTrip(
+		func(e *binaryEncoder) { encodeForwardOperationRequest(e, &forwardOperation) },
+		func(d *binaryDecoder) { decodeForwardOperationRequest(d, &decodedForwardOperation) },
+	)
+	require.Equal(t, forwardOperation, decodedForwardOperation)
+
+	operationResponse := ForwardOperationResponse{
+		LogIndex:            1,
+		LogTerm:             2,
+		ApplicationResponse: []byte("response"),
+		Error:               "error",
+		ErrorCode:           1,
+	}
+	var decodedOperationResponse ForwardOperationResponse
+	roundTrip(
+		func(e *binaryEncoder) { encodeForwardOperationResponse(e, &operationResponse) },
+		func(d *binaryDecoder) { decodeForwardOperationResponse(d, &decodedOperationResponse) },
+	)
+	require.Equal(t, operationResponse, decodedOperationResponse)
+
+	changeRequest := ForwardMembershipChangeRequest{
+		Timeout: time.Second,
+		Changes: []MembershipChange{
+			{ID: "1", Address: "127.0.0.1:8080", IsVoter: true},
+			{ID: "2", Remove: true},
+		},
+	}
+	var decodedChangeRequest ForwardMembershipChangeRequest
+	roundTrip(
+		func(e *binaryEncoder) { encodeForwardMembershipChangeRequest(e, &changeRequest) },
+		func(d *binaryDecoder) { decodeForwardMembershipChangeRequest(d, &decodedChangeRequest) },
+	)
+	require.Equal(t, changeRequest, decodedChangeRequest)
+
+	promoteRequest := ForwardMembershipChangeRequest{
+		ID:      "1",
+		Address: "127.0.0.1:8080",
+		Promote: true,
+		Timeout: time.Second,
+	}
+	var decodedPromoteRequest ForwardMembershipChangeRequest
+	roundTrip(
+		func(e *binaryEncoder) { encodeForwardMembershipChangeRequest(e, &promoteRequest) },
+		func(d *binaryDecoder) { decodeForwardMembershipChangeRequest(d, &decodedPromoteRequest) },
+	)
+	require.Equal(t, promoteRequest, decodedPromoteRequest)
+
+	membershipChange := ForwardMembershipChangeResponse{
+		Error:     "error",
+		ErrorCode: 2,
+		NotLeader: &NotLeaderError{LeaderID: "1", LeaderAddress: "127.0.0.1:8080", Term: 2},
+	}
+	var decodedMembershipChange ForwardMembershipChangeResponse
+	roundTrip(
+		func(e *binaryEncoder) { encodeForwardMembershipChangeResponse(e, &membershipChange) },
+		func(d *binaryDecoder) { decodeForwardMembershipChangeResponse(d, &decodedMembershipChange) },
+	)
+	require.Equal(t, membershipChange, decodedMembershipChange)
+
+	// Truncated payloads are rejected.
+	e := &binaryEncoder{}
+	encodeInstallSnapshotRequest(e, &installSnapshot)
+	d := &binaryDecoder{buf: e.buf[:len(e.buf)-3]}
+	decodeInstallSnapshotRequest(d, &InstallSnapshotRequest{})
+	require.ErrorIs(t, d.finish(), errMalformedFrame)
+}
+
+// TestTCPCluster checks that a cluster using TCP transports is able to elect a leader and
+// replicate operations.
+func TestTCPCluster(t *testing.T) {
+	addresses := []string{"127.0.0.1:18121", "127.0.0.1:18122", "127.0.0.1:18123"}
+	members := make(map[string]string)
+	for i, address := range addresses {
+		members[fmt.Sprint(i)] = address
+	}
+
+	nodes := make([]*Raft, 0, len(members))
+	for id, address := range members {
+		transport, err := NewTCPTransport(address)
+		require.NoError(t, err)
+		node, err := makeRaftWithStateMachine(
+			id,
+			address,
+			t.TempDir(),
+			newStateMachineMock(false, 0),
+			WithTransport(transport),
+		)
+		require.NoError(t, err)
+		require.NoError(t, node.Bootstrap(members))
+		require.NoError(t, node.Start())
+		defer node.Stop()
+		nodes = append(nodes, node)
+	}
+
+	operation := []byte("operation")
+	require.Eventually(t, func() bool {
+		for _, node := range nodes {
+			response := node.SubmitOperation(operation, Replicated, futureTimeout).Await()
+			if response.Error() == nil {
+				return string(response.Success().Operation.Bytes) == string(operation)
+			}
+		}
+		return false
+	}, maxSubmissionTime*time.Second, 50*time.Millisecond)
+}
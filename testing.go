@@ -2,10 +2,12 @@ package raft
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"reflect"
 	"sync"
 	"testing"
@@ -34,6 +36,10 @@ const (
 	futureTimeout = 200 * time.Millisecond
 )
 
+// The network that connects the nodes created for tests. Tests use in-memory
+// transports unless the TRANSPORT environment variable is set to "grpc" or "tcp".
+var testNetwork = NewInmemNetwork()
+
 func checkLogEntry(t *testing.T, expected *LogEntry, actual *LogEntry) {
 	require.Equal(t, expected.Index, actual.Index)
 	require.Equal(t, expected.Term, actual.Term)
@@ -69,19 +75,22 @@ func makeRaft(
 	snapshotting bool,
 	snapshotSize int,
 ) (*Raft, error) {
-	fsm := newStateMachineMock(snapshotting, snapshotSize)
+	return makeRaftWithStateMachine(id, address, dataPath, newStateMachineMock(snapshotting, snapshotSize))
+}
+
+func makeRaftWithStateMachine(
+	id string,
+	address string,
+	dataPath string,
+	fsm StateMachine,
+	opts ...Option,
+) (*Raft, error) {
 	transport, err := newTransportMock(address)
 	if err != nil {
 		return nil, err
 	}
-	raft, err := NewRaft(
-		id,
-		address,
-		fsm,
-		dataPath,
-		WithLogLevel(logging.Debug),
-		WithTransport(transport),
-	)
+	opts = append([]Option{WithLogLevel(logging.Debug), WithTransport(transport)}, opts...)
+	raft, err := NewRaft(id, address, fsm, dataPath, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -127,9 +136,20 @@ type transportMock struct {
 }
 
 func newTransportMock(address string) (*transportMock, error) {
-	base, err := NewTransport(address)
-	if err != nil {
-		return nil, err
+	var base Transport = testNetwork.NewTransport(address)
+	switch os.Getenv("TRANSPORT") {
+	case "grpc":
+		grpcTransport, err := NewTransport(address)
+		if err != nil {
+			return nil, err
+		}
+		base = grpcTransport
+	case "tcp":
+		tcpTransport, err := NewTCPTransport(address)
+		if err != nil {
+			return nil, err
+		}
+		base = tcpTransport
 	}
 	return &transportMock{
 		Transport:    base,
@@ -151,26 +171,29 @@ func (t *transportMock) shouldDropMessage() bool {
 }
 
 func (t *transportMock) SendAppendEntries(
+	ctx context.Context,
 	address string,
 	request AppendEntriesRequest,
 ) (AppendEntriesResponse, error) {
 	if _, ok := t.disconnected.Load(address); ok || t.shouldDropMessage() {
 		return AppendEntriesResponse{}, errors.New("could not send AppendEntries RPC: disconnected")
 	}
-	return t.Transport.SendAppendEntries(address, request)
+	return t.Transport.SendAppendEntries(ctx, address, request)
 }
 
 func (t *transportMock) SendRequestVote(
+	ctx context.Context,
 	address string,
 	request RequestVoteRequest,
 ) (RequestVoteResponse, error) {
 	if _, ok := t.disconnected.Load(address); ok || t.shouldDropMessage() {
 		return RequestVoteResponse{}, errors.New("could not send RequestVote RPC: disconnected")
 	}
-	return t.Transport.SendRequestVote(address, request)
+	return t.Transport.SendRequestVote(ctx, address, request)
 }
 
 func (t *transportMock) SendInstallSnapshot(
+	ctx context.Context,
 	address string,
 	request InstallSnapshotRequest,
 ) (InstallSnapshotResponse, error) {
@@ -179,7 +202,59 @@ func (t *transportMock) SendInstallSnapshot(
 			"could not send InstallSnapshot RPC: disconnected",
 		)
 	}
-	return t.Transport.SendInstallSnapshot(address, request)
+	return t.Transport.SendInstallSnapshot(ctx, address, request)
+}
+
+func (t *transportMock) SendFetchSnapshot(
+	ctx context.Context,
+	address string,
+	request FetchSnapshotRequest,
+) (FetchSnapshotResponse, error) {
+	if _, ok := t.disconnected.Load(address); ok || t.shouldDropMessage() {
+		return FetchSnapshotResponse{}, errors.New(
+			"could not send FetchSnapshot RPC: disconnected",
+		)
+	}
+	return t.Transport.SendFetchSnapshot(ctx, address, request)
+}
+
+func (t *transportMock) SendTimeoutNow(
+	ctx context.Context,
+	address string,
+	request TimeoutNowRequest,
+) (TimeoutNowResponse, error) {
+	if _, ok := t.disconnected.Load(address); ok || t.shouldDropMessage() {
+		return TimeoutNowResponse{}, errors.New(
+			"could not send TimeoutNow RPC: disconnected",
+		)
+	}
+	return t.Transport.SendTimeoutNow(ctx, address, request)
+}
+
+func (t *transportMock) SendForwardOperation(
+	ctx context.Context,
+	address string,
+	request ForwardOperationRequest,
+) (ForwardOperationResponse, error) {
+	if _, ok := t.disconnected.Load(address); ok || t.shouldDropMessage() {
+		return ForwardOperationResponse{}, errors.New(
+			"could not send ForwardOperation RPC: disconnected",
+		)
+	}
+	return t.Transport.SendForwardOperation(ctx, address, request)
+}
+
+func (t *transportMock) SendForwardMembershipChange(
+	ctx context.Context,
+	address string,
+	request ForwardMembershipChangeRequest,
+) (ForwardMembershipChangeResponse, error) {
+	if _, ok := t.disconnected.Load(address); ok || t.shouldDropMessage() {
+		return ForwardMembershipChangeResponse{}, errors.New(
+			"could not send ForwardMembershipChange RPC: disconnected",
+		)
+	}
+	return t.Transport.SendForwardMembershipChange(ctx, address, request)
 }
 
 type stateMachineMock struct {
@@ -258,6 +333,22 @@ func (s *stateMachineMock) NeedSnapshot(logSize int) bool {
 	return s.snapshotting && logSize%s.snapshotSize == 0
 }
 
+func (s *stateMachineMock) EncodeResponse(response interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(response); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *stateMachineMock) DecodeResponse(data []byte) (interface{}, error) {
+	var response int
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
 func (s *stateMachineMock) appliedOperations() []Operation {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -267,6 +358,81 @@ func (s *stateMachineMock) appliedOperations() []Operation {
 	return operationsCopy
 }
 
+type persistentStateMachineMock struct {
+	*stateMachineMock
+}
+
+func (s *persistentStateMachineMock) AppliedIndex() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.operations) == 0 {
+		return 0, nil
+	}
+	return s.operations[len(s.operations)-1].LogIndex, nil
+}
+
+type incrementalStateMachineMock struct {
+	*stateMachineMock
+}
+
+func (s *incrementalStateMachineMock) NeedSnapshot(logSize int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotting && logSize >= s.snapshotSize
+}
+
+func (s *incrementalStateMachineMock) SnapshotDelta(baseIndex uint64, snapshotWriter io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The delta contains all operations not contained in the base.
+	delta := make([]Operation, 0)
+	for _, operation := range s.operations {
+		if operation.LogIndex > baseIndex {
+			delta = append(delta, operation)
+		}
+	}
+
+	snapshotBytes, err := encodeOperations(delta)
+	if err != nil {
+		return fmt.Errorf("error taking incremental snapshot of state machine: error = %v", err)
+	}
+
+	if _, err := snapshotWriter.Write(snapshotBytes); err != nil {
+		return fmt.Errorf("error taking incremental snapshot of state machine: error = %v", err)
+	}
+
+	return nil
+}
+
+func (s *incrementalStateMachineMock) RestoreDelta(snapshotReader io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, snapshotReader); err != nil {
+		return fmt.Errorf("error restoring state machine: error = %v", err)
+	}
+
+	delta, err := decodeOperations(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("error restoring state machine: error = %v", err)
+	}
+
+	// The base may already contain some of the operations in the delta.
+	var lastIndex uint64
+	if len(s.operations) != 0 {
+		lastIndex = s.operations[len(s.operations)-1].LogIndex
+	}
+	for _, operation := range delta {
+		if operation.LogIndex > lastIndex {
+			s.operations = append(s.operations, operation)
+		}
+	}
+
+	return nil
+}
+
 type testCluster struct {
 	// The testing instance associated with the cluster.
 	t *testing.T
@@ -289,6 +455,12 @@ type testCluster struct {
 	// Indicates whether auto snapshotting will be used.
 	snapshotting bool
 
+	// Indicates whether the state machines support incremental snapshots.
+	incremental bool
+
+	// Additional options used to create each node.
+	options []Option
+
 	// The maximum number of log entries per snapshot if snapshotting is enabled.
 	snapshotSize int
 
@@ -306,38 +478,73 @@ func newCluster(
 	snapshotSize int,
 	lossRate int,
 ) *testCluster {
-	nodes := make(map[string]*Raft, numServers)
-	dirs := make(map[string]string, numServers)
-	stateMachines := make(map[string]*stateMachineMock, numServers)
-	transports := make(map[string]*transportMock, numServers)
-	configuration := makeClusterConfiguration(numServers)
-
-	// Create the nodes.
-	for id, address := range configuration.Members {
-		tmpDir := t.TempDir()
-		node, err := makeRaft(id, address, tmpDir, snapshotting, snapshotSize)
-		if err != nil {
-			t.Fatalf("failed to create node: error = %v", err)
-		}
-		stateMachines[id] = node.fsm.(*stateMachineMock)
-		dirs[id] = tmpDir
-		nodes[id] = node
+	return makeCluster(t, numServers, snapshotting, false, snapshotSize, lossRate)
+}
 
-		nodeTransport := node.transport.(*transportMock)
-		nodeTransport.lossRate = lossRate
-		transports[id] = nodeTransport
-	}
+func newIncrementalCluster(
+	t *testing.T,
+	numServers int,
+	snapshotSize int,
+	lossRate int,
+) *testCluster {
+	return makeCluster(t, numServers, true, true, snapshotSize, lossRate)
+}
 
-	return &testCluster{
+func makeCluster(
+	t *testing.T,
+	numServers int,
+	snapshotting bool,
+	incremental bool,
+	snapshotSize int,
+	lossRate int,
+	opts ...Option,
+) *testCluster {
+	tc := &testCluster{
 		t:             t,
-		nodes:         nodes,
-		transports:    transports,
-		configuration: configuration,
-		stateMachines: stateMachines,
-		dirs:          dirs,
+		nodes:         make(map[string]*Raft, numServers),
+		transports:    make(map[string]*transportMock, numServers),
+		configuration: makeClusterConfiguration(numServers),
+		stateMachines: make(map[string]*stateMachineMock, numServers),
+		dirs:          make(map[string]string, numServers),
 		snapshotting:  snapshotting,
+		incremental:   incremental,
+		options:       opts,
 		snapshotSize:  snapshotSize,
+		lossRate:      lossRate,
+	}
+
+	// Create the nodes.
+	for id, address := range tc.configuration.Members {
+		tc.makeNode(id, address, t.TempDir())
 	}
+
+	return tc
+}
+
+// makeNode creates a node with the provided ID and address that persists its state
+// to the provided directory and adds it to the cluster. It does not start the node.
+func (tc *testCluster) makeNode(id string, address string, dataPath string) *Raft {
+	fsm := newStateMachineMock(tc.snapshotting, tc.snapshotSize)
+	var node *Raft
+	var err error
+	if tc.incremental {
+		node, err = makeRaftWithStateMachine(id, address, dataPath, &incrementalStateMachineMock{fsm}, tc.options...)
+	} else {
+		node, err = makeRaftWithStateMachine(id, address, dataPath, fsm, tc.options...)
+	}
+	if err != nil {
+		tc.t.Fatalf("failed to create node: error = %v", err)
+	}
+	tc.nodes[id] = node
+	tc.dirs[id] = dataPath
+	tc.stateMachines[id] = fsm
+
+	// Ensure the set loss rate is preserved.
+	nodeTransport := node.transport.(*transportMock)
+	nodeTransport.lossRate = tc.lossRate
+	tc.transports[id] = nodeTransport
+
+	return node
 }
 
 func (tc *testCluster) startCluster() {
@@ -432,18 +639,7 @@ func (tc *testCluster) addServer(id string, address string, isVoter bool) {
 	tc.mu.Lock()
 	if _, ok := tc.nodes[id]; !ok {
 		// Create the node
-		tmpDir := tc.t.TempDir()
-		node, err := makeRaft(id, address, tmpDir, tc.snapshotting, tc.snapshotSize)
-		if err != nil {
-			tc.t.Fatalf("failed to make node: error = %v", err)
-		}
-		tc.nodes[id] = node
-		tc.dirs[id] = tmpDir
-		tc.stateMachines[id] = node.fsm.(*stateMachineMock)
-
-		nodeTransport := node.transport.(*transportMock)
-		nodeTransport.lossRate = tc.lossRate
-		tc.transports[id] = nodeTransport
+		node := tc.makeNode(id, address, tc.t.TempDir())
 
 		// Start the node as a non-voting member with no configuration.
 		if err := node.Start(); err != nil {
@@ -503,6 +699,55 @@ func (tc *testCluster) addServer(id string, address string, isVoter bool) {
 	tc.t.Fatalf("timed out trying to add a node: ID = %s, address = %s", id, address)
 }
 
+// addLearner is used to add a new node to the cluster with the provided ID and address
+// as a non-voting member that is promoted to a voting member once it has caught up. The
+// node will be created and started. This function will panic if the node is not promoted
+// after a predefined amount of time.
+func (tc *testCluster) addLearner(id string, address string) {
+	tc.mu.Lock()
+	if _, ok := tc.nodes[id]; !ok {
+		node := tc.makeNode(id, address, tc.t.TempDir())
+		if err := node.Start(); err != nil {
+			tc.t.Fatalf("failed to start node: error = %v", err)
+		}
+	}
+	tc.mu.Unlock()
+
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	start := time.Now()
+	for time.Since(start).Seconds() < maxMembershipChangeTime {
+		for _, node := range tc.nodes {
+			// Submit the request to add a learner to the cluster. This node might be the leader.
+			future := node.AddLearner(id, address, futureTimeout)
+			response := future.Await()
+			if err := response.Error(); err != nil {
+				continue
+			}
+
+			// Make sure the node was promoted.
+			configuration := response.Success()
+			if actualAddress, ok := configuration.Members[id]; !ok || actualAddress != address {
+				tc.t.Fatalf(
+					"membership change returned success, but node is missing from configuration: ID = %s",
+					id,
+				)
+			}
+			if !configuration.IsVoter[id] {
+				tc.t.Fatalf("membership change returned success, but node was not promoted: ID = %s", id)
+			}
+			return
+		}
+
+		// Sleep a bit in case the cluster needs to stabilize.
+		tc.mu.RUnlock()
+		time.Sleep(defaultElectionTimeout)
+		tc.mu.RLock()
+	}
+
+	tc.t.Fatalf("timed out trying to add a learner: ID = %s, address = %s", id, address)
+}
+
 // removeServer is used to remove the node with the provided ID from the cluster.
 // Once removed, the node will be stopped. This function will panic if the request
 // to remove the server is not successful after a predefined amount of time. This
@@ -556,6 +801,80 @@ func (tc *testCluster) removeServer(id string) {
 	tc.t.Fatalf("timed out trying to remove a node: ID = %s", id)
 }
 
+// changeMembership is used to atomically add the provided number of new voting members to the
+// cluster and remove the nodes with the provided IDs. Once removed, the nodes will be stopped.
+// This function will panic if the membership change is not successful after a predefined amount
+// of time. This function should always be called in the same thread as addServer.
+func (tc *testCluster) changeMembership(numAdded int, removed ...string) {
+	changes := make([]MembershipChange, 0, numAdded+len(removed))
+	for i := 0; i < numAdded; i++ {
+		id, address := tc.unusedIDandAddress()
+		tc.mu.Lock()
+		node := tc.makeNode(id, address, tc.t.TempDir())
+		if err := node.Start(); err != nil {
+			tc.t.Fatalf("failed to start node: error = %v", err)
+		}
+		tc.mu.Unlock()
+		changes = append(changes, MembershipChange{ID: id, Address: address, IsVoter: true})
+	}
+	for _, id := range removed {
+		changes = append(changes, MembershipChange{ID: id, Remove: true})
+	}
+
+	tc.mu.RLock()
+	start := time.Now()
+	for time.Since(start).Seconds() < maxMembershipChangeTime {
+		for _, node := range tc.nodes {
+			// Submit the membership change to the cluster. This node might be the leader.
+			future := node.ChangeMembership(changes, futureTimeout)
+			response := future.Await()
+			if err := response.Error(); err != nil {
+				continue
+			}
+			tc.mu.RUnlock()
+
+			// Make sure the configuration reflects the membership changes.
+			configuration := response.Success()
+			if configuration.IsJoint() {
+				tc.t.Fatalf("membership change returned success, but configuration is joint")
+			}
+			for _, change := range changes {
+				_, inMembers := configuration.Members[change.ID]
+				if change.Remove == inMembers || configuration.IsVoter[change.ID] != change.IsVoter {
+					tc.t.Fatalf(
+						"membership change returned success, but configuration does not reflect it: ID = %s",
+						change.ID,
+					)
+				}
+			}
+
+			// Stop the removed nodes.
+			tc.mu.Lock()
+			defer tc.mu.Unlock()
+			for _, id := range removed {
+				removeNode, ok := tc.nodes[id]
+				if !ok {
+					tc.t.Fatalf("tried to remove a node that does not exist: ID = %s", id)
+				}
+				removeNode.Stop()
+				delete(tc.nodes, id)
+				delete(tc.dirs, id)
+				delete(tc.stateMachines, id)
+				delete(tc.transports, id)
+			}
+			return
+		}
+
+		// Sleep a bit in case the cluster needs to stabilize.
+		tc.mu.RUnlock()
+		time.Sleep(defaultElectionTimeout)
+		tc.mu.RLock()
+	}
+
+	tc.mu.RUnlock()
+	tc.t.Fatalf("timed out trying to change membership: changes = %v", changes)
+}
+
 // checkStateMachines will check that atleast expectedMatches state machines of the nodes in the
 // cluster match one another. The node with the highest number of applied operations is used as the
 // source of truth. If aleast expectedMatches state machines are not matching within a predefined
@@ -800,18 +1119,7 @@ func (tc *testCluster) restart(id string) {
 	if !ok {
 		tc.t.Fatalf("attempted to restart node which does not exist: ID = %s", id)
 	}
-	node, err := makeRaft(id, crashedNode.address, tc.dirs[id], tc.snapshotting, tc.snapshotSize)
-	if err != nil {
-		tc.t.Fatalf("failed to create node: error = %v", err)
-	}
-	tc.nodes[id] = node
-	tc.stateMachines[id] = node.fsm.(*stateMachineMock)
-
-	// Ensure the set loss rate is preserved.
-	nodeTransport := node.transport.(*transportMock)
-	nodeTransport.lossRate = tc.lossRate
-	tc.transports[id] = nodeTransport
-
+	node := tc.makeNode(id, crashedNode.address, tc.dirs[id])
 	node.Start()
 }
 
@@ -0,0 +1,262 @@
+package raft
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// certificateReloader loads a certificate and key from files and reloads them when
+// either file is modified. This allows certificates to be rotated without restarting
+// the transport. This implementation is concurrent safe.
+type certificateReloader struct {
+	// The path to the PEM encoded certificate.
+	certFile string
+
+	// The path to the PEM encoded private key.
+	keyFile string
+
+	// The most recently loaded certificate.
+	certificate *tls.Certificate
+
+	// The modification times of the certificate and key files when
+	// the certificate was loaded.
+	certModTime time.Time
+	keyModTime  time.Time
+
+	mu sync.Mutex
+}
+
+// newCertificateReloader creates a new certificate reloader for the provided certificate
+// and key files. The certificate is loaded immediately so that invalid files are detected.
+func newCertificateReloader(certFile string, keyFile string) (*certificateReloader, error) {
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := reloader.load(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// load returns the certificate, reloading it first if the certificate or key file
+// has been modified since it was last loaded.
+func (c *certificateReloader) load() (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not stat certificate file: %w", err)
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not stat key file: %w", err)
+	}
+	if c.certificate != nil && certInfo.ModTime().Equal(c.certModTime) &&
+		keyInfo.ModTime().Equal(c.keyModTime) {
+		return c.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		// Keep using the previous certificate if the files are in the middle of being replaced.
+		if c.certificate != nil {
+			return c.certificate, nil
+		}
+		return nil, fmt.Errorf("could not load certificate: %w", err)
+	}
+	c.certificate = &certificate
+	c.certModTime = certInfo.ModTime()
+	c.keyModTime = keyInfo.ModTime()
+
+	return c.certificate, nil
+}
+
+// getCertificate returns the certificate that a server presents to clients.
+func (c *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.load()
+}
+
+// getClientCertificate returns the certificate that a client presents to servers.
+func (c *certificateReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return c.load()
+}
+
+// certificatePoolReloader loads the certificates of the authorities used to verify peers from a
+// file and reloads them when the file is modified. This allows the authorities to be rotated
+// without restarting the transport. This implementation is concurrent safe.
+type certificatePoolReloader struct {
+	// The path to the PEM encoded certificates.
+	caFile string
+
+	// The most recently loaded certificate pool.
+	pool *x509.CertPool
+
+	// The modification time of the file when the pool was loaded.
+	modTime time.Time
+
+	mu sync.Mutex
+}
+
+// newCertificatePoolReloader creates a new certificate pool reloader for the provided file.
+// The certificates are loaded immediately so that an invalid file is detected.
+func newCertificatePoolReloader(caFile string) (*certificatePoolReloader, error) {
+	reloader := &certificatePoolReloader{caFile: caFile}
+	if _, err := reloader.load(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// load returns the certificate pool, reloading it first if the file has been modified
+// since it was last loaded.
+func (c *certificatePoolReloader) load() (*x509.CertPool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(c.caFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not stat CA file: %w", err)
+	}
+	if c.pool != nil && info.ModTime().Equal(c.modTime) {
+		return c.pool, nil
+	}
+
+	pool, err := loadCertificatePool(c.caFile)
+	if err != nil {
+		// Keep using the previous certificates if the file is in the middle of being replaced.
+		if c.pool != nil {
+			return c.pool, nil
+		}
+		return nil, err
+	}
+	c.pool = pool
+	c.modTime = info.ModTime()
+
+	return c.pool, nil
+}
+
+// verifyClient checks that the certificates presented by a client are signed by one
+// of the authorities.
+func (c *certificatePoolReloader) verifyClient(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("client did not present a certificate")
+	}
+	pool, err := c.load()
+	if err != nil {
+		return err
+	}
+	options := x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, certificate := range state.PeerCertificates[1:] {
+		options.Intermediates.AddCert(certificate)
+	}
+	_, err = state.PeerCertificates[0].Verify(options)
+	return err
+}
+
+// loadCertificatePool creates a certificate pool containing the PEM encoded certificates
+// in the provided file.
+func loadCertificatePool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("CA file does not contain any valid certificates")
+	}
+	return pool, nil
+}
+
+// reloadingClientCredentials are the credentials used to connect to peers when they are verified
+// against the authorities in a file. The authorities are reloaded before each handshake so that
+// they may be rotated without restarting the transport.
+type reloadingClientCredentials struct {
+	credentials.TransportCredentials
+
+	// The configuration used for each handshake, excluding the authorities.
+	config *tls.Config
+
+	// Loads the authorities used to verify peers.
+	pool *certificatePoolReloader
+}
+
+func (c *reloadingClientCredentials) ClientHandshake(
+	ctx context.Context,
+	authority string,
+	conn net.Conn,
+) (net.Conn, credentials.AuthInfo, error) {
+	pool, err := c.pool.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	config := c.config.Clone()
+	config.RootCAs = pool
+	return credentials.NewTLS(config).ClientHandshake(ctx, authority, conn)
+}
+
+func (c *reloadingClientCredentials) Clone() credentials.TransportCredentials {
+	return &reloadingClientCredentials{
+		TransportCredentials: c.TransportCredentials.Clone(),
+		config:               c.config.Clone(),
+		pool:                 c.pool,
+	}
+}
+
+func (c *reloadingClientCredentials) OverrideServerName(serverName string) error {
+	c.config.ServerName = serverName
+	return nil
+}
+
+// makeTLSCredentials creates the credentials used by the server and clients of a transport
+// with the provided options.
+func makeTLSCredentials(
+	options *transportOptions,
+) (credentials.TransportCredentials, credentials.TransportCredentials, error) {
+	reloader, err := newCertificateReloader(options.certFile, options.keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serverConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.getCertificate,
+	}
+	clientConfig := &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		ServerName:           options.serverName,
+		GetClientCertificate: reloader.getClientCertificate,
+	}
+	if options.caFile == "" {
+		return credentials.NewTLS(serverConfig), credentials.NewTLS(clientConfig), nil
+	}
+
+	// Peers are verified against the authorities that are loaded when each connection is
+	// established so that the authorities may be rotated without restarting the transport.
+	pool, err := newCertificatePoolReloader(options.caFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if options.mutualTLS {
+		serverConfig.ClientAuth = tls.RequireAnyClientCert
+		serverConfig.VerifyConnection = pool.verifyClient
+	}
+	clientCredentials := &reloadingClientCredentials{
+		TransportCredentials: credentials.NewTLS(clientConfig),
+		config:               clientConfig,
+		pool:                 pool,
+	}
+
+	return credentials.NewTLS(serverConfig), clientCredentials, nil
+}
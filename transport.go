@@ -4,19 +4,211 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/jmsadair/raft/internal/numeric"
 	pb "github.com/jmsadair/raft/internal/protobuf"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 )
 
-const shutdownGracePeriod = 300 * time.Millisecond
+const (
+	shutdownGracePeriod = 300 * time.Millisecond
+
+	// The default delay before reconnecting to a peer after its connection first fails.
+	defaultBaseReconnectDelay = 100 * time.Millisecond
+
+	// The default maximum delay before reconnecting to a peer.
+	defaultMaxReconnectDelay = 2 * time.Second
+
+	// The delay before opening a new AppendEntries stream to a peer after a stream could not
+	// be opened or broke. AppendEntries requests are sent as separate RPCs in the meantime.
+	appendEntriesStreamRetryDelay = time.Second
+)
+
+type transportOptions struct {
+	// The path to the PEM encoded certificate presented to peers.
+	// TLS is enabled if this is set.
+	certFile string
+
+	// The path to the PEM encoded private key of the certificate.
+	keyFile string
+
+	// The path to the PEM encoded certificates of the authorities used to
+	// verify peers. The system pool is used if this is not set.
+	caFile string
+
+	// Indicates whether peers must present a certificate signed by one
+	// of the authorities in order to connect.
+	mutualTLS bool
+
+	// The name used to verify the certificates presented by peers. The host
+	// of the peer address is used if this is not set.
+	serverName string
+
+	// Authenticates the RPCs sent to and received from peers.
+	// RPCs are not authenticated if this is not set.
+	authenticator PeerAuthenticator
+
+	// Additional options used to create the RPC server.
+	serverOptions []grpc.ServerOption
+
+	// Additional options used to connect to peers.
+	dialOptions []grpc.DialOption
+
+	// The delay before reconnecting to a peer after its connection first fails.
+	baseReconnectDelay time.Duration
+
+	// The maximum delay before reconnecting to a peer.
+	maxReconnectDelay time.Duration
+
+	// Indicates whether AppendEntries requests are sent over a stream.
+	appendEntriesStreaming bool
+}
+
+// TransportOption is a function that updates the options associated with a transport.
+type TransportOption func(options *transportOptions) error
+
+// WithTLSCertificate enables TLS using the PEM encoded certificate and key in the provided files.
+// The certificate is presented to peers that connect to the transport and, if requested, to the
+// peers that the transport connects to. The files are reloaded whenever they are modified so that
+// the certificate may be rotated without restarting the transport.
+func WithTLSCertificate(certFile string, keyFile string) TransportOption {
+	return func(options *transportOptions) error {
+		if certFile == "" || keyFile == "" {
+			return errors.New("certificate and key files must not be empty")
+		}
+		options.certFile = certFile
+		options.keyFile = keyFile
+		return nil
+	}
+}
+
+// WithTLSCA sets the file containing the PEM encoded certificates of the authorities used to
+// verify the certificates presented by peers. The file is reloaded whenever it is modified so
+// that the authorities may be rotated without restarting the transport. The system certificate
+// pool is used if this is not provided.
+func WithTLSCA(caFile string) TransportOption {
+	return func(options *transportOptions) error {
+		if caFile == "" {
+			return errors.New("CA file must not be empty")
+		}
+		options.caFile = caFile
+		return nil
+	}
+}
+
+// WithMutualTLS requires peers that connect to the transport to present a certificate signed
+// by one of the authorities provided to WithTLSCA.
+func WithMutualTLS() TransportOption {
+	return func(options *transportOptions) error {
+		options.mutualTLS = true
+		return nil
+	}
+}
+
+// WithTLSServerName sets the name used to verify the certificates presented by peers. This is
+// useful when peers are addressed by IP address but their certificates only contain a host name.
+func WithTLSServerName(serverName string) TransportOption {
+	return func(options *transportOptions) error {
+		options.serverName = serverName
+		return nil
+	}
+}
+
+// WithSharedSecret enables authentication of peers using the provided secret, which must be
+// shared by all members of the cluster. Each RPC is signed with an HMAC over the method it
+// invokes, a digest of its request, the time at which it was sent, and a random nonce. RPCs that
+// are not signed with the secret, or that replay an RPC that has already been handled, are
+// rejected before they are handled. This does not encrypt RPCs; WithTLSCertificate should also
+// be provided if their contents must be kept private.
+func WithSharedSecret(secret []byte) TransportOption {
+	return func(options *transportOptions) error {
+		if len(secret) == 0 {
+			return errors.New("shared secret must not be empty")
+		}
+		options.authenticator = newSharedSecretAuthenticator(secret)
+		return nil
+	}
+}
+
+// WithPeerAuthenticator enables authentication of peers using the provided authenticator. This
+// is useful if you wish to authenticate peers using your own credentials, such as tokens issued
+// by an identity provider. RPCs that are not verified by the authenticator are rejected before
+// they are handled.
+func WithPeerAuthenticator(authenticator PeerAuthenticator) TransportOption {
+	return func(options *transportOptions) error {
+		if authenticator == nil {
+			return errors.New("peer authenticator must not be nil")
+		}
+		options.authenticator = authenticator
+		return nil
+	}
+}
+
+// WithServerOptions adds options that are used to create the gRPC server that serves incoming RPCs,
+// such as interceptors, keepalive parameters, or message size limits. The options are applied after
+// the options configured by the transport, so they take precedence over them.
+func WithServerOptions(opts ...grpc.ServerOption) TransportOption {
+	return func(options *transportOptions) error {
+		options.serverOptions = append(options.serverOptions, opts...)
+		return nil
+	}
+}
+
+// WithDialOptions adds options that are used to connect to peers, such as interceptors, keepalive
+// parameters, compression, or a custom dialer. The options are applied after the options configured
+// by the transport, so they take precedence over them.
+func WithDialOptions(opts ...grpc.DialOption) TransportOption {
+	return func(options *transportOptions) error {
+		options.dialOptions = append(options.dialOptions, opts...)
+		return nil
+	}
+}
+
+// WithReconnectBackoff sets the delays used when reconnecting to a peer whose connection has
+// failed. The first reconnection is attempted after the base delay and the delay doubles with
+// each consecutive failure up to the maximum delay. RPCs to the peer fail immediately until the
+// delay has elapsed.
+func WithReconnectBackoff(baseDelay time.Duration, maxDelay time.Duration) TransportOption {
+	return func(options *transportOptions) error {
+		if baseDelay <= 0 {
+			return errors.New("base reconnect delay must be positive")
+		}
+		if maxDelay < baseDelay {
+			return errors.New("max reconnect delay must not be less than the base reconnect delay")
+		}
+		options.baseReconnectDelay = baseDelay
+		options.maxReconnectDelay = maxDelay
+		return nil
+	}
+}
+
+// WithAppendEntriesStreaming sets whether AppendEntries requests are sent to each peer over a
+// single long-lived stream rather than as separate RPCs. Streaming is enabled by default. The
+// requests sent over a stream are handled in the order that they are sent. If a stream cannot be
+// opened or breaks, for example because the peer does not support streaming, requests are sent
+// as separate RPCs until a new stream is opened.
+//
+// Peers are only authenticated when a stream is opened, not for each request sent over it, so
+// streaming is disabled if WithSharedSecret or WithPeerAuthenticator is provided without
+// WithTLSCertificate. Otherwise, a request could be injected into an authenticated stream.
+func WithAppendEntriesStreaming(enabled bool) TransportOption {
+	return func(options *transportOptions) error {
+		options.appendEntriesStreaming = enabled
+		return nil
+	}
+}
 
 // Transport represents the underlying transport mechanism used by a node in a cluster
 // to send and receive RPCs. It is the implementers responsibility to provide functions
@@ -28,30 +220,113 @@ type Transport interface {
 	// Shutdown will stop the serving of incoming RPCs.
 	Shutdown() error
 
-	// AppendEntries sends an append entries request to the provided address.
-	SendAppendEntries(address string, request AppendEntriesRequest) (AppendEntriesResponse, error)
+	// AppendEntries sends an append entries request to the provided address. The RPC is
+	// abandoned if the provided context is cancelled or its deadline is exceeded.
+	SendAppendEntries(
+		ctx context.Context,
+		address string,
+		request AppendEntriesRequest,
+	) (AppendEntriesResponse, error)
 
-	// RequestVote sends a request vote request to the peer to the provided address.
-	SendRequestVote(address string, request RequestVoteRequest) (RequestVoteResponse, error)
+	// RequestVote sends a request vote request to the peer to the provided address. The RPC
+	// is abandoned if the provided context is cancelled or its deadline is exceeded.
+	SendRequestVote(
+		ctx context.Context,
+		address string,
+		request RequestVoteRequest,
+	) (RequestVoteResponse, error)
 
-	// InstallSnapshot sends a install snapshot request to the provided address.
+	// InstallSnapshot sends a install snapshot request to the provided address. The RPC is
+	// abandoned if the provided context is cancelled or its deadline is exceeded.
 	SendInstallSnapshot(
+		ctx context.Context,
 		address string,
 		request InstallSnapshotRequest,
 	) (InstallSnapshotResponse, error)
 
+	// FetchSnapshot sends a fetch snapshot request to the provided address. The RPC is
+	// abandoned if the provided context is cancelled or its deadline is exceeded.
+	SendFetchSnapshot(
+		ctx context.Context,
+		address string,
+		request FetchSnapshotRequest,
+	) (FetchSnapshotResponse, error)
+
+	// TimeoutNow sends a timeout now request to the provided address. The RPC is
+	// abandoned if the provided context is cancelled or its deadline is exceeded.
+	SendTimeoutNow(
+		ctx context.Context,
+		address string,
+		request TimeoutNowRequest,
+	) (TimeoutNowResponse, error)
+
+	// ForwardOperation forwards an operation to the leader at the provided address. The RPC is
+	// abandoned if the provided context is cancelled or its deadline is exceeded.
+	SendForwardOperation(
+		ctx context.Context,
+		address string,
+		request ForwardOperationRequest,
+	) (ForwardOperationResponse, error)
+
+	// ForwardMembershipChange forwards a membership change to the leader at the provided address.
+	// The RPC is abandoned if the provided context is cancelled or its deadline is exceeded.
+	SendForwardMembershipChange(
+		ctx context.Context,
+		address string,
+		request ForwardMembershipChangeRequest,
+	) (ForwardMembershipChangeResponse, error)
+
 	// RegisterAppendEntriesHandler registers the function the that will be called when an
-	// AppendEntries RPC is received.
-	RegisterAppendEntriesHandler(handler func(*AppendEntriesRequest, *AppendEntriesResponse) error)
+	// AppendEntries RPC is received. The handler is provided a context that is cancelled
+	// if the sender abandons the RPC.
+	RegisterAppendEntriesHandler(
+		handler func(context.Context, *AppendEntriesRequest, *AppendEntriesResponse) error,
+	)
 
 	// RegisterRequestVoteHandler registers the function that will be called when a
-	// RequestVote RPC is received.
-	RegisterRequestVoteHandler(handler func(*RequestVoteRequest, *RequestVoteResponse) error)
+	// RequestVote RPC is received. The handler is provided a context that is cancelled
+	// if the sender abandons the RPC.
+	RegisterRequestVoteHandler(
+		handler func(context.Context, *RequestVoteRequest, *RequestVoteResponse) error,
+	)
 
 	// RegisterInstallSnapshotHandler registers the function that will called when an
-	// InstallSnapshot RPC is received.
+	// InstallSnapshot RPC is received. The handler is provided a context that is cancelled
+	// if the sender abandons the RPC.
 	RegsiterInstallSnapshotHandler(
-		handler func(*InstallSnapshotRequest, *InstallSnapshotResponse) error,
+		handler func(context.Context, *InstallSnapshotRequest, *InstallSnapshotResponse) error,
+	)
+
+	// RegisterFetchSnapshotHandler registers the function that will be called when a
+	// FetchSnapshot RPC is received. The handler is provided a context that is cancelled
+	// if the sender abandons the RPC.
+	RegisterFetchSnapshotHandler(
+		handler func(context.Context, *FetchSnapshotRequest, *FetchSnapshotResponse) error,
+	)
+
+	// RegisterTimeoutNowHandler registers the function that will be called when a
+	// TimeoutNow RPC is received. The handler is provided a context that is cancelled
+	// if the sender abandons the RPC.
+	RegisterTimeoutNowHandler(
+		handler func(context.Context, *TimeoutNowRequest, *TimeoutNowResponse) error,
+	)
+
+	// RegisterForwardOperationHandler registers the function that will be called when a
+	// ForwardOperation RPC is received. The handler is provided a context that is cancelled
+	// if the sender abandons the RPC.
+	RegisterForwardOperationHandler(
+		handler func(context.Context, *ForwardOperationRequest, *ForwardOperationResponse) error,
+	)
+
+	// RegisterForwardMembershipChangeHandler registers the function that will be called when a
+	// ForwardMembershipChange RPC is received. The handler is provided a context that is cancelled
+	// if the sender abandons the RPC.
+	RegisterForwardMembershipChangeHandler(
+		handler func(
+			context.Context,
+			*ForwardMembershipChangeRequest,
+			*ForwardMembershipChangeResponse,
+		) error,
 	)
 
 	// EncodeConfiguration accepts a configuration and encodes it such that it can be
@@ -62,11 +337,18 @@ type Transport interface {
 	// it into a configuration.
 	DecodeConfiguration(data []byte) (Configuration, error)
 
+	// RemovePeer releases the resources, such as connections, held for the peer at the
+	// provided address. It is called when the peer is removed from the cluster.
+	RemovePeer(address string)
+
 	// Address returns the local network address.
 	Address() string
 }
 
-// connectionManager handles creating new connections and closing existing ones.
+// connectionManager handles creating new connections and closing existing ones. A connection
+// to a peer that fails is closed and is not recreated until an exponentially increasing delay
+// has elapsed. Recreating a connection resolves the address of the peer again, so peers that
+// are addressed by host name may change their network address.
 // This implementation is concurrent safe.
 type connectionManager struct {
 	// The connections to the nodes in the cluster. Maps address to connection.
@@ -75,22 +357,61 @@ type connectionManager struct {
 	// The clients used to make RPCs. Maps address to client.
 	clients map[string]pb.RaftClient
 
-	// The credentials each client will use.
-	creds credentials.TransportCredentials
+	// The peers whose connections have failed. Maps address to backoff.
+	backoffs map[string]*reconnectBackoff
+
+	// The streams used to send AppendEntries requests. Maps address to stream.
+	streams map[string]*appendEntriesStream
+
+	// The time before which no stream to the peer will be opened. Maps address to time.
+	streamRetryAt map[string]time.Time
+
+	// Indicates whether AppendEntries requests are sent over a stream.
+	streaming bool
+
+	// The options each connection will be created with.
+	dialOptions []grpc.DialOption
+
+	// The delay before reconnecting to a peer after its connection first fails.
+	baseReconnectDelay time.Duration
+
+	// The maximum delay before reconnecting to a peer.
+	maxReconnectDelay time.Duration
 
 	mu sync.Mutex
 }
 
-func newConnectionManager(creds credentials.TransportCredentials) *connectionManager {
+// reconnectBackoff tracks the failures of the connection to a peer.
+type reconnectBackoff struct {
+	// The number of times in a row the connection to the peer has failed.
+	failures int
+
+	// The time before which no connection to the peer will be created.
+	retryAt time.Time
+}
+
+func newConnectionManager(
+	dialOptions []grpc.DialOption,
+	baseReconnectDelay time.Duration,
+	maxReconnectDelay time.Duration,
+	streaming bool,
+) *connectionManager {
 	return &connectionManager{
-		connections: make(map[string]*grpc.ClientConn),
-		clients:     make(map[string]pb.RaftClient),
-		creds:       creds,
+		connections:        make(map[string]*grpc.ClientConn),
+		clients:            make(map[string]pb.RaftClient),
+		backoffs:           make(map[string]*reconnectBackoff),
+		streams:            make(map[string]*appendEntriesStream),
+		streamRetryAt:      make(map[string]time.Time),
+		dialOptions:        dialOptions,
+		baseReconnectDelay: baseReconnectDelay,
+		maxReconnectDelay:  maxReconnectDelay,
+		streaming:          streaming,
 	}
 }
 
 // getClient will retrieve a client for the provided address. If one does not
-// exist, it will be created.
+// exist, it will be created unless the connection to the address has recently
+// failed.
 func (c *connectionManager) getClient(address string) (pb.RaftClient, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -99,7 +420,18 @@ func (c *connectionManager) getClient(address string) (pb.RaftClient, error) {
 		return client, nil
 	}
 
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(c.creds))
+	if backoff, ok := c.backoffs[address]; ok && time.Now().Before(backoff.retryAt) {
+		return nil, fmt.Errorf(
+			"could not establish connection: peer is unreachable, retrying in %v",
+			time.Until(backoff.retryAt),
+		)
+	}
+
+	dialOptions := append(
+		[]grpc.DialOption{grpc.WithChainUnaryInterceptor(c.healthInterceptor(address))},
+		c.dialOptions...,
+	)
+	conn, err := grpc.NewClient(address, dialOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("could not establish connection: %w", err)
 	}
@@ -109,16 +441,164 @@ func (c *connectionManager) getClient(address string) (pb.RaftClient, error) {
 	return c.clients[address], nil
 }
 
+// getAppendEntriesStream will retrieve the stream used to send AppendEntries requests to the
+// provided address. If one does not exist, it will be opened in the background so that requests
+// never wait for a stream to be opened. Nil is returned if there is no open stream, in which
+// case AppendEntries requests should be sent as separate RPCs.
+func (c *connectionManager) getAppendEntriesStream(address string) *appendEntriesStream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.streaming {
+		return nil
+	}
+	if stream, ok := c.streams[address]; ok {
+		if !stream.broken() {
+			return stream
+		}
+		delete(c.streams, address)
+		c.streamRetryAt[address] = time.Now().Add(appendEntriesStreamRetryDelay)
+	}
+	client, ok := c.clients[address]
+	if !ok || time.Now().Before(c.streamRetryAt[address]) {
+		return nil
+	}
+
+	// No other stream will be opened until the retry delay elapses, which also applies
+	// if this stream cannot be opened.
+	c.streamRetryAt[address] = time.Now().Add(appendEntriesStreamRetryDelay)
+	go c.openAppendEntriesStream(address, client)
+
+	return nil
+}
+
+// openAppendEntriesStream opens a stream to the provided address using the provided client.
+func (c *connectionManager) openAppendEntriesStream(address string, client pb.RaftClient) {
+	stream, err := newAppendEntriesStream(client)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The connection may have been closed while the stream was being opened.
+	if c.clients[address] != client {
+		stream.close()
+		return
+	}
+	c.streams[address] = stream
+	delete(c.streamRetryAt, address)
+}
+
+// closeStream closes the stream to the provided address if there is one.
+func (c *connectionManager) closeStream(address string) {
+	if stream, ok := c.streams[address]; ok {
+		stream.close()
+	}
+	delete(c.streams, address)
+	delete(c.streamRetryAt, address)
+}
+
+// healthInterceptor returns an interceptor that observes the outcome of the RPCs sent
+// to the provided address. The connection is closed if an RPC fails while it is unable
+// to reach the peer, and the backoff for the peer is reset once an RPC succeeds.
+func (c *connectionManager) healthInterceptor(address string) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			c.markHealthy(address)
+		} else if cc.GetState() == connectivity.TransientFailure {
+			c.markFailed(address, cc)
+		}
+		return err
+	}
+}
+
+// markHealthy resets the backoff for the provided address.
+func (c *connectionManager) markHealthy(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.backoffs, address)
+}
+
+// markFailed closes the provided connection to the provided address and delays
+// the creation of a new connection to the address.
+func (c *connectionManager) markFailed(address string, conn *grpc.ClientConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The connection may have already been replaced or removed.
+	if c.connections[address] != conn {
+		return
+	}
+	c.closeStream(address)
+	conn.Close()
+	delete(c.connections, address)
+	delete(c.clients, address)
+
+	backoff, ok := c.backoffs[address]
+	if !ok {
+		backoff = &reconnectBackoff{}
+		c.backoffs[address] = backoff
+	}
+	backoff.failures++
+	backoff.retryAt = time.Now().Add(c.reconnectDelay(backoff.failures))
+}
+
+// reconnectDelay returns the delay before reconnecting to a peer whose connection has
+// failed the provided number of times in a row. The delay doubles with each failure up
+// to the maximum and is jittered so that peers do not reconnect in lockstep.
+func (c *connectionManager) reconnectDelay(failures int) time.Duration {
+	delay := c.baseReconnectDelay
+	for i := 1; i < failures && delay < c.maxReconnectDelay; i++ {
+		delay *= 2
+	}
+	delay = numeric.Min(delay, c.maxReconnectDelay)
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// remove closes the connection to the provided address and forgets the peer.
+func (c *connectionManager) remove(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeStream(address)
+	if conn, ok := c.connections[address]; ok {
+		conn.Close()
+	}
+	delete(c.connections, address)
+	delete(c.clients, address)
+	delete(c.backoffs, address)
+}
+
 // closeAll closes all open connections.
 func (c *connectionManager) closeAll() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	for address, conn := range c.connections {
+		c.closeStream(address)
 		conn.Close()
 		delete(c.connections, address)
 		delete(c.clients, address)
 	}
+	for address := range c.streamRetryAt {
+		delete(c.streamRetryAt, address)
+	}
+	for address := range c.backoffs {
+		delete(c.backoffs, address)
+	}
 }
 
 // transport is an implementation of the Transport interface.
@@ -128,20 +608,43 @@ type transport struct {
 	// Indicates whether the transport is started.
 	running bool
 
-	// The local network address.
-	address net.Addr
+	// The local network address. A host name is resolved when the transport is run. An
+	// address of the form unix:///absolute/path or unix:path refers to a Unix domain socket.
+	address string
 
 	// The RPC server for raft.
 	server *grpc.Server
 
+	// The options used to create the RPC server.
+	serverOptions []grpc.ServerOption
+
+	// Closed when the transport is shutdown so that open streams are ended.
+	closing chan struct{}
+
 	// The function that is called when an AppendEntries RPC is received.
-	appendEntriesHandler func(*AppendEntriesRequest, *AppendEntriesResponse) error
+	appendEntriesHandler func(context.Context, *AppendEntriesRequest, *AppendEntriesResponse) error
 
 	// The function that is called when a RequestVote RPC is received.
-	requestVoteHandler func(*RequestVoteRequest, *RequestVoteResponse) error
+	requestVoteHandler func(context.Context, *RequestVoteRequest, *RequestVoteResponse) error
 
 	// The function that is called when an InstallSnapshot RPC is recieved.
-	installSnapshotHandler func(*InstallSnapshotRequest, *InstallSnapshotResponse) error
+	installSnapshotHandler func(context.Context, *InstallSnapshotRequest, *InstallSnapshotResponse) error
+
+	// The function that is called when a FetchSnapshot RPC is received.
+	fetchSnapshotHandler func(context.Context, *FetchSnapshotRequest, *FetchSnapshotResponse) error
+
+	// The function that is called when a TimeoutNow RPC is received.
+	timeoutNowHandler func(context.Context, *TimeoutNowRequest, *TimeoutNowResponse) error
+
+	// The function that is called when a ForwardOperation RPC is received.
+	forwardOperationHandler func(context.Context, *ForwardOperationRequest, *ForwardOperationResponse) error
+
+	// The function that is called when a ForwardMembershipChange RPC is received.
+	forwardMembershipChangeHandler func(
+		context.Context,
+		*ForwardMembershipChangeRequest,
+		*ForwardMembershipChangeResponse,
+	) error
 
 	// Manages connections to other members of the cluster.
 	connManager *connectionManager
@@ -149,15 +652,131 @@ type transport struct {
 	mu sync.RWMutex
 }
 
-// NewTransport creates a new Transport instance.
-func NewTransport(address string) (Transport, error) {
-	resolvedAddress, err := net.ResolveTCPAddr("tcp", address)
+// NewTransport creates a new Transport instance listening at the provided address, which is
+// either a host and port or a Unix domain socket of the form unix:///absolute/path. Peers are
+// dialed using the same address forms. By default, RPCs are sent in cleartext.
+// WithTLSCertificate may be provided to secure RPCs using TLS. WithServerOptions and
+// WithDialOptions may be provided to further configure the underlying gRPC server and
+// connections.
+func NewTransport(address string, opts ...TransportOption) (Transport, error) {
+	if _, _, err := parseListenAddress(address); err != nil {
+		return nil, fmt.Errorf("could not parse address: %w", err)
+	}
+
+	connManager, serverOptions, err := makeGRPCOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transport{
+		address:       address,
+		connManager:   connManager,
+		serverOptions: serverOptions,
+	}, nil
+}
+
+// parseListenAddress returns the network and address that the transport listens on for the
+// provided address. Addresses of the form unix:///absolute/path or unix:path refer to a Unix
+// domain socket, which is the same form that gRPC uses to dial them. All other addresses must
+// be a host and port.
+func parseListenAddress(address string) (string, string, error) {
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		if !filepath.IsAbs(path) {
+			return "", "", fmt.Errorf("unix socket path must be absolute: address = %s", address)
+		}
+		return "unix", path, nil
+	}
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		if path == "" {
+			return "", "", fmt.Errorf("unix socket path must not be empty: address = %s", address)
+		}
+		return "unix", path, nil
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return "", "", err
+	}
+	return "tcp", address, nil
+}
+
+// listen creates a listener for the provided address. A Unix domain socket left behind by a
+// previous process that did not shut down cleanly is removed before listening.
+func listen(address string) (net.Listener, error) {
+	network, listenAddress, err := parseListenAddress(address)
 	if err != nil {
-		return nil, fmt.Errorf("could not resove tcp address: %w", err)
+		return nil, err
+	}
+	if network == "unix" {
+		if info, err := os.Lstat(listenAddress); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(listenAddress); err != nil {
+				return nil, fmt.Errorf("could not remove stale unix socket: %w", err)
+			}
+		}
+	}
+	return net.Listen(network, listenAddress)
+}
+
+// makeGRPCOptions applies the provided transport options and returns the connection manager
+// used to connect to peers and the options used to create the RPC server.
+func makeGRPCOptions(
+	opts []TransportOption,
+) (*connectionManager, []grpc.ServerOption, error) {
+	options := transportOptions{
+		baseReconnectDelay:     defaultBaseReconnectDelay,
+		maxReconnectDelay:      defaultMaxReconnectDelay,
+		appendEntriesStreaming: true,
 	}
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			return nil, nil, err
+		}
+	}
+	if options.certFile == "" && (options.caFile != "" || options.mutualTLS || options.serverName != "") {
+		return nil, nil, errors.New("TLS certificate must be provided to enable TLS")
+	}
+	if options.mutualTLS && options.caFile == "" {
+		return nil, nil, errors.New("CA file must be provided to enable mutual TLS")
+	}
+
 	creds := insecure.NewCredentials()
-	connManager := newConnectionManager(creds)
-	return &transport{address: resolvedAddress, connManager: connManager}, nil
+	var serverOptions []grpc.ServerOption
+	if options.certFile != "" {
+		serverCreds, clientCreds, err := makeTLSCredentials(&options)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not configure TLS: %w", err)
+		}
+		creds = clientCreds
+		serverOptions = append(serverOptions, grpc.Creds(serverCreds))
+	}
+
+	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if options.authenticator != nil {
+		dialOptions = append(
+			dialOptions,
+			grpc.WithChainUnaryInterceptor(authClientInterceptor(options.authenticator)),
+			grpc.WithChainStreamInterceptor(authStreamClientInterceptor(options.authenticator)),
+		)
+		serverOptions = append(
+			serverOptions,
+			grpc.ChainUnaryInterceptor(authServerInterceptor(options.authenticator)),
+			grpc.ChainStreamInterceptor(authStreamServerInterceptor(options.authenticator)),
+		)
+	}
+
+	dialOptions = append(dialOptions, options.dialOptions...)
+	serverOptions = append(serverOptions, options.serverOptions...)
+
+	// The requests sent over a stream are not authenticated individually, so they must be
+	// protected by TLS if peers are authenticated.
+	streaming := options.appendEntriesStreaming &&
+		(options.authenticator == nil || options.certFile != "")
+
+	connManager := newConnectionManager(
+		dialOptions,
+		options.baseReconnectDelay,
+		options.maxReconnectDelay,
+		streaming,
+	)
+	return connManager, serverOptions, nil
 }
 
 func (t *transport) Run() error {
@@ -167,12 +786,13 @@ func (t *transport) Run() error {
 		return nil
 	}
 
-	listener, err := net.Listen(t.address.Network(), t.address.String())
+	listener, err := listen(t.address)
 	if err != nil {
 		return fmt.Errorf("could not create listener: %w", err)
 	}
 
-	t.server = grpc.NewServer()
+	t.closing = make(chan struct{})
+	t.server = grpc.NewServer(t.serverOptions...)
 	pb.RegisterRaftServer(t.server, t)
 	go t.server.Serve(listener)
 	t.running = true
@@ -187,6 +807,7 @@ func (t *transport) Shutdown() error {
 		return nil
 	}
 	t.running = false
+	close(t.closing)
 	t.mu.Unlock()
 
 	stopped := make(chan interface{})
@@ -208,6 +829,7 @@ func (t *transport) Shutdown() error {
 }
 
 func (t *transport) SendAppendEntries(
+	ctx context.Context,
 	address string,
 	request AppendEntriesRequest,
 ) (AppendEntriesResponse, error) {
@@ -226,7 +848,19 @@ func (t *transport) SendAppendEntries(
 	}
 
 	pbRequest := makeProtoAppendEntriesRequest(request)
-	pbResponse, err := client.AppendEntries(context.Background(), pbRequest)
+	if stream := t.connManager.getAppendEntriesStream(address); stream != nil {
+		pbResponse, err := stream.send(ctx, pbRequest)
+		if err == nil {
+			return makeAppendEntriesResponse(pbResponse), nil
+		}
+		if ctx.Err() != nil {
+			return AppendEntriesResponse{}, fmt.Errorf("could not make AppendEntries RPC: %w", err)
+		}
+		// The stream broke, so fall back to a separate RPC. AppendEntries requests are
+		// idempotent, so it does not matter if the peer already handled the request.
+	}
+
+	pbResponse, err := client.AppendEntries(ctx, pbRequest)
 	if err != nil {
 		return AppendEntriesResponse{}, fmt.Errorf("could not make AppendEntries RPC: %w", err)
 	}
@@ -235,6 +869,7 @@ func (t *transport) SendAppendEntries(
 }
 
 func (t *transport) SendRequestVote(
+	ctx context.Context,
 	address string,
 	request RequestVoteRequest,
 ) (RequestVoteResponse, error) {
@@ -253,7 +888,7 @@ func (t *transport) SendRequestVote(
 	}
 
 	pbRequest := makeProtoRequestVoteRequest(request)
-	pbResponse, err := client.RequestVote(context.Background(), pbRequest)
+	pbResponse, err := client.RequestVote(ctx, pbRequest)
 	if err != nil {
 		return RequestVoteResponse{}, fmt.Errorf("could not make RequestVote RPC: %w", err)
 	}
@@ -262,6 +897,7 @@ func (t *transport) SendRequestVote(
 }
 
 func (t *transport) SendInstallSnapshot(
+	ctx context.Context,
 	address string,
 	request InstallSnapshotRequest,
 ) (InstallSnapshotResponse, error) {
@@ -280,7 +916,7 @@ func (t *transport) SendInstallSnapshot(
 	}
 
 	pbRequest := makeProtoInstallSnapshotRequest(request)
-	pbResponse, err := client.InstallSnapshot(context.Background(), pbRequest)
+	pbResponse, err := client.InstallSnapshot(ctx, pbRequest)
 	if err != nil {
 		return InstallSnapshotResponse{}, fmt.Errorf("could not make InstallSnapshot RPC: %w", err)
 	}
@@ -288,24 +924,170 @@ func (t *transport) SendInstallSnapshot(
 	return makeInstallSnapshotResponse(pbResponse), nil
 }
 
+func (t *transport) SendFetchSnapshot(
+	ctx context.Context,
+	address string,
+	request FetchSnapshotRequest,
+) (FetchSnapshotResponse, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.running {
+		return FetchSnapshotResponse{}, errors.New(
+			"could not make FetchSnapshot RPC: transport is closed",
+		)
+	}
+
+	client, err := t.connManager.getClient(address)
+	if err != nil {
+		return FetchSnapshotResponse{}, fmt.Errorf("could not get client connection: %w", err)
+	}
+
+	pbRequest := makeProtoFetchSnapshotRequest(request)
+	pbResponse, err := client.FetchSnapshot(ctx, pbRequest)
+	if err != nil {
+		return FetchSnapshotResponse{}, fmt.Errorf("could not make FetchSnapshot RPC: %w", err)
+	}
+
+	return makeFetchSnapshotResponse(pbResponse), nil
+}
+
+func (t *transport) SendTimeoutNow(
+	ctx context.Context,
+	address string,
+	request TimeoutNowRequest,
+) (TimeoutNowResponse, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.running {
+		return TimeoutNowResponse{}, errors.New(
+			"could not make TimeoutNow RPC: transport is closed",
+		)
+	}
+
+	client, err := t.connManager.getClient(address)
+	if err != nil {
+		return TimeoutNowResponse{}, fmt.Errorf("could not get client connection: %w", err)
+	}
+
+	pbRequest := makeProtoTimeoutNowRequest(request)
+	pbResponse, err := client.TimeoutNow(ctx, pbRequest)
+	if err != nil {
+		return TimeoutNowResponse{}, fmt.Errorf("could not make TimeoutNow RPC: %w", err)
+	}
+
+	return makeTimeoutNowResponse(pbResponse), nil
+}
+
+func (t *transport) SendForwardOperation(
+	ctx context.Context,
+	address string,
+	request ForwardOperationRequest,
+) (ForwardOperationResponse, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.running {
+		return ForwardOperationResponse{}, errors.New(
+			"could not make ForwardOperation RPC: transport is closed",
+		)
+	}
+
+	client, err := t.connManager.getClient(address)
+	if err != nil {
+		return ForwardOperationResponse{}, fmt.Errorf("could not get client connection: %w", err)
+	}
+
+	pbRequest := makeProtoForwardOperationRequest(request)
+	pbResponse, err := client.ForwardOperation(ctx, pbRequest)
+	if err != nil {
+		return ForwardOperationResponse{}, fmt.Errorf("could not make ForwardOperation RPC: %w", err)
+	}
+
+	return makeForwardOperationResponse(pbResponse), nil
+}
+
+func (t *transport) SendForwardMembershipChange(
+	ctx context.Context,
+	address string,
+	request ForwardMembershipChangeRequest,
+) (ForwardMembershipChangeResponse, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.running {
+		return ForwardMembershipChangeResponse{}, errors.New(
+			"could not make ForwardMembershipChange RPC: transport is closed",
+		)
+	}
+
+	client, err := t.connManager.getClient(address)
+	if err != nil {
+		return ForwardMembershipChangeResponse{}, fmt.Errorf(
+			"could not get client connection: %w",
+			err,
+		)
+	}
+
+	pbRequest := makeProtoForwardMembershipChangeRequest(request)
+	pbResponse, err := client.ForwardMembershipChange(ctx, pbRequest)
+	if err != nil {
+		return ForwardMembershipChangeResponse{}, fmt.Errorf(
+			"could not make ForwardMembershipChange RPC: %w",
+			err,
+		)
+	}
+
+	return makeForwardMembershipChangeResponse(pbResponse), nil
+}
+
 func (t *transport) RegisterAppendEntriesHandler(
-	handler func(*AppendEntriesRequest, *AppendEntriesResponse) error,
+	handler func(context.Context, *AppendEntriesRequest, *AppendEntriesResponse) error,
 ) {
 	t.appendEntriesHandler = handler
 }
 
 func (t *transport) RegisterRequestVoteHandler(
-	handler func(*RequestVoteRequest, *RequestVoteResponse) error,
+	handler func(context.Context, *RequestVoteRequest, *RequestVoteResponse) error,
 ) {
 	t.requestVoteHandler = handler
 }
 
 func (t *transport) RegsiterInstallSnapshotHandler(
-	handler func(*InstallSnapshotRequest, *InstallSnapshotResponse) error,
+	handler func(context.Context, *InstallSnapshotRequest, *InstallSnapshotResponse) error,
 ) {
 	t.installSnapshotHandler = handler
 }
 
+func (t *transport) RegisterFetchSnapshotHandler(
+	handler func(context.Context, *FetchSnapshotRequest, *FetchSnapshotResponse) error,
+) {
+	t.fetchSnapshotHandler = handler
+}
+
+func (t *transport) RegisterTimeoutNowHandler(
+	handler func(context.Context, *TimeoutNowRequest, *TimeoutNowResponse) error,
+) {
+	t.timeoutNowHandler = handler
+}
+
+func (t *transport) RegisterForwardOperationHandler(
+	handler func(context.Context, *ForwardOperationRequest, *ForwardOperationResponse) error,
+) {
+	t.forwardOperationHandler = handler
+}
+
+func (t *transport) RegisterForwardMembershipChangeHandler(
+	handler func(
+		context.Context,
+		*ForwardMembershipChangeRequest,
+		*ForwardMembershipChangeResponse,
+	) error,
+) {
+	t.forwardMembershipChangeHandler = handler
+}
+
 func (t *transport) EncodeConfiguration(configuration *Configuration) ([]byte, error) {
 	data, err := encodeConfiguration(configuration)
 	if err != nil {
@@ -322,8 +1104,12 @@ func (t *transport) DecodeConfiguration(data []byte) (Configuration, error) {
 	return configuration, nil
 }
 
+func (t *transport) RemovePeer(address string) {
+	t.connManager.remove(address)
+}
+
 func (t *transport) Address() string {
-	return t.address.String()
+	return t.address
 }
 
 func (t *transport) AppendEntries(
@@ -332,19 +1118,70 @@ func (t *transport) AppendEntries(
 ) (*pb.AppendEntriesResponse, error) {
 	appendEntriesRequest := makeAppendEntriesRequest(request)
 	appendEntriesResponse := &AppendEntriesResponse{}
-	if err := t.appendEntriesHandler(&appendEntriesRequest, appendEntriesResponse); err != nil {
+	if err := t.appendEntriesHandler(ctx, &appendEntriesRequest, appendEntriesResponse); err != nil {
 		return nil, status.Error(codes.Unavailable, err.Error())
 	}
 	return makeProtoAppendEntriesResponse(*appendEntriesResponse), nil
 }
 
+func (t *transport) AppendEntriesStream(stream pb.Raft_AppendEntriesStreamServer) error {
+	t.mu.RLock()
+	closing := t.closing
+	t.mu.RUnlock()
+
+	// Requests are received in a separate goroutine so that the stream may be ended
+	// when the transport is shutdown.
+	requests := make(chan *pb.AppendEntriesRequest)
+	received := make(chan error, 1)
+	go func() {
+		for {
+			request, err := stream.Recv()
+			if err != nil {
+				received <- err
+				return
+			}
+			select {
+			case requests <- request:
+			case <-stream.Context().Done():
+				received <- stream.Context().Err()
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closing:
+			return status.Error(codes.Unavailable, "transport is closed")
+		case err := <-received:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case request := <-requests:
+			appendEntriesRequest := makeAppendEntriesRequest(request)
+			appendEntriesResponse := &AppendEntriesResponse{}
+			if err := t.appendEntriesHandler(
+				stream.Context(),
+				&appendEntriesRequest,
+				appendEntriesResponse,
+			); err != nil {
+				return status.Error(codes.Unavailable, err.Error())
+			}
+			if err := stream.Send(makeProtoAppendEntriesResponse(*appendEntriesResponse)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func (t *transport) RequestVote(
 	ctx context.Context,
 	request *pb.RequestVoteRequest,
 ) (*pb.RequestVoteResponse, error) {
 	requestVoteRequest := makeRequestVoteRequest(request)
 	requestVoteResponse := &RequestVoteResponse{}
-	if err := t.requestVoteHandler(&requestVoteRequest, requestVoteResponse); err != nil {
+	if err := t.requestVoteHandler(ctx, &requestVoteRequest, requestVoteResponse); err != nil {
 		return nil, status.Error(codes.Unavailable, err.Error())
 	}
 	return makeProtoRequestVoteResponse(*requestVoteResponse), nil
@@ -356,8 +1193,60 @@ func (t *transport) InstallSnapshot(
 ) (*pb.InstallSnapshotResponse, error) {
 	installSnapshotRequest := makeInstallSnapshotRequest(request)
 	installSnapshotResponse := &InstallSnapshotResponse{}
-	if err := t.installSnapshotHandler(&installSnapshotRequest, installSnapshotResponse); err != nil {
+	if err := t.installSnapshotHandler(ctx, &installSnapshotRequest, installSnapshotResponse); err != nil {
 		return nil, status.Error(codes.Unavailable, err.Error())
 	}
 	return makeProtoInstallSnapshotResponse(*installSnapshotResponse), nil
 }
+
+func (t *transport) FetchSnapshot(
+	ctx context.Context,
+	request *pb.FetchSnapshotRequest,
+) (*pb.FetchSnapshotResponse, error) {
+	fetchSnapshotRequest := makeFetchSnapshotRequest(request)
+	fetchSnapshotResponse := &FetchSnapshotResponse{}
+	if err := t.fetchSnapshotHandler(ctx, &fetchSnapshotRequest, fetchSnapshotResponse); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoFetchSnapshotResponse(*fetchSnapshotResponse), nil
+}
+
+func (t *transport) TimeoutNow(
+	ctx context.Context,
+	request *pb.TimeoutNowRequest,
+) (*pb.TimeoutNowResponse, error) {
+	timeoutNowRequest := makeTimeoutNowRequest(request)
+	timeoutNowResponse := &TimeoutNowResponse{}
+	if err := t.timeoutNowHandler(ctx, &timeoutNowRequest, timeoutNowResponse); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoTimeoutNowResponse(*timeoutNowResponse), nil
+}
+
+func (t *transport) ForwardOperation(
+	ctx context.Context,
+	request *pb.ForwardOperationRequest,
+) (*pb.ForwardOperationResponse, error) {
+	forwardOperationRequest := makeForwardOperationRequest(request)
+	forwardOperationResponse := &ForwardOperationResponse{}
+	if err := t.forwardOperationHandler(ctx, &forwardOperationRequest, forwardOperationResponse); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoForwardOperationResponse(*forwardOperationResponse), nil
+}
+
+func (t *transport) ForwardMembershipChange(
+	ctx context.Context,
+	request *pb.ForwardMembershipChangeRequest,
+) (*pb.ForwardMembershipChangeResponse, error) {
+	forwardMembershipChangeRequest := makeForwardMembershipChangeRequest(request)
+	forwardMembershipChangeResponse := &ForwardMembershipChangeResponse{}
+	if err := t.forwardMembershipChangeHandler(
+		ctx,
+		&forwardMembershipChangeRequest,
+		forwardMembershipChangeResponse,
+	); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return makeProtoForwardMembershipChangeResponse(*forwardMembershipChangeResponse), nil
+}
@@ -1,9 +1,35 @@
 package raft
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	pb "github.com/jmsadair/raft/internal/protobuf"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func TestConfigurationEncoderDecoder(t *testing.T) {
@@ -20,4 +46,856 @@ func TestConfigurationEncoderDecoder(t *testing.T) {
 	require.NoError(t, err)
 
 	require.Equal(t, configuration, &decodedConfiguration)
+
+	joint := configuration.joint([]MembershipChange{
+		{ID: "3", Address: "127.0.0.2:8080", IsVoter: true},
+		{ID: "1", Remove: true},
+	})
+	encodedJoint, err := transport.EncodeConfiguration(&joint)
+	require.NoError(t, err)
+
+	decodedJoint, err := transport.DecodeConfiguration(encodedJoint)
+	require.NoError(t, err)
+
+	require.Equal(t, joint, decodedJoint)
+	require.True(t, decodedJoint.IsJoint())
+}
+
+// testCA is a certificate authority used to issue certificates for tests.
+type testCA struct {
+	// The certificate of the authority.
+	certificate *x509.Certificate
+
+	// The private key of the authority.
+	key *ecdsa.PrivateKey
+
+	// The path to the PEM encoded certificate of the authority.
+	file string
+}
+
+// writePEM writes the provided PEM block to the provided file.
+func writePEM(t *testing.T, file string, blockType string, bytes []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: bytes})
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+}
+
+// newTestCA creates a self-signed certificate authority and writes its certificate to the
+// provided directory.
+func newTestCA(t *testing.T, dir string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "raft-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "ca.pem")
+	writePEM(t, file, "CERTIFICATE", der)
+
+	return &testCA{certificate: certificate, key: key, file: file}
+}
+
+// issue creates a certificate for 127.0.0.1 with the provided serial number signed by the
+// authority and writes it and its key to the provided files.
+func (ca *testCA) issue(t *testing.T, certFile string, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "raft-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+}
+
+// startTestTransport creates and starts a transport at the provided address that responds
+// successfully to AppendEntries RPCs.
+func startTestTransport(t *testing.T, address string, opts ...TransportOption) Transport {
+	transport, err := NewTransport(address, opts...)
+	require.NoError(t, err)
+	transport.RegisterAppendEntriesHandler(
+		func(
+			ctx context.Context,
+			request *AppendEntriesRequest,
+			response *AppendEntriesResponse,
+		) error {
+			response.Term = request.Term
+			response.Success = true
+			return nil
+		},
+	)
+	require.NoError(t, transport.Run())
+	t.Cleanup(func() { transport.Shutdown() })
+	return transport
+}
+
+// TestTransportTLS checks that transports using TLS are able to send RPCs to each other.
+func TestTransportTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ca.issue(t, certFile, keyFile, 2)
+
+	server := startTestTransport(t, "127.0.0.1:18080", WithTLSCertificate(certFile, keyFile), WithTLSCA(ca.file))
+	client := startTestTransport(t, "127.0.0.1:18081", WithTLSCertificate(certFile, keyFile), WithTLSCA(ca.file))
+
+	response, err := client.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.NoError(t, err)
+	require.True(t, response.Success)
+
+	// A transport that does not use TLS cannot send RPCs to a transport that does.
+	plaintext := startTestTransport(t, "127.0.0.1:18082")
+	_, err = plaintext.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.Error(t, err)
+}
+
+// TestTransportMutualTLS checks that a transport using mutual TLS only accepts RPCs from
+// peers that present a certificate signed by a trusted authority.
+func TestTransportMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ca.issue(t, certFile, keyFile, 2)
+
+	// A certificate signed by an authority the server does not trust.
+	otherDir := t.TempDir()
+	otherCA := newTestCA(t, otherDir)
+	otherCertFile, otherKeyFile := filepath.Join(otherDir, "cert.pem"), filepath.Join(otherDir, "key.pem")
+	otherCA.issue(t, otherCertFile, otherKeyFile, 2)
+
+	server := startTestTransport(
+		t,
+		"127.0.0.1:18083",
+		WithTLSCertificate(certFile, keyFile),
+		WithTLSCA(ca.file),
+		WithMutualTLS(),
+	)
+	trusted := startTestTransport(t, "127.0.0.1:18084", WithTLSCertificate(certFile, keyFile), WithTLSCA(ca.file))
+	untrusted := startTestTransport(
+		t,
+		"127.0.0.1:18085",
+		WithTLSCertificate(otherCertFile, otherKeyFile),
+		WithTLSCA(ca.file),
+	)
+
+	response, err := trusted.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.NoError(t, err)
+	require.True(t, response.Success)
+
+	_, err = untrusted.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.Error(t, err)
+}
+
+// TestTransportTLSReload checks that a transport presents a new certificate once the
+// certificate files are replaced.
+func TestTransportTLSReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ca.issue(t, certFile, keyFile, 2)
+
+	server := startTestTransport(t, "127.0.0.1:18086", WithTLSCertificate(certFile, keyFile), WithTLSCA(ca.file))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+	serial := func() int64 {
+		conn, err := tls.Dial(
+			"tcp",
+			server.Address(),
+			&tls.Config{RootCAs: pool, NextProtos: []string{"h2"}},
+		)
+		require.NoError(t, err)
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+	require.Equal(t, int64(2), serial())
+
+	// Replace the certificate and ensure the modification time changes.
+	ca.issue(t, certFile, keyFile, 3)
+	modTime := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	require.Equal(t, int64(3), serial())
+}
+
+// writeCAs writes the certificates of the provided authorities to the provided file and
+// ensures that the modification time of the file changes.
+func writeCAs(t *testing.T, file string, cas ...*testCA) {
+	var data []byte
+	for _, ca := range cas {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.certificate.Raw})...)
+	}
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+	modTime := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(file, modTime, modTime))
+}
+
+// TestTransportTLSCAReload checks that a transport verifies peers using the authorities in
+// the CA file once it is replaced.
+func TestTransportTLSCAReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ca.issue(t, certFile, keyFile, 2)
+
+	otherDir := t.TempDir()
+	otherCA := newTestCA(t, otherDir)
+	otherCertFile, otherKeyFile := filepath.Join(otherDir, "cert.pem"), filepath.Join(otherDir, "key.pem")
+	otherCA.issue(t, otherCertFile, otherKeyFile, 2)
+
+	send := func(client Transport, server Transport) error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := client.SendAppendEntries(ctx, server.Address(), AppendEntriesRequest{Term: 1})
+		return err
+	}
+
+	// A client that does not trust the authority of the server trusts it once it is added.
+	clientCAFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeCAs(t, clientCAFile, otherCA)
+	server := startTestTransport(t, "127.0.0.1:18131", WithTLSCertificate(certFile, keyFile), WithTLSCA(ca.file))
+	client := startTestTransport(
+		t,
+		"127.0.0.1:18132",
+		WithTLSCertificate(certFile, keyFile),
+		WithTLSCA(clientCAFile),
+	)
+	require.Error(t, send(client, server))
+	writeCAs(t, clientCAFile, otherCA, ca)
+	require.Eventually(t, func() bool { return send(client, server) == nil }, 10*time.Second, 100*time.Millisecond)
+
+	// A server using mutual TLS that does not trust the authority of the client trusts it
+	// once it is added.
+	serverCAFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeCAs(t, serverCAFile, ca)
+	server = startTestTransport(
+		t,
+		"127.0.0.1:18133",
+		WithTLSCertificate(certFile, keyFile),
+		WithTLSCA(serverCAFile),
+		WithMutualTLS(),
+	)
+	client = startTestTransport(
+		t,
+		"127.0.0.1:18134",
+		WithTLSCertificate(otherCertFile, otherKeyFile),
+		WithTLSCA(ca.file),
+	)
+	require.Error(t, send(client, server))
+	writeCAs(t, serverCAFile, ca, otherCA)
+	require.Eventually(t, func() bool { return send(client, server) == nil }, 10*time.Second, 100*time.Millisecond)
+}
+
+// TestNewTransportTLSFailure checks that a transport cannot be created with an
+// incomplete TLS configuration.
+func TestNewTransportTLSFailure(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ca.issue(t, certFile, keyFile, 2)
+
+	_, err := NewTransport("127.0.0.1:18087", WithTLSCA(ca.file))
+	require.Error(t, err)
+	_, err = NewTransport("127.0.0.1:18087", WithTLSCertificate(certFile, keyFile), WithMutualTLS())
+	require.Error(t, err)
+	_, err = NewTransport("127.0.0.1:18087", WithTLSCertificate(filepath.Join(dir, "missing.pem"), keyFile))
+	require.Error(t, err)
+}
+
+// TestTransportDeadline checks that an RPC is abandoned once its deadline is exceeded and
+// that the handler observes the cancellation.
+func TestTransportDeadline(t *testing.T) {
+	cancelled := make(chan struct{})
+	server, err := NewTransport("127.0.0.1:18088")
+	require.NoError(t, err)
+	server.RegisterAppendEntriesHandler(
+		func(
+			ctx context.Context,
+			request *AppendEntriesRequest,
+			response *AppendEntriesResponse,
+		) error {
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		},
+	)
+	require.NoError(t, server.Run())
+	defer server.Shutdown()
+
+	client := startTestTransport(t, "127.0.0.1:18089")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.SendAppendEntries(ctx, server.Address(), AppendEntriesRequest{Term: 1})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Second)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not observe cancellation")
+	}
+}
+
+// TestTransportGRPCOptions checks that the provided gRPC server and dial options are used by the
+// transport when serving and sending RPCs.
+func TestTransportGRPCOptions(t *testing.T) {
+	var served, sent atomic.Int32
+	serverInterceptor := func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		served.Add(1)
+		return handler(ctx, req)
+	}
+	clientInterceptor := func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		sent.Add(1)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	server := startTestTransport(
+		t,
+		"127.0.0.1:18097",
+		WithServerOptions(grpc.UnaryInterceptor(serverInterceptor)),
+	)
+	client := startTestTransport(
+		t,
+		"127.0.0.1:18098",
+		WithDialOptions(grpc.WithUnaryInterceptor(clientInterceptor)),
+	)
+
+	response, err := client.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.NoError(t, err)
+	require.True(t, response.Success)
+	require.Equal(t, int32(1), served.Load())
+	require.Equal(t, int32(1), sent.Load())
+
+	// A message size limit provided as a server option is enforced.
+	limited := startTestTransport(t, "127.0.0.1:18099", WithServerOptions(grpc.MaxRecvMsgSize(16)))
+	request := AppendEntriesRequest{
+		Term:    1,
+		Entries: []*LogEntry{NewLogEntry(1, 1, make([]byte, 64), OperationEntry)},
+	}
+	_, err = client.SendAppendEntries(context.Background(), limited.Address(), request)
+	require.Error(t, err)
+}
+
+// TestTransportSharedSecret checks that a transport using a shared secret only handles RPCs from
+// peers that sign them with the same secret.
+func TestTransportSharedSecret(t *testing.T) {
+	var handled atomic.Int32
+	server, err := NewTransport("127.0.0.1:18100", WithSharedSecret([]byte("secret")))
+	require.NoError(t, err)
+	server.RegisterAppendEntriesHandler(
+		func(
+			ctx context.Context,
+			request *AppendEntriesRequest,
+			response *AppendEntriesResponse,
+		) error {
+			handled.Add(1)
+			response.Success = true
+			return nil
+		},
+	)
+	require.NoError(t, server.Run())
+	defer server.Shutdown()
+
+	client := startTestTransport(t, "127.0.0.1:18101", WithSharedSecret([]byte("secret")))
+	response, err := client.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.NoError(t, err)
+	require.True(t, response.Success)
+	require.Equal(t, int32(1), handled.Load())
+
+	// Peers that use a different secret or no secret are rejected before the handler is called.
+	for address, opts := range map[string][]TransportOption{
+		"127.0.0.1:18102": {WithSharedSecret([]byte("wrong"))},
+		"127.0.0.1:18103": nil,
+	} {
+		peer := startTestTransport(t, address, opts...)
+		_, err := peer.SendAppendEntries(
+			context.Background(),
+			server.Address(),
+			AppendEntriesRequest{Term: 1},
+		)
+		require.Error(t, err)
+		require.Equal(t, codes.Unauthenticated, status.Code(errors.Unwrap(err)))
+	}
+	require.Equal(t, int32(1), handled.Load())
+
+	_, err = NewTransport("127.0.0.1:18104", WithSharedSecret(nil))
+	require.Error(t, err)
+}
+
+// TestSharedSecretAuthenticator checks that the shared secret authenticator rejects signatures
+// for a different method or body, tampered signatures, replayed signatures, and signatures that
+// are too old.
+func TestSharedSecretAuthenticator(t *testing.T) {
+	authenticator := newSharedSecretAuthenticator([]byte("secret"))
+	method := "/Raft/AppendEntries"
+	body := []byte("request")
+
+	signed, err := authenticator.Sign(context.Background(), method, body)
+	require.NoError(t, err)
+	md := metadata.New(signed)
+	require.NoError(t, authenticator.Verify(context.Background(), method, body, md))
+	require.Error(t, authenticator.Verify(context.Background(), "/Raft/RequestVote", body, md))
+	require.Error(t, authenticator.Verify(context.Background(), method, []byte("tampered"), md))
+	require.Error(t, authenticator.Verify(context.Background(), method, body, metadata.MD{}))
+
+	tampered := md.Copy()
+	tampered.Set(authSignatureMetadataKey, strings.Repeat("0", 64))
+	require.Error(t, authenticator.Verify(context.Background(), method, body, tampered))
+
+	// A signature may only be verified once.
+	signed, err = authenticator.Sign(context.Background(), method, body)
+	require.NoError(t, err)
+	md = metadata.New(signed)
+	require.NoError(t, authenticator.Verify(context.Background(), method, body, md))
+	require.Error(t, authenticator.Verify(context.Background(), method, body, md))
+
+	timestamp := strconv.FormatInt(time.Now().Add(-2*maxAuthClockSkew).UnixNano(), 10)
+	nonce := "nonce"
+	stale := metadata.New(map[string]string{
+		authTimestampMetadataKey: timestamp,
+		authNonceMetadataKey:     nonce,
+		authSignatureMetadataKey: hex.EncodeToString(
+			authenticator.signature(method, body, timestamp, nonce),
+		),
+	})
+	require.Error(t, authenticator.Verify(context.Background(), method, body, stale))
+}
+
+// TestTransportSharedSecretReplay checks that a transport using a shared secret rejects RPCs and
+// streams that replay the signature of one that it has already handled.
+func TestTransportSharedSecretReplay(t *testing.T) {
+	secret := []byte("secret")
+	server := startTestTransport(t, "127.0.0.1:18129", WithSharedSecret(secret))
+	authenticator := newSharedSecretAuthenticator(secret)
+
+	conn, err := grpc.NewClient(
+		server.Address(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewRaftClient(conn)
+
+	// Capture the signature of an AppendEntries RPC and send it twice.
+	request := &pb.AppendEntriesRequest{Term: 1}
+	body, err := authBody(request)
+	require.NoError(t, err)
+	signed, err := authenticator.Sign(context.Background(), "/Raft/AppendEntries", body)
+	require.NoError(t, err)
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.New(signed))
+	_, err = client.AppendEntries(ctx, request)
+	require.NoError(t, err)
+	_, err = client.AppendEntries(ctx, request)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Capture the signature of a stream and open it twice.
+	signed, err = authenticator.Sign(context.Background(), "/Raft/AppendEntriesStream", nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(
+		metadata.NewOutgoingContext(context.Background(), metadata.New(signed)),
+	)
+	defer cancel()
+	stream, err := client.AppendEntriesStream(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(request))
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	replayed, err := client.AppendEntriesStream(ctx)
+	require.NoError(t, err)
+	_, err = replayed.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+// TestTransportReconnectBackoff checks that RPCs to a peer whose connection has failed fail
+// immediately until the reconnect delay has elapsed, after which the peer is reconnected to.
+func TestTransportReconnectBackoff(t *testing.T) {
+	client := startTestTransport(
+		t,
+		"127.0.0.1:18105",
+		WithReconnectBackoff(500*time.Millisecond, time.Second),
+	)
+
+	// The peer is not running, so the connection fails.
+	_, err := client.SendAppendEntries(
+		context.Background(),
+		"127.0.0.1:18106",
+		AppendEntriesRequest{Term: 1},
+	)
+	require.Error(t, err)
+
+	// The peer is running, but the transport waits before reconnecting.
+	startTestTransport(t, "127.0.0.1:18106")
+	_, err = client.SendAppendEntries(
+		context.Background(),
+		"127.0.0.1:18106",
+		AppendEntriesRequest{Term: 1},
+	)
+	require.Error(t, err)
+
+	require.Eventually(t, func() bool {
+		response, err := client.SendAppendEntries(
+			context.Background(),
+			"127.0.0.1:18106",
+			AppendEntriesRequest{Term: 1},
+		)
+		return err == nil && response.Success
+	}, 3*time.Second, 50*time.Millisecond)
+
+	_, err = NewTransport("127.0.0.1:18107", WithReconnectBackoff(time.Second, time.Millisecond))
+	require.Error(t, err)
+}
+
+// TestTransportRemovePeer checks that removing a peer closes the connection to it.
+func TestTransportRemovePeer(t *testing.T) {
+	server := startTestTransport(t, "127.0.0.1:18108")
+	client := startTestTransport(t, "127.0.0.1:18109")
+
+	_, err := client.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.NoError(t, err)
+
+	connManager := client.(*transport).connManager
+	connManager.mu.Lock()
+	require.Contains(t, connManager.connections, server.Address())
+	connManager.mu.Unlock()
+
+	client.RemovePeer(server.Address())
+	connManager.mu.Lock()
+	require.NotContains(t, connManager.connections, server.Address())
+	connManager.mu.Unlock()
+}
+
+// TestTransportHostName checks that a transport may be addressed by host name.
+func TestTransportHostName(t *testing.T) {
+	server := startTestTransport(t, "localhost:18110")
+	require.Equal(t, "localhost:18110", server.Address())
+	client := startTestTransport(t, "127.0.0.1:18111")
+
+	response, err := client.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.NoError(t, err)
+	require.True(t, response.Success)
+
+	_, err = NewTransport("localhost")
+	require.Error(t, err)
+}
+
+// TestTransportUnixSocket checks that transports are able to listen on and send RPCs to
+// Unix domain sockets.
+func TestTransportUnixSocket(t *testing.T) {
+	dir := t.TempDir()
+	server := startTestTransport(t, "unix://"+filepath.Join(dir, "server.sock"))
+	require.Equal(t, "unix://"+filepath.Join(dir, "server.sock"), server.Address())
+	client := startTestTransport(t, "unix://"+filepath.Join(dir, "client.sock"))
+
+	response, err := client.SendAppendEntries(
+		context.Background(),
+		server.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.NoError(t, err)
+	require.True(t, response.Success)
+
+	// A socket left behind by a transport that did not shut down cleanly is replaced.
+	stalePath := filepath.Join(dir, "stale.sock")
+	listener, err := net.Listen("unix", stalePath)
+	require.NoError(t, err)
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, listener.Close())
+	stale := startTestTransport(t, "unix://"+stalePath)
+	response, err = client.SendAppendEntries(
+		context.Background(),
+		stale.Address(),
+		AppendEntriesRequest{Term: 1},
+	)
+	require.NoError(t, err)
+	require.True(t, response.Success)
+
+	_, err = NewTransport("unix://relative.sock")
+	require.Error(t, err)
+	_, err = NewTransport("unix:")
+	require.Error(t, err)
+}
+
+// TestUnixSocketCluster checks that a cluster whose members are addressed by Unix domain
+// sockets is able to elect a leader and replicate operations.
+func TestUnixSocketCluster(t *testing.T) {
+	dir := t.TempDir()
+	members := make(map[string]string)
+	for i := 0; i < 3; i++ {
+		members[fmt.Sprint(i)] = "unix://" + filepath.Join(dir, fmt.Sprintf("node%d.sock", i))
+	}
+
+	nodes := make([]*Raft, 0, len(members))
+	for id, address := range members {
+		transport, err := NewTransport(address)
+		require.NoError(t, err)
+		node, err := makeRaftWithStateMachine(
+			id,
+			address,
+			t.TempDir(),
+			newStateMachineMock(false, 0),
+			WithTransport(transport),
+		)
+		require.NoError(t, err)
+		require.NoError(t, node.Bootstrap(members))
+		require.NoError(t, node.Start())
+		defer node.Stop()
+		nodes = append(nodes, node)
+	}
+
+	operation := []byte("operation")
+	require.Eventually(t, func() bool {
+		for _, node := range nodes {
+			response := node.SubmitOperation(operation, Replicated, futureTimeout).Await()
+			if response.Error() == nil {
+				return string(response.Success().Operation.Bytes) == string(operation)
+			}
+		}
+		return false
+	}, maxSubmissionTime*time.Second, 50*time.Millisecond)
+
+	for _, node := range nodes {
+		require.Equal(t, members, node.Configuration().Members)
+	}
+}
+
+// waitForAppendEntriesStream sends AppendEntries RPCs from the provided client to the provided
+// address until a stream to the address is open.
+func waitForAppendEntriesStream(t *testing.T, client Transport, address string) {
+	connManager := client.(*transport).connManager
+	require.Eventually(t, func() bool {
+		_, err := client.SendAppendEntries(context.Background(), address, AppendEntriesRequest{})
+		require.NoError(t, err)
+		connManager.mu.Lock()
+		defer connManager.mu.Unlock()
+		stream, ok := connManager.streams[address]
+		return ok && !stream.broken()
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestTransportAppendEntriesStream checks that AppendEntries requests are sent over a stream
+// once it is open, that each request receives its own response, and that requests fall back to
+// separate RPCs when the stream breaks.
+func TestTransportAppendEntriesStream(t *testing.T) {
+	var unary atomic.Int32
+	countUnary := func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		unary.Add(1)
+		return handler(ctx, req)
+	}
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ca.issue(t, certFile, keyFile, 2)
+	opts := []TransportOption{
+		WithTLSCertificate(certFile, keyFile),
+		WithTLSCA(ca.file),
+		WithSharedSecret([]byte("secret")),
+	}
+
+	server := startTestTransport(
+		t,
+		"127.0.0.1:18124",
+		append(opts, WithServerOptions(grpc.UnaryInterceptor(countUnary)))...,
+	)
+	client := startTestTransport(t, "127.0.0.1:18125", opts...)
+	waitForAppendEntriesStream(t, client, server.Address())
+
+	sent := unary.Load()
+	var wg sync.WaitGroup
+	for i := 1; i <= 100; i++ {
+		wg.Add(1)
+		go func(term uint64) {
+			defer wg.Done()
+			response, err := client.SendAppendEntries(
+				context.Background(),
+				server.Address(),
+				AppendEntriesRequest{Term: term},
+			)
+			require.NoError(t, err)
+			require.Equal(t, term, response.Term)
+		}(uint64(i))
+	}
+	wg.Wait()
+	require.Equal(t, sent, unary.Load())
+
+	// Restarting the peer breaks the stream.
+	require.NoError(t, server.Shutdown())
+	require.NoError(t, server.Run())
+	require.Eventually(t, func() bool {
+		response, err := client.SendAppendEntries(
+			context.Background(),
+			server.Address(),
+			AppendEntriesRequest{Term: 1},
+		)
+		return err == nil && response.Success
+	}, 3*time.Second, 10*time.Millisecond)
+	require.Greater(t, unary.Load(), sent)
+}
+
+// blockedStream is an AppendEntries stream whose peer never reads requests or sends responses.
+type blockedStream struct {
+	grpc.ClientStream
+
+	// The context of the stream.
+	ctx context.Context
+}
+
+func (s *blockedStream) Send(request *pb.AppendEntriesRequest) error {
+	<-s.ctx.Done()
+	return s.ctx.Err()
+}
+
+func (s *blockedStream) Recv() (*pb.AppendEntriesResponse, error) {
+	<-s.ctx.Done()
+	return nil, s.ctx.Err()
+}
+
+// TestAppendEntriesStreamBlockedSend checks that a request that cannot be written to a stream
+// fails once its context is done, breaking the stream, and that requests queued behind it do not
+// wait for the stream to be unblocked.
+func TestAppendEntriesStreamBlockedSend(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := startAppendEntriesStream(&blockedStream{ctx: ctx}, cancel)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := stream.send(ctx, &pb.AppendEntriesRequest{})
+			require.Error(t, err)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("requests blocked on a stream that is not being read")
+	}
+	require.True(t, stream.broken())
+
+	_, err := stream.send(context.Background(), &pb.AppendEntriesRequest{})
+	require.Error(t, err)
+}
+
+// TestTransportAppendEntriesStreamUnsupported checks that AppendEntries requests are sent as
+// separate RPCs to peers that do not support streaming and when streaming is disabled.
+func TestTransportAppendEntriesStreamUnsupported(t *testing.T) {
+	rejectStreams := func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return status.Error(codes.Unimplemented, "streaming is not supported")
+	}
+	server := startTestTransport(
+		t,
+		"127.0.0.1:18126",
+		WithServerOptions(grpc.StreamInterceptor(rejectStreams)),
+	)
+	client := startTestTransport(t, "127.0.0.1:18127")
+
+	for i := 0; i < 20; i++ {
+		response, err := client.SendAppendEntries(
+			context.Background(),
+			server.Address(),
+			AppendEntriesRequest{Term: 1},
+		)
+		require.NoError(t, err)
+		require.True(t, response.Success)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Streaming may be disabled.
+	disabled := startTestTransport(t, "127.0.0.1:18128", WithAppendEntriesStreaming(false))
+	for i := 0; i < 5; i++ {
+		_, err := disabled.SendAppendEntries(
+			context.Background(),
+			server.Address(),
+			AppendEntriesRequest{Term: 1},
+		)
+		require.NoError(t, err)
+	}
+	require.Empty(t, disabled.(*transport).connManager.streams)
+
+	// Streaming is disabled if peers are authenticated without TLS.
+	authenticated := startTestTransport(t, "127.0.0.1:18130", WithSharedSecret([]byte("secret")))
+	require.False(t, authenticated.(*transport).connManager.streaming)
 }